@@ -0,0 +1,173 @@
+// auth/auth.go
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token types carried in the "typ" claim, so a refresh token cannot be used
+// as an access token and vice versa.
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+// userIDKey is the gin context key holding the authenticated user's ID.
+const userIDKey = "auth.userID"
+
+// ErrInvalidToken is returned for tokens that are malformed, expired,
+// wrongly signed or of the wrong type.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the JWT claims issued by the gateway. The subject is the user ID.
+type Claims struct {
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair is returned by a successful login or refresh.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Manager issues and verifies HMAC-SHA256 signed tokens.
+type Manager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewManager creates a Manager signing with secret.
+func NewManager(secret string, accessTTL, refreshTTL time.Duration) *Manager {
+	return &Manager{secret: []byte(secret), accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+// Issue creates a new access and refresh token for userID.
+func (m *Manager) Issue(userID uint32) (TokenPair, error) {
+	access, err := m.sign(userID, AccessToken, m.accessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := m.sign(userID, RefreshToken, m.refreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(m.accessTTL.Seconds()),
+	}, nil
+}
+
+func (m *Manager) sign(userID uint32, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// Verify checks the signature, expiry and type of a token and returns the
+// user ID it was issued to.
+func (m *Manager) Verify(token, tokenType string) (uint32, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.TokenType != tokenType {
+		return 0, fmt.Errorf("%w: expected %s token", ErrInvalidToken, tokenType)
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+	return uint32(userID), nil
+}
+
+// Middleware rejects requests without a valid "Authorization: Bearer"
+// access token and stores the caller's user ID in the request context.
+func (m *Manager) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		userID, err := m.Verify(token, AccessToken)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(userIDKey, userID)
+		c.Next()
+	}
+}
+
+// UserID returns the authenticated caller's user ID set by Middleware.
+func UserID(c *gin.Context) (uint32, bool) {
+	v, ok := c.Get(userIDKey)
+	if !ok {
+		return 0, false
+	}
+	userID, ok := v.(uint32)
+	return userID, ok
+}
+
+// RequireUsers returns a middleware, to be installed after Middleware, that
+// aborts with 403 unless the caller is one of userIDs.
+func RequireUsers(userIDs []uint32) gin.HandlerFunc {
+	allowed := make(map[uint32]bool, len(userIDs))
+	for _, id := range userIDs {
+		allowed[id] = true
+	}
+	return func(c *gin.Context) {
+		caller, ok := UserID(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+			return
+		}
+		if !allowed[caller] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this route is restricted to support staff"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelf aborts with 403 unless userID is the authenticated caller.
+// It reports whether the request may continue.
+func RequireSelf(c *gin.Context, userID uint32) bool {
+	caller, ok := UserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return false
+	}
+	if caller != userID {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access to another user's resources is not allowed"})
+		return false
+	}
+	return true
+}
@@ -2,18 +2,26 @@
 package main
 
 import (
-	"ewallet/gateaway/config"
 	"ewallet/gateaway/router"
 	"ewallet/gateaway/service"
+	"ewallet/pkg/config"
 	"log"
 )
 
 func main() {
-	srv := service.NewServer()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	if err := cfg.Gateway.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	srv := service.NewServer(cfg.Gateway)
 	r := router.SetupRouter(srv)
 
-	log.Println("Starting HTTP server on port", config.GetHTTPPort())
-	if err := r.Run(config.GetHTTPPort()); err != nil {
+	log.Println("Starting HTTP server on", cfg.Gateway.HTTPAddr)
+	if err := r.Run(cfg.Gateway.HTTPAddr); err != nil {
 		log.Fatalf("could not start server: %v", err)
 	}
 }
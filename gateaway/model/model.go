@@ -3,46 +3,267 @@ package model
 
 import (
 	pb "ewallet/gateaway/proto"
+	"ewallet/pkg/money"
 	"time"
 )
 
+// Amounts in requests and responses are decimal strings such as "12.50".
+// Requests may also send a JSON number; it is parsed exactly, never through
+// a float.
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// IdempotencyKey is optional on money-moving requests; it may also be sent
+// in the Idempotency-Key header. Retrying with the same key returns the
+// original result instead of moving money again.
+
+// Wallet IDs are optional on money-moving requests; when omitted the user's
+// default wallet is used. A transfer needs either the recipient user or the
+// recipient wallet.
+
 type TransferWalletRequest struct {
-	UserIDFrom int32   `json:"user_idfrom"`
-	UserIDTo   int32   `json:"user_idto"`
-	Amount     float32 `json:"amount"`
+	UserIDFrom     int32        `json:"user_idfrom" binding:"required,gt=0"`
+	FromWalletID   int32        `json:"from_wallet_id" binding:"omitempty,gt=0"`
+	UserIDTo       int32        `json:"user_idto" binding:"required_without=ToWalletID,omitempty,gt=0"`
+	ToWalletID     int32        `json:"to_wallet_id" binding:"omitempty,gt=0"`
+	Amount         money.Amount `json:"amount" binding:"required,gt=0"`
+	IdempotencyKey string       `json:"idempotency_key"`
 }
 
 type UserAndWalletResponse struct {
-	User   *pb.User   `json:"user"`
-	Wallet *pb.Wallet `json:"wallet"`
+	User   *pb.User `json:"user"`
+	Wallet *Wallet  `json:"wallet"`
 }
 
 type TopUpRequest struct {
-	UserID int32   `json:"user_id"`
-	Amount float32 `json:"amount"`
+	UserID         int32        `json:"user_id" binding:"required,gt=0"`
+	WalletID       int32        `json:"wallet_id" binding:"omitempty,gt=0"`
+	Amount         money.Amount `json:"amount" binding:"required,gt=0"`
+	IdempotencyKey string       `json:"idempotency_key"`
+}
+
+// RefundRequest refunds a payment or a transfer given its debit
+// transaction. Amount is in the paying wallet's currency; leaving it out
+// refunds everything not refunded yet.
+type RefundRequest struct {
+	TransactionID  uint32       `json:"transaction_id" binding:"required,gt=0"`
+	Amount         money.Amount `json:"amount" binding:"omitempty,gt=0"`
+	IdempotencyKey string       `json:"idempotency_key"`
+}
+
+// CreateScheduledTransferRequest creates a standing order from one of the
+// caller's wallets. Schedule is "@every <duration>", "@hourly", "@daily",
+// "@weekly", "@monthly", "@yearly" or a five-field cron expression in UTC,
+// such as "0 9 1 * *" for 09:00 on the first of every month. MaxRuns ends
+// the schedule after that many runs.
+type CreateScheduledTransferRequest struct {
+	FromWalletID int32        `json:"from_wallet_id" binding:"omitempty,gt=0"`
+	UserIDTo     int32        `json:"user_idto" binding:"required_without=ToWalletID,omitempty,gt=0"`
+	ToWalletID   int32        `json:"to_wallet_id" binding:"omitempty,gt=0"`
+	Amount       money.Amount `json:"amount" binding:"required,gt=0"`
+	Schedule     string       `json:"schedule" binding:"required,max=100"`
+	Description  string       `json:"description" binding:"max=255"`
+	MaxRuns      int32        `json:"max_runs" binding:"omitempty,gt=0"`
+}
+
+// ScheduledTransferRunsQuery holds the query parameters of the run history
+// route.
+type ScheduledTransferRunsQuery struct {
+	Limit int32 `form:"limit" binding:"omitempty,gt=0,lte=500"`
+}
+
+// SetUserTierRequest moves a user to another spending limit tier.
+type SetUserTierRequest struct {
+	UserID uint32 `json:"user_id" binding:"required,gt=0"`
+	Tier   string `json:"tier" binding:"required,max=32"`
+}
+
+// SetWalletLimitsRequest replaces the overrides of a wallet's tier limits.
+// An omitted limit restores the tier's; "0" removes it.
+type SetWalletLimitsRequest struct {
+	WalletID      int32         `json:"wallet_id" binding:"required,gt=0"`
+	DailyAmount   *money.Amount `json:"daily_amount"`
+	MonthlyAmount *money.Amount `json:"monthly_amount"`
+	DailyCount    *int32        `json:"daily_count"`
+	MonthlyCount  *int32        `json:"monthly_count"`
+}
+
+// SetWalletStatusRequest freezes or unfreezes a wallet.
+type SetWalletStatusRequest struct {
+	WalletID int32  `json:"wallet_id" binding:"required,gt=0"`
+	Status   string `json:"status" binding:"required,oneof=active frozen"`
+	Reason   string `json:"reason" binding:"required,max=500"`
+}
+
+// CloseWalletRequest closes a wallet, sweeping any balance left to
+// SweepToWalletID.
+type CloseWalletRequest struct {
+	WalletID        int32  `json:"wallet_id" binding:"required,gt=0"`
+	SweepToWalletID int32  `json:"sweep_to_wallet_id" binding:"omitempty,gt=0,nefield=WalletID"`
+	Reason          string `json:"reason" binding:"required,max=500"`
+}
+
+type CreateWalletRequest struct {
+	Name      string `json:"name" binding:"max=50"`
+	Currency  string `json:"currency" binding:"omitempty,len=3"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// Wallet is a wallet as returned by the gateway. Balance is the ledger
+// balance; AvailableBalance leaves out funds held by payment
+// authorizations.
+type Wallet struct {
+	ID               int32        `json:"id"`
+	UserID           uint32       `json:"user_id"`
+	Balance          money.Amount `json:"balance"`
+	AvailableBalance money.Amount `json:"available_balance"`
+	Currency         string       `json:"currency"`
+	Name             string       `json:"name"`
+	IsDefault        bool         `json:"is_default"`
+	Status           string       `json:"status"`
+	StatusReason     string       `json:"status_reason,omitempty"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// Conversion describes a money movement across currencies. Same-currency
+// movements report equal amounts and a rate of "1".
+type Conversion struct {
+	OriginalAmount    money.Amount `json:"original_amount"`
+	OriginalCurrency  string       `json:"original_currency"`
+	ConvertedAmount   money.Amount `json:"converted_amount"`
+	ConvertedCurrency string       `json:"converted_currency"`
+	FxRate            string       `json:"fx_rate"`
+}
+
+// Refund links a ledger row to refunds. RefundOf is set on a refund to the
+// transaction it reverses; RefundedAmount is how much of a payment or
+// transfer has been refunded.
+type Refund struct {
+	RefundOf       uint32       `json:"refund_of,omitempty"`
+	RefundedAmount money.Amount `json:"refunded_amount"`
+}
+
+type WalletTransaction struct {
+	TransactionID uint32       `json:"transaction_id"`
+	WalletID      int32        `json:"wallet_id"`
+	Amount        money.Amount `json:"amount"`
+	Type          string       `json:"type"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Refund
+	Conversion
 }
 
 type UserBalance struct {
-	UserID    uint32    `json:"user_id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	Balance   float32   `json:"balance"`
+	UserID    uint32       `json:"user_id"`
+	Username  string       `json:"username"`
+	Email     string       `json:"email"`
+	CreatedAt time.Time    `json:"created_at"`
+	Balance   money.Amount `json:"balance"`
+	// AvailableBalance leaves out funds held by payment authorizations.
+	AvailableBalance money.Amount `json:"available_balance"`
 }
 
 type Transaction struct {
-	TransactionID  uint32    `json:"transaction_id"`
-	UserID         uint32    `json:"user_id"`
-	Username       string    `json:"username"`
-	Amount         float32   `json:"amount"`
-	Description    string    `json:"description"`
-	Type           string    `json:"type"`
-	Status         string    `json:"status"`
-	CreatedAt      time.Time `json:"created_at"`
-	SourceUserID   uint32    `json:"SourceUserID"`
-	SourceUserName string    `json:"SourceUserName"`
+	TransactionID  uint32       `json:"transaction_id"`
+	UserID         uint32       `json:"user_id"`
+	Username       string       `json:"username"`
+	Amount         money.Amount `json:"amount"`
+	Description    string       `json:"description"`
+	Type           string       `json:"type"`
+	Status         string       `json:"status"`
+	CreatedAt      time.Time    `json:"created_at"`
+	SourceUserID   uint32       `json:"SourceUserID"`
+	SourceUserName string       `json:"SourceUserName"`
+	// MerchantID is the merchant paid by a payment.
+	MerchantID string `json:"merchant_id,omitempty"`
+	Refund
+	Conversion
+}
+
+// TransactionHistoryQuery holds the query parameters of the transaction
+// history route. Every parameter is optional; from and to are RFC 3339
+// timestamps, and cursor is the next_cursor of the previous page.
+type TransactionHistoryQuery struct {
+	PageSize             int32        `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+	Cursor               string       `form:"cursor"`
+	From                 time.Time    `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To                   time.Time    `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Type                 string       `form:"type" binding:"omitempty,oneof=in out"`
+	CounterpartyWalletID int32        `form:"counterparty_wallet_id" binding:"omitempty,gt=0"`
+	CounterpartyUserID   uint32       `form:"counterparty_user_id" binding:"omitempty,gt=0"`
+	MinAmount            money.Amount `form:"min_amount" binding:"omitempty,gt=0"`
+	MaxAmount            money.Amount `form:"max_amount" binding:"omitempty,gt=0"`
 }
 
 type TransactionsResponse struct {
 	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
+
+// WalletEvent is one committed balance change, sent as the data of a
+// wallet_event Server-Sent Event.
+type WalletEvent struct {
+	EventID       int64        `json:"event_id"`
+	WalletID      int32        `json:"wallet_id"`
+	Type          string       `json:"type"`
+	TransactionID uint32       `json:"transaction_id"`
+	Amount        money.Amount `json:"amount"`
+	Balance       money.Amount `json:"balance"`
+	Currency      string       `json:"currency"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// ScheduledTransfer is a standing order. Status is "active", "paused",
+// "cancelled" or "completed".
+type ScheduledTransfer struct {
+	ID           int64        `json:"id"`
+	UserID       uint32       `json:"user_id"`
+	FromWalletID int32        `json:"from_wallet_id"`
+	ToWalletID   int32        `json:"to_wallet_id"`
+	Amount       money.Amount `json:"amount"`
+	Schedule     string       `json:"schedule"`
+	Description  string       `json:"description"`
+	Status       string       `json:"status"`
+	NextRunAt    time.Time    `json:"next_run_at"`
+	LastRunAt    *time.Time   `json:"last_run_at,omitempty"`
+	MaxRuns      int32        `json:"max_runs"`
+	RunCount     int32        `json:"run_count"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// ScheduledTransferRun is one execution of a standing order. A failed run
+// carries the reason in Error.
+type ScheduledTransferRun struct {
+	ID            int64     `json:"id"`
+	ScheduleID    int64     `json:"schedule_id"`
+	ScheduledFor  time.Time `json:"scheduled_for"`
+	Status        string    `json:"status"`
+	TransactionID uint32    `json:"transaction_id,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WalletLimits are the spending limits of a wallet, in its currency, and
+// what it spent in the current UTC day and month. Zero means unlimited.
+type WalletLimits struct {
+	WalletID          int32        `json:"wallet_id"`
+	Currency          string       `json:"currency"`
+	Tier              string       `json:"tier"`
+	DailyAmount       money.Amount `json:"daily_amount"`
+	MonthlyAmount     money.Amount `json:"monthly_amount"`
+	DailyCount        int32        `json:"daily_count"`
+	MonthlyCount      int32        `json:"monthly_count"`
+	UsedDailyAmount   money.Amount `json:"used_daily_amount"`
+	UsedMonthlyAmount money.Amount `json:"used_monthly_amount"`
+	UsedDailyCount    int32        `json:"used_daily_count"`
+	UsedMonthlyCount  int32        `json:"used_monthly_count"`
 }
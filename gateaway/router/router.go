@@ -2,30 +2,45 @@
 package router
 
 import (
-	"ewallet/gateaway/config"
+	"ewallet/gateaway/auth"
 	"ewallet/gateaway/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func basicAuth() gin.HandlerFunc {
-	return gin.BasicAuth(gin.Accounts{
-		config.GetBasicAuthUsername(): config.GetBasicAuthPassword(),
-	})
-}
-
 func SetupRouter(srv *service.Server) *gin.Engine {
 	r := gin.Default()
-	r.GET("/getUserByID/:userID", srv.GetUserByID)
-	r.GET("/getWalletByUserID/:userID", srv.GetWalletByUserID)
-	r.GET("/getTransactionByUserID/:userID", srv.GetTransactionByUserID)
-	r.GET("/getUserAndBalanceWallet/:userID", srv.GetUserAndBalanceWallet)
+	r.POST("/login", srv.Login)
+	r.POST("/refresh", srv.Refresh)
+	r.POST("/createUser", srv.CreateUser)
 
-	authorized := r.Group("/", basicAuth())
+	authorized := r.Group("/", srv.Auth.Middleware())
 	{
-		authorized.POST("/createUser", srv.CreateUser)
+		authorized.GET("/getUserByID/:userID", srv.GetUserByID)
+		authorized.GET("/getWalletByUserID/:userID", srv.GetWalletByUserID)
+		authorized.GET("/getWalletsByUserID/:userID", srv.GetWalletsByUserID)
+		authorized.GET("/getWalletLimits/:walletID", srv.GetWalletLimits)
+		authorized.GET("/subscribeWalletEvents/:walletID", srv.SubscribeWalletEvents)
+		authorized.POST("/createWallet", srv.CreateWallet)
+		authorized.GET("/getTransactionByUserID/:userID", srv.GetTransactionByUserID)
+		authorized.GET("/getUserAndBalanceWallet/:userID", srv.GetUserAndBalanceWallet)
 		authorized.POST("/transferWallet", srv.TransferWallet)
-		authorized.POST("/topUp", srv.TopUp)
+		authorized.POST("/createScheduledTransfer", srv.CreateScheduledTransfer)
+		authorized.GET("/getScheduledTransfersByUserID/:userID", srv.GetScheduledTransfersByUserID)
+		authorized.POST("/pauseScheduledTransfer/:scheduleID", srv.PauseScheduledTransfer)
+		authorized.POST("/resumeScheduledTransfer/:scheduleID", srv.ResumeScheduledTransfer)
+		authorized.POST("/cancelScheduledTransfer/:scheduleID", srv.CancelScheduledTransfer)
+		authorized.GET("/getScheduledTransferRuns/:scheduleID", srv.GetScheduledTransferRuns)
+	}
+
+	support := authorized.Group("/", auth.RequireUsers(srv.SupportUserIDs))
+	{
+		support.POST("/topUp", srv.TopUp)
+		support.POST("/refund", srv.Refund)
+		support.POST("/setUserTier", srv.SetUserTier)
+		support.POST("/setWalletLimits", srv.SetWalletLimits)
+		support.POST("/setWalletStatus", srv.SetWalletStatus)
+		support.POST("/closeWallet", srv.CloseWallet)
 	}
 
 	return r
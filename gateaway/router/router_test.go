@@ -0,0 +1,62 @@
+package router_test
+
+import (
+	"ewallet/gateaway/auth"
+	"ewallet/gateaway/router"
+	"ewallet/gateaway/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSupportRoutesRejectPlainUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const supportUser, plainUser = 1, 2
+	srv := &service.Server{
+		Auth:           auth.NewManager("router-test-secret-at-least-32-bytes", time.Minute, time.Hour),
+		SupportUserIDs: []uint32{supportUser},
+		RequestTimeout: time.Second,
+	}
+	r := router.SetupRouter(srv)
+
+	token := func(userID uint32) string {
+		pair, err := srv.Auth.Issue(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pair.AccessToken
+	}
+
+	// An empty body fails binding, so a request that gets past the route's
+	// guards answers 400 without reaching the wallet service.
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"top-up without a token", "/topUp", "", http.StatusUnauthorized},
+		{"top-up by a plain user", "/topUp", token(plainUser), http.StatusForbidden},
+		{"top-up by support", "/topUp", token(supportUser), http.StatusBadRequest},
+		{"refund by a plain user", "/refund", token(plainUser), http.StatusForbidden},
+		{"wallet status by a plain user", "/setWalletStatus", token(plainUser), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("POST %s answered %d, want %d: %s", tt.path, w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
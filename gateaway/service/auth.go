@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"ewallet/gateaway/auth"
+	"ewallet/gateaway/model"
+	pb "ewallet/gateaway/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Login verifies the user's credentials with the user service and issues an
+// access and a refresh token.
+func (s *Server) Login(c *gin.Context) {
+	var req model.LoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.UserClient.Login(ctx, &pb.LoginRequest{Username: req.Username, Password: req.Password})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	tokens, err := s.Auth.Issue(res.GetUser().GetUserId())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair.
+func (s *Server) Refresh(c *gin.Context) {
+	var req model.RefreshRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID, err := s.Auth.Verify(req.RefreshToken, auth.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := s.Auth.Issue(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FieldError is one rejected request field in an HTTP 400 response.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report validation failures using the JSON or query field names
+	// clients send.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, key := range []string{"json", "form"} {
+				name, _, _ := strings.Cut(f.Tag.Get(key), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return ""
+		})
+	}
+}
+
+// bindJSON binds the request body into req. On failure it writes a 400
+// response, listing each invalid field when validation failed, and returns
+// false.
+func bindJSON(c *gin.Context, req interface{}) bool {
+	return checkBinding(c, c.ShouldBindJSON(req))
+}
+
+// bindQuery is bindJSON for URL query parameters.
+func bindQuery(c *gin.Context, req interface{}) bool {
+	return checkBinding(c, c.ShouldBindQuery(req))
+}
+
+// checkBinding writes the 400 response for a failed binding and reports
+// whether the request may continue.
+func checkBinding(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	fields := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "fields": fields})
+	return false
+}
+
+// validationMessage describes a failed binding rule in plain words.
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "nefield":
+		return "must differ from " + fe.Param()
+	case "required_without":
+		return "is required when " + fe.Param() + " is not set"
+	case "max":
+		return "must be at most " + fe.Param() + " characters"
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "len":
+		return "must be exactly " + fe.Param() + " characters"
+	default:
+		return "failed the " + fe.Tag() + " rule"
+	}
+}
+
+// httpStatusFromCode maps the gRPC code returned by the user and wallet
+// services to the HTTP status sent to clients.
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.FailedPrecondition:
+		return http.StatusUnprocessableEntity
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// limitExceededReason marks a wallet service error caused by a spending
+// limit rather than, say, insufficient funds.
+const limitExceededReason = "LIMIT_EXCEEDED"
+
+// writeGRPCError writes the error returned by a downstream gRPC call as an
+// HTTP response. InvalidArgument errors carry the rejected fields along, and
+// a spending limit error carries code "limit_exceeded" and the limit that
+// was hit.
+func writeGRPCError(c *gin.Context, err error) {
+	st := status.Convert(err)
+	body := gin.H{"error": st.Message()}
+	if st.Code() == codes.InvalidArgument {
+		var fields []FieldError
+		for _, detail := range st.Details() {
+			if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+				for _, v := range badRequest.GetFieldViolations() {
+					fields = append(fields, FieldError{Field: v.GetField(), Message: v.GetDescription()})
+				}
+			}
+		}
+		body["fields"] = fields
+	}
+	if st.Code() == codes.FailedPrecondition {
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetReason() == limitExceededReason {
+				body["code"] = "limit_exceeded"
+				body["limit"] = info.GetMetadata()
+			}
+		}
+	}
+	c.JSON(httpStatusFromCode(st.Code()), body)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/gateaway/auth"
+	"ewallet/gateaway/model"
+	pb "ewallet/gateaway/proto"
+	"ewallet/pkg/money"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment line, so
+// that proxies do not close the connection.
+const sseKeepAlive = 15 * time.Second
+
+// walletEventFromPB converts a streamed wallet event into its JSON
+// representation.
+func walletEventFromPB(e *pb.WalletEvent) model.WalletEvent {
+	return model.WalletEvent{
+		EventID:       e.GetEventId(),
+		WalletID:      e.GetWalletId(),
+		Type:          e.GetEventType(),
+		TransactionID: e.GetTransactionId(),
+		Amount:        money.Amount(e.GetAmount()),
+		Balance:       money.Amount(e.GetBalance()),
+		Currency:      e.GetCurrency(),
+		CreatedAt:     e.GetCreatedAt().AsTime(),
+	}
+}
+
+// SubscribeWalletEvents streams the balance changes of one of the caller's
+// wallets as Server-Sent Events. Each event carries its event ID, so a
+// reconnecting client resumes where it stopped by sending the standard
+// Last-Event-ID header (or the last_event_id query parameter).
+func (s *Server) SubscribeWalletEvents(c *gin.Context) {
+	walletID, err := strconv.ParseInt(c.Param("walletID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet ID"})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var afterEventID int64
+	if lastEventID != "" {
+		afterEventID, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || afterEventID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last event ID"})
+			return
+		}
+	}
+
+	lookupCtx, cancelLookup := context.WithTimeout(context.Background(), s.RequestTimeout)
+	wallet, err := s.TransactionClient.GetWalletByID(lookupCtx, &pb.GetWalletByIdrequest{Id: int32(walletID)})
+	cancelLookup()
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+	if !auth.RequireSelf(c, wallet.GetWallet().GetUserId()) {
+		return
+	}
+
+	// The stream lives as long as the client stays connected, so it is not
+	// bounded by RequestTimeout.
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stream, err := s.TransactionClient.SubscribeWalletEvents(ctx, &pb.SubscribeWalletEventsRequest{
+		WalletId:     int32(walletID),
+		AfterEventId: afterEventID,
+	})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	events := make(chan *pb.WalletEvent)
+	streamErr := make(chan error, 1)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				streamErr <- err
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-events:
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatInt(event.GetEventId(), 10),
+				Event: "wallet_event",
+				Data:  walletEventFromPB(event),
+			})
+			return true
+		case err := <-streamErr:
+			if !errors.Is(err, io.EOF) && status.Code(err) != codes.Canceled {
+				c.SSEvent("error", gin.H{"error": status.Convert(err).Message()})
+			}
+			return false
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"ewallet/gateaway/auth"
+	"ewallet/gateaway/model"
+	pb "ewallet/gateaway/proto"
+	"ewallet/pkg/money"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// walletLimitsFromPB converts a wallet's limits returned by the wallet
+// service into their JSON representation.
+func walletLimitsFromPB(l *pb.WalletLimits) model.WalletLimits {
+	return model.WalletLimits{
+		WalletID:          l.GetWalletId(),
+		Currency:          l.GetCurrency(),
+		Tier:              l.GetTier(),
+		DailyAmount:       money.Amount(l.GetDailyAmount()),
+		MonthlyAmount:     money.Amount(l.GetMonthlyAmount()),
+		DailyCount:        l.GetDailyCount(),
+		MonthlyCount:      l.GetMonthlyCount(),
+		UsedDailyAmount:   money.Amount(l.GetUsedDailyAmount()),
+		UsedMonthlyAmount: money.Amount(l.GetUsedMonthlyAmount()),
+		UsedDailyCount:    l.GetUsedDailyCount(),
+		UsedMonthlyCount:  l.GetUsedMonthlyCount(),
+	}
+}
+
+// GetWalletLimits returns the spending limits of one of the authenticated
+// user's wallets and how much of them is used.
+func (s *Server) GetWalletLimits(c *gin.Context) {
+	walletID, err := strconv.ParseInt(c.Param("walletID"), 10, 32)
+	if err != nil || walletID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet ID"})
+		return
+	}
+	userID, ok := auth.UserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	if _, ok := s.resolveWallet(ctx, c, int32(userID), int32(walletID), http.StatusForbidden); !ok {
+		return
+	}
+
+	res, err := s.TransactionClient.GetWalletLimits(ctx, &pb.GetWalletLimitsRequest{WalletId: int32(walletID)})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, walletLimitsFromPB(res.GetLimits()))
+}
+
+// SetUserTier moves a user to another spending limit tier, typically after
+// KYC. It is a support route.
+func (s *Server) SetUserTier(c *gin.Context) {
+	var req model.SetUserTierRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.TransactionClient.SetUserTier(ctx, &pb.SetUserTierRequest{UserId: req.UserID, Tier: req.Tier})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": res.GetUserId(), "tier": res.GetTier()})
+}
+
+// SetWalletLimits overrides the tier limits of one wallet. It is a support
+// route.
+func (s *Server) SetWalletLimits(c *gin.Context) {
+	var req model.SetWalletLimitsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	pbReq := &pb.SetWalletLimitsRequest{
+		WalletId:     req.WalletID,
+		DailyCount:   req.DailyCount,
+		MonthlyCount: req.MonthlyCount,
+	}
+	if req.DailyAmount != nil {
+		minor := req.DailyAmount.Minor()
+		pbReq.DailyAmount = &minor
+	}
+	if req.MonthlyAmount != nil {
+		minor := req.MonthlyAmount.Minor()
+		pbReq.MonthlyAmount = &minor
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.TransactionClient.SetWalletLimits(ctx, pbReq)
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, walletLimitsFromPB(res.GetLimits()))
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"ewallet/gateaway/auth"
+	"ewallet/gateaway/model"
+	pb "ewallet/gateaway/proto"
+	"ewallet/pkg/money"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scheduledTransferFromPB converts a standing order returned by the wallet
+// service into its JSON representation.
+func scheduledTransferFromPB(t *pb.ScheduledTransfer) model.ScheduledTransfer {
+	transfer := model.ScheduledTransfer{
+		ID:           t.GetId(),
+		UserID:       t.GetUserId(),
+		FromWalletID: t.GetFromWalletId(),
+		ToWalletID:   t.GetToWalletId(),
+		Amount:       money.Amount(t.GetAmount()),
+		Schedule:     t.GetSchedule(),
+		Description:  t.GetDescription(),
+		Status:       t.GetStatus(),
+		NextRunAt:    t.GetNextRunAt().AsTime(),
+		MaxRuns:      t.GetMaxRuns(),
+		RunCount:     t.GetRunCount(),
+		CreatedAt:    t.GetCreatedAt().AsTime(),
+		UpdatedAt:    t.GetUpdatedAt().AsTime(),
+	}
+	if t.GetLastRunAt() != nil {
+		lastRunAt := t.GetLastRunAt().AsTime()
+		transfer.LastRunAt = &lastRunAt
+	}
+	return transfer
+}
+
+// CreateScheduledTransfer creates a standing order from one of the
+// authenticated user's wallets, the default one unless from_wallet_id is
+// given.
+func (s *Server) CreateScheduledTransfer(c *gin.Context) {
+	var req model.CreateScheduledTransferRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	userID, ok := auth.UserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	walletfrom, ok := s.resolveWallet(ctx, c, int32(userID), req.FromWalletID, http.StatusForbidden)
+	if !ok {
+		return
+	}
+	walletto, ok := s.resolveWallet(ctx, c, req.UserIDTo, req.ToWalletID, http.StatusBadRequest)
+	if !ok {
+		return
+	}
+
+	res, err := s.TransactionClient.CreateScheduledTransfer(ctx, &pb.CreateScheduledTransferRequest{
+		FromWalletId: walletfrom.Id,
+		ToWalletId:   walletto.Id,
+		Amount:       req.Amount.Minor(),
+		Schedule:     req.Schedule,
+		Description:  req.Description,
+		MaxRuns:      req.MaxRuns,
+	})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, scheduledTransferFromPB(res.GetScheduledTransfer()))
+}
+
+func (s *Server) GetScheduledTransfersByUserID(c *gin.Context) {
+	userIDParam := c.Param("userID")
+	userID, err := strconv.ParseInt(userIDParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if !auth.RequireSelf(c, uint32(userID)) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.TransactionClient.GetScheduledTransfersByUserID(ctx, &pb.GetScheduledTransfersByUserIDRequest{UserId: int32(userID)})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	transfers := make([]model.ScheduledTransfer, 0, len(res.GetScheduledTransfers()))
+	for _, t := range res.GetScheduledTransfers() {
+		transfers = append(transfers, scheduledTransferFromPB(t))
+	}
+	c.JSON(http.StatusOK, gin.H{"scheduled_transfers": transfers})
+}
+
+// ownScheduledTransfer parses the scheduleID route parameter and checks that
+// the standing order belongs to the authenticated user. On failure it writes
+// the response and returns false.
+func (s *Server) ownScheduledTransfer(ctx context.Context, c *gin.Context) (int64, bool) {
+	scheduleID, err := strconv.ParseInt(c.Param("scheduleID"), 10, 64)
+	if err != nil || scheduleID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return 0, false
+	}
+
+	res, err := s.TransactionClient.GetScheduledTransfer(ctx, &pb.GetScheduledTransferRequest{ScheduleId: scheduleID})
+	if err != nil {
+		writeGRPCError(c, err)
+		return 0, false
+	}
+	if !auth.RequireSelf(c, res.GetScheduledTransfer().GetUserId()) {
+		return 0, false
+	}
+	return scheduleID, true
+}
+
+// PauseScheduledTransfer stops a standing order until it is resumed.
+func (s *Server) PauseScheduledTransfer(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	scheduleID, ok := s.ownScheduledTransfer(ctx, c)
+	if !ok {
+		return
+	}
+
+	res, err := s.TransactionClient.PauseScheduledTransfer(ctx, &pb.PauseScheduledTransferRequest{ScheduleId: scheduleID})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduledTransferFromPB(res.GetScheduledTransfer()))
+}
+
+// ResumeScheduledTransfer reactivates a paused standing order from its next
+// occurrence.
+func (s *Server) ResumeScheduledTransfer(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	scheduleID, ok := s.ownScheduledTransfer(ctx, c)
+	if !ok {
+		return
+	}
+
+	res, err := s.TransactionClient.ResumeScheduledTransfer(ctx, &pb.ResumeScheduledTransferRequest{ScheduleId: scheduleID})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduledTransferFromPB(res.GetScheduledTransfer()))
+}
+
+// CancelScheduledTransfer ends a standing order for good.
+func (s *Server) CancelScheduledTransfer(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	scheduleID, ok := s.ownScheduledTransfer(ctx, c)
+	if !ok {
+		return
+	}
+
+	res, err := s.TransactionClient.CancelScheduledTransfer(ctx, &pb.CancelScheduledTransferRequest{ScheduleId: scheduleID})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduledTransferFromPB(res.GetScheduledTransfer()))
+}
+
+// GetScheduledTransferRuns returns the run history of a standing order,
+// newest first, with the reason of every failed run.
+func (s *Server) GetScheduledTransferRuns(c *gin.Context) {
+	var query model.ScheduledTransferRunsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	scheduleID, ok := s.ownScheduledTransfer(ctx, c)
+	if !ok {
+		return
+	}
+
+	res, err := s.TransactionClient.GetScheduledTransferRuns(ctx, &pb.GetScheduledTransferRunsRequest{
+		ScheduleId: scheduleID,
+		Limit:      query.Limit,
+	})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	runs := make([]model.ScheduledTransferRun, 0, len(res.GetRuns()))
+	for _, r := range res.GetRuns() {
+		runs = append(runs, model.ScheduledTransferRun{
+			ID:            r.GetId(),
+			ScheduleID:    r.GetScheduleId(),
+			ScheduledFor:  r.GetScheduledFor().AsTime(),
+			Status:        r.GetStatus(),
+			TransactionID: r.GetTransactionId(),
+			Error:         r.GetError(),
+			CreatedAt:     r.GetCreatedAt().AsTime(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
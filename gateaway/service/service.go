@@ -2,37 +2,43 @@ package service
 
 import (
 	"context"
-	"ewallet/gateaway/config"
+	"ewallet/gateaway/auth"
 	"ewallet/gateaway/model"
 	pb "ewallet/gateaway/proto"
+	"ewallet/pkg/config"
+	"ewallet/pkg/money"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Server struct {
 	UserClient        pb.UserServiceClient
 	TransactionClient pb.TransactionServiceClient
+	Auth              *auth.Manager
 	UserID            uint32
+	// SupportUserIDs may use the support routes.
+	SupportUserIDs []uint32
+	// RequestTimeout bounds the downstream calls made for one HTTP request.
+	RequestTimeout time.Duration
 }
 
-func NewServer() *Server {
+func NewServer(cfg config.GatewayConfig) *Server {
 	// User connection
-	connUser, err := grpc.NewClient(config.GetUserAddress(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	connUser, err := grpc.NewClient(cfg.UserAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
 
 	// Transaction connection
-	connTransaction, err := grpc.NewClient(config.GetTransactionAddress(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	connTransaction, err := grpc.NewClient(cfg.WalletAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -40,9 +46,97 @@ func NewServer() *Server {
 	return &Server{
 		UserClient:        pb.NewUserServiceClient(connUser),
 		TransactionClient: pb.NewTransactionServiceClient(connTransaction),
+		Auth:              auth.NewManager(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL),
+		RequestTimeout:    cfg.RequestTimeout,
+		SupportUserIDs:    cfg.SupportUserIDs,
 	}
 }
 
+// walletFromPB converts a wallet returned by the wallet service into its JSON
+// representation, with the balance rendered as a decimal string.
+func walletFromPB(w *pb.Wallet) model.Wallet {
+	return model.Wallet{
+		ID:               w.GetId(),
+		UserID:           w.GetUserId(),
+		Balance:          money.Amount(w.GetBalance()),
+		AvailableBalance: money.Amount(w.GetAvailableBalance()),
+		Currency:         w.GetCurrency(),
+		Name:             w.GetName(),
+		IsDefault:        w.GetIsDefault(),
+		Status:           w.GetStatus(),
+		StatusReason:     w.GetStatusReason(),
+		CreatedAt:        w.GetCreatedAt().AsTime(),
+		UpdatedAt:        w.GetUpdatedAt().AsTime(),
+	}
+}
+
+// walletTransactionFromPB converts a ledger row returned by the wallet
+// service into its JSON representation.
+func walletTransactionFromPB(t *pb.Transaction) model.WalletTransaction {
+	return model.WalletTransaction{
+		TransactionID: t.GetTransactionId(),
+		WalletID:      t.GetWalletId(),
+		Amount:        money.Amount(t.GetAmount()),
+		Type:          t.GetTransactionType(),
+		CreatedAt:     t.GetCreatedAt().AsTime(),
+		Refund:        refundFromPB(t),
+		Conversion:    conversionFromPB(t),
+	}
+}
+
+// refundFromPB extracts the refund links of a ledger row.
+func refundFromPB(t *pb.Transaction) model.Refund {
+	return model.Refund{
+		RefundOf:       t.GetRefundOf(),
+		RefundedAmount: money.Amount(t.GetRefundedAmount()),
+	}
+}
+
+// conversionFromPB extracts the currency conversion of a ledger row.
+func conversionFromPB(t *pb.Transaction) model.Conversion {
+	return model.Conversion{
+		OriginalAmount:    money.Amount(t.GetOriginalAmount()),
+		OriginalCurrency:  t.GetOriginalCurrency(),
+		ConvertedAmount:   money.Amount(t.GetConvertedAmount()),
+		ConvertedCurrency: t.GetConvertedCurrency(),
+		FxRate:            t.GetFxRate(),
+	}
+}
+
+// idempotencyKey returns the key from the request body, falling back to the
+// Idempotency-Key header.
+func idempotencyKey(c *gin.Context, fromBody string) string {
+	if fromBody != "" {
+		return fromBody
+	}
+	return c.GetHeader("Idempotency-Key")
+}
+
+// resolveWallet returns the wallet with walletID, or the default wallet of
+// userID when walletID is zero. An explicit wallet must belong to userID;
+// otherwise resolveWallet writes notOwned and returns false.
+func (s *Server) resolveWallet(ctx context.Context, c *gin.Context, userID, walletID int32, notOwned int) (*pb.Wallet, bool) {
+	if walletID == 0 {
+		res, err := s.TransactionClient.GetWalletByUserID(ctx, &pb.GetWalletByUserIDRequest{UserId: userID})
+		if err != nil {
+			writeGRPCError(c, err)
+			return nil, false
+		}
+		return res.GetWallets(), true
+	}
+
+	res, err := s.TransactionClient.GetWalletByID(ctx, &pb.GetWalletByIdrequest{Id: walletID})
+	if err != nil {
+		writeGRPCError(c, err)
+		return nil, false
+	}
+	if userID != 0 && res.GetWallet().GetUserId() != uint32(userID) {
+		c.JSON(notOwned, gin.H{"error": "wallet does not belong to the user"})
+		return nil, false
+	}
+	return res.GetWallet(), true
+}
+
 func (s *Server) GetUserByID(c *gin.Context) {
 	userIDParam := c.Param("userID")
 	userID, err := strconv.ParseUint(userIDParam, 10, 32)
@@ -50,13 +144,16 @@ func (s *Server) GetUserByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
+	if !auth.RequireSelf(c, uint32(userID)) {
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
 	defer cancel()
 
 	res, err := s.UserClient.GetUserByID(ctx, &pb.GetUserByIDRequest{UserId: uint32(userID)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeGRPCError(c, err)
 		return
 	}
 
@@ -70,17 +167,78 @@ func (s *Server) GetWalletByUserID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
+	if !auth.RequireSelf(c, uint32(userID)) {
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
 	defer cancel()
 
 	res, err := s.TransactionClient.GetWalletByUserID(ctx, &pb.GetWalletByUserIDRequest{UserId: int32(userID)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, walletFromPB(res.GetWallets()))
+}
+
+func (s *Server) GetWalletsByUserID(c *gin.Context) {
+	userIDParam := c.Param("userID")
+	userID, err := strconv.ParseInt(userIDParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if !auth.RequireSelf(c, uint32(userID)) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.TransactionClient.GetWalletsByUserID(ctx, &pb.GetWalletsByUserIDRequest{UserId: int32(userID)})
+	if err != nil {
+		writeGRPCError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, res.GetWallets())
+	wallets := make([]model.Wallet, 0, len(res.GetWallets()))
+	for _, w := range res.GetWallets() {
+		wallets = append(wallets, walletFromPB(w))
+	}
+	c.JSON(http.StatusOK, gin.H{"wallets": wallets})
+}
+
+// CreateWallet opens an additional wallet for the authenticated user.
+func (s *Server) CreateWallet(c *gin.Context) {
+	var req model.CreateWalletRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	userID, ok := auth.UserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.TransactionClient.CreateWallet(ctx, &pb.CreateWalletRequest{
+		Wallet: &pb.Wallet{
+			UserId:    userID,
+			Currency:  strings.ToUpper(req.Currency),
+			Name:      req.Name,
+			IsDefault: req.IsDefault,
+		},
+	})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, walletFromPB(res.GetWallet()))
 }
 
 func (s *Server) CreateUser(c *gin.Context) {
@@ -91,12 +249,12 @@ func (s *Server) CreateUser(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
 	defer cancel()
 
 	res, err := s.UserClient.CreateUser(ctx, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeGRPCError(c, err)
 		return
 	}
 	s.UserID = res.GetUser().UserId
@@ -111,7 +269,7 @@ func (s *Server) CreateUser(c *gin.Context) {
 	// Memanggil RPC CreateWallet
 	_, err = s.TransactionClient.CreateWallet(ctx, &reqW)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeGRPCError(c, err)
 		return
 	}
 
@@ -120,65 +278,101 @@ func (s *Server) CreateUser(c *gin.Context) {
 
 func (s *Server) TransferWallet(c *gin.Context) {
 	var req model.TransferWalletRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !auth.RequireSelf(c, uint32(req.UserIDFrom)) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
 	defer cancel()
 
-	walletfrom, _ := s.TransactionClient.GetWalletByUserID(ctx, &pb.GetWalletByUserIDRequest{UserId: int32(req.UserIDFrom)})
-	walletto, _ := s.TransactionClient.GetWalletByUserID(ctx, &pb.GetWalletByUserIDRequest{UserId: int32(req.UserIDTo)})
+	walletfrom, ok := s.resolveWallet(ctx, c, req.UserIDFrom, req.FromWalletID, http.StatusForbidden)
+	if !ok {
+		return
+	}
+	walletto, ok := s.resolveWallet(ctx, c, req.UserIDTo, req.ToWalletID, http.StatusBadRequest)
+	if !ok {
+		return
+	}
 
 	res, err := s.TransactionClient.TransferWallet(ctx, &pb.TransferWalletRequest{
-		FromWalletId: walletfrom.Wallets.Id,
-		ToWalletId:   walletto.Wallets.Id,
-		Amount:       req.Amount,
+		FromWalletId:   walletfrom.Id,
+		ToWalletId:     walletto.Id,
+		Amount:         req.Amount.Minor(),
+		IdempotencyKey: idempotencyKey(c, req.IdempotencyKey),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeGRPCError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": res.Message})
+	c.JSON(http.StatusOK, gin.H{
+		"message":     res.Message,
+		"transaction": walletTransactionFromPB(res.GetTransaction()),
+	})
 }
 
+// TopUp credits a user's wallet with money received outside the platform.
+// Nothing backs the credit but the caller's word, so it is a support route.
 func (s *Server) TopUp(c *gin.Context) {
 	var req model.TopUpRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
 	defer cancel()
 
-	wallet, err := s.TransactionClient.GetWalletByUserID(ctx, &pb.GetWalletByUserIDRequest{UserId: int32(req.UserID)})
-	if err != nil {
-		st, ok := status.FromError(err)
-		if ok && st.Code() == codes.NotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+	wallet, ok := s.resolveWallet(ctx, c, req.UserID, req.WalletID, http.StatusBadRequest)
+	if !ok {
 		return
 	}
 
 	res, err := s.TransactionClient.TopUp(ctx, &pb.TopUpRequest{
-		WalletId: wallet.Wallets.Id,
-		Amount:   req.Amount,
+		WalletId:       wallet.Id,
+		Amount:         req.Amount.Minor(),
+		IdempotencyKey: idempotencyKey(c, req.IdempotencyKey),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeGRPCError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "Top-up successful",
-		"transaction": res.Transaction,
+		"transaction": walletTransactionFromPB(res.GetTransaction()),
 	})
 }
+
+// Refund reverses all or part of a payment or a transfer. It is a support
+// route: the wallet service does not check who asks.
+func (s *Server) Refund(c *gin.Context) {
+	var req model.RefundRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.TransactionClient.Refund(ctx, &pb.RefundRequest{
+		TransactionId:  req.TransactionID,
+		Amount:         req.Amount.Minor(),
+		IdempotencyKey: idempotencyKey(c, req.IdempotencyKey),
+	})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Refund successful",
+		"transaction": walletTransactionFromPB(res.GetTransaction()),
+	})
+}
+
 func (s *Server) GetTransactionByUserID(c *gin.Context) {
 	userIDParam := c.Param("userID")
 	userID, err := strconv.ParseInt(userIDParam, 10, 32)
@@ -186,66 +380,139 @@ func (s *Server) GetTransactionByUserID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
+	if !auth.RequireSelf(c, uint32(userID)) {
+		return
+	}
+	var query model.TransactionHistoryQuery
+	if !bindQuery(c, &query) {
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
 	defer cancel()
 	userres, err := s.UserClient.GetUserByID(ctx, &pb.GetUserByIDRequest{UserId: uint32(userID)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error1": status.Convert(err).Message()})
+		writeGRPCError(c, err)
 		return
 	}
 
-	res, err := s.TransactionClient.GetTransactionByUserID(ctx, &pb.GetTransactionByUserIDRequest{UserId: int32(userID)})
+	req := &pb.GetTransactionByUserIDRequest{
+		UserId:               int32(userID),
+		PageSize:             query.PageSize,
+		Cursor:               query.Cursor,
+		TransactionType:      query.Type,
+		CounterpartyWalletId: query.CounterpartyWalletID,
+		CounterpartyUserId:   query.CounterpartyUserID,
+		MinAmount:            query.MinAmount.Minor(),
+		MaxAmount:            query.MaxAmount.Minor(),
+	}
+	if !query.From.IsZero() {
+		req.CreatedFrom = timestamppb.New(query.From)
+	}
+	if !query.To.IsZero() {
+		req.CreatedTo = timestamppb.New(query.To)
+	}
+
+	res, err := s.TransactionClient.GetTransactionByUserID(ctx, req)
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	counterparties, err := s.counterparties(ctx, res.GetTransactions())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": status.Convert(err).Message()})
+		writeGRPCError(c, err)
 		return
 	}
 
 	// Prepare response
-	var transactions []model.Transaction
+	transactions := []model.Transaction{}
 	for _, t := range res.GetTransactions() {
-		// log.Printf("walletsource: %+v", t.Walletidsource)
-		res_wall, err := s.TransactionClient.GetWalletByID(ctx, &pb.GetWalletByIdrequest{Id: int32(t.Walletidsource)})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error4": status.Convert(err).Message()})
-			return
-		}
-		// log.Printf("result: %+v", res_wall)
-
-		userRes_wall, err := s.UserClient.GetUserByID(ctx, &pb.GetUserByIDRequest{UserId: uint32(res_wall.Wallet.UserId)})
 		var sourceUserID uint32
 		var sourceUserName string
 
-		// log.Printf("result: %+v", res_wall)
-
-		if err == nil && userRes_wall.User != nil {
-			sourceUserID = userRes_wall.User.UserId
-			sourceUserName = userRes_wall.User.Username
-		} else {
-			sourceUserID = 0
-			sourceUserName = ""
+		// Top-ups and payments have no counterparty wallet
+		if user := counterparties[t.Walletidsource]; user != nil {
+			sourceUserID = user.UserId
+			sourceUserName = user.Username
 		}
 
 		transaction := model.Transaction{
 			TransactionID:  t.TransactionId,
 			UserID:         uint32(userID),
 			Username:       userres.User.Username,
-			Amount:         t.Amount,
+			Amount:         money.Amount(t.Amount),
 			Type:           t.TransactionType,
 			CreatedAt:      t.CreatedAt.AsTime(),
 			SourceUserID:   sourceUserID,
 			SourceUserName: sourceUserName,
+			MerchantID:     t.GetMerchantId(),
+			Refund:         refundFromPB(t),
+			Conversion:     conversionFromPB(t),
 		}
 		transactions = append(transactions, transaction)
 	}
 
 	response := model.TransactionsResponse{
 		Transactions: transactions,
+		NextCursor:   res.GetNextCursor(),
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// counterparties returns the owner of every counterparty wallet in txs,
+// keyed by wallet ID. Each distinct wallet and user is fetched once, with
+// one batch call to each service. Users that cannot be fetched are left
+// out, so the history still renders without their names.
+func (s *Server) counterparties(ctx context.Context, txs []*pb.Transaction) (map[int32]*pb.User, error) {
+	owners := map[int32]*pb.User{}
+
+	var walletIDs []int32
+	seenWallets := map[int32]bool{}
+	for _, t := range txs {
+		if t.Walletidsource != 0 && !seenWallets[t.Walletidsource] {
+			seenWallets[t.Walletidsource] = true
+			walletIDs = append(walletIDs, t.Walletidsource)
+		}
+	}
+	if len(walletIDs) == 0 {
+		return owners, nil
+	}
+
+	wallets, err := s.TransactionClient.GetWalletsByIDs(ctx, &pb.GetWalletsByIDsRequest{Ids: walletIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []uint32
+	walletOwners := map[int32]uint32{}
+	seenUsers := map[uint32]bool{}
+	for _, w := range wallets.GetWallets() {
+		walletOwners[w.GetId()] = w.GetUserId()
+		if !seenUsers[w.GetUserId()] {
+			seenUsers[w.GetUserId()] = true
+			userIDs = append(userIDs, w.GetUserId())
+		}
+	}
+
+	users, err := s.UserClient.GetUsersByIDs(ctx, &pb.GetUsersByIDsRequest{UserIds: userIDs})
+	if err != nil {
+		log.Printf("failed to look up counterparty users: %v", err)
+		return owners, nil
+	}
+	byID := make(map[uint32]*pb.User, len(users.GetUsers()))
+	for _, u := range users.GetUsers() {
+		byID[u.GetUserId()] = u
+	}
+	for walletID, userID := range walletOwners {
+		if u, ok := byID[userID]; ok {
+			owners[walletID] = u
+		}
+	}
+	return owners, nil
+}
+
 func (s *Server) GetUserAndBalanceWallet(c *gin.Context) {
 	userIDParam := c.Param("userID")
 	userID, err := strconv.ParseInt(userIDParam, 10, 32)
@@ -253,34 +520,38 @@ func (s *Server) GetUserAndBalanceWallet(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
+	if !auth.RequireSelf(c, uint32(userID)) {
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
 	defer cancel()
 
 	// Fetch user details
 	userRes, err := s.UserClient.GetUserByID(ctx, &pb.GetUserByIDRequest{UserId: uint32(userID)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": status.Convert(err).Message()})
+		writeGRPCError(c, err)
 		return
 	}
 
 	// Fetch wallet details
 	walletRes, err := s.TransactionClient.GetWalletByUserID(ctx, &pb.GetWalletByUserIDRequest{UserId: int32(userID)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": status.Convert(err).Message()})
+		writeGRPCError(c, err)
 		return
 	}
 
 	// Prepare combined response
 	user := userRes.GetUser()
-	wallet := walletRes.GetWallets() // Assuming only one wallet per user
+	wallet := walletRes.GetWallets() // The user's default wallet
 
 	response := model.UserBalance{
-		UserID:    user.UserId,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.AsTime(),
-		Balance:   wallet.Balance,
+		UserID:           user.UserId,
+		Username:         user.Username,
+		Email:            user.Email,
+		CreatedAt:        user.CreatedAt.AsTime(),
+		Balance:          money.Amount(wallet.Balance),
+		AvailableBalance: money.Amount(wallet.AvailableBalance),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"ewallet/gateaway/auth"
+	"ewallet/gateaway/model"
+	pb "ewallet/gateaway/proto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetWalletStatus freezes or unfreezes a wallet on behalf of the
+// authenticated support user. It is a support route.
+func (s *Server) SetWalletStatus(c *gin.Context) {
+	var req model.SetWalletStatusRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	supportID, ok := auth.UserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.TransactionClient.SetWalletStatus(ctx, &pb.SetWalletStatusRequest{
+		WalletId:  req.WalletID,
+		Status:    req.Status,
+		Reason:    req.Reason,
+		ChangedBy: supportID,
+	})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, walletFromPB(res.GetWallet()))
+}
+
+// CloseWallet closes a wallet for good on behalf of the authenticated
+// support user. It is a support route.
+func (s *Server) CloseWallet(c *gin.Context) {
+	var req model.CloseWalletRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	supportID, ok := auth.UserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.RequestTimeout)
+	defer cancel()
+
+	res, err := s.TransactionClient.CloseWallet(ctx, &pb.CloseWalletRequest{
+		WalletId:        req.WalletID,
+		SweepToWalletId: req.SweepToWalletID,
+		Reason:          req.Reason,
+		ChangedBy:       supportID,
+	})
+	if err != nil {
+		writeGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, walletFromPB(res.GetWallet()))
+}
@@ -0,0 +1,370 @@
+// Package config loads the settings of the user, wallet and gateway
+// services.
+//
+// Settings are resolved in three layers: built-in defaults, then an optional
+// YAML file named by the EWALLET_CONFIG environment variable, then individual
+// environment variables, which always win. Each service validates only the
+// section it uses, so a missing secret fails at startup with the name of the
+// setting and the variable that provides it.
+package config
+
+import (
+	"errors"
+	"ewallet/pkg/money"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable holding the optional config file path.
+const FileEnv = "EWALLET_CONFIG"
+
+// Config holds the settings of every service.
+type Config struct {
+	User    UserConfig    `yaml:"user"`
+	Wallet  WalletConfig  `yaml:"wallet"`
+	Gateway GatewayConfig `yaml:"gateway"`
+}
+
+// UserConfig configures the user service.
+type UserConfig struct {
+	DSN        string `yaml:"dsn"`
+	ListenAddr string `yaml:"listen_addr"`
+	// WalletAddr is the wallet service, asked before deleting a user
+	// whether any of the user's wallets is still open.
+	WalletAddr string `yaml:"wallet_addr"`
+	// WalletTimeout bounds each call to the wallet service.
+	WalletTimeout time.Duration `yaml:"wallet_timeout"`
+}
+
+// WalletConfig configures the wallet service.
+type WalletConfig struct {
+	DSN        string `yaml:"dsn"`
+	ListenAddr string `yaml:"listen_addr"`
+	// FXRatesFile is an optional YAML file of exchange rates. Without it,
+	// transfers between wallets of different currencies are rejected.
+	FXRatesFile string `yaml:"fx_rates_file"`
+	// RiskRulesFile is an optional YAML file of fraud screening rules.
+	// Without it, top-ups, payments and transfers are not screened.
+	RiskRulesFile string        `yaml:"risk_rules_file"`
+	Outbox        OutboxConfig  `yaml:"outbox"`
+	Webhooks      WebhookConfig `yaml:"webhooks"`
+	// AuthorizationExpiryInterval is how often holds of expired payment
+	// authorizations are released.
+	AuthorizationExpiryInterval time.Duration `yaml:"authorization_expiry_interval"`
+	// SchedulerPollInterval is how often due scheduled transfers are
+	// executed.
+	SchedulerPollInterval time.Duration `yaml:"scheduler_poll_interval"`
+	// Limits caps what each wallet may spend, by the tier of its owner. A
+	// tier set in the config file replaces its default entirely.
+	Limits map[string]TierLimits `yaml:"limits"`
+}
+
+// Tiers of wallet owners. Users are unverified until support marks them
+// verified after KYC.
+const (
+	TierUnverified = "unverified"
+	TierVerified   = "verified"
+)
+
+// TierLimits caps the payments and outgoing transfers of a wallet per UTC
+// calendar day and month. Amounts are decimal strings keyed by currency; a
+// currency without an amount, and a zero count, are not limited.
+type TierLimits struct {
+	DailyAmount   map[string]string `yaml:"daily_amount"`
+	MonthlyAmount map[string]string `yaml:"monthly_amount"`
+	DailyCount    int               `yaml:"daily_count"`
+	MonthlyCount  int               `yaml:"monthly_count"`
+}
+
+// WebhookConfig configures the delivery of merchant payment webhooks.
+type WebhookConfig struct {
+	// Timeout bounds a single delivery attempt.
+	Timeout      time.Duration `yaml:"timeout"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// MaxAttempts is the number of attempts, backing off exponentially,
+	// before a delivery is marked failed.
+	MaxAttempts int `yaml:"max_attempts"`
+}
+
+// OutboxConfig configures the relay publishing the wallet service's domain
+// events. With no publisher the relay does not run and events wait in the
+// outbox table.
+type OutboxConfig struct {
+	// Publisher is "", "log" or "webhook".
+	Publisher      string        `yaml:"publisher"`
+	WebhookURL     string        `yaml:"webhook_url"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	MaxAttempts    int           `yaml:"max_attempts"`
+}
+
+// GatewayConfig configures the HTTP gateway.
+type GatewayConfig struct {
+	HTTPAddr        string        `yaml:"http_addr"`
+	UserAddr        string        `yaml:"user_addr"`
+	WalletAddr      string        `yaml:"wallet_addr"`
+	RequestTimeout  time.Duration `yaml:"request_timeout"`
+	JWTSecret       string        `yaml:"jwt_secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+	// SupportUserIDs are the users allowed on support routes such as
+	// top-ups and refunds. With none configured those routes always answer
+	// 403.
+	SupportUserIDs []uint32 `yaml:"support_user_ids"`
+}
+
+// Default returns the settings used when neither the file nor the
+// environment provide a value. DSNs and secrets have no default.
+func Default() Config {
+	return Config{
+		User: UserConfig{
+			ListenAddr:    ":50052",
+			WalletAddr:    "localhost:50051",
+			WalletTimeout: 5 * time.Second,
+		},
+		Wallet: WalletConfig{
+			ListenAddr: ":50051",
+			Outbox: OutboxConfig{
+				WebhookTimeout: 10 * time.Second,
+				PollInterval:   time.Second,
+				MaxAttempts:    10,
+			},
+			Webhooks: WebhookConfig{
+				Timeout:      10 * time.Second,
+				PollInterval: time.Second,
+				MaxAttempts:  12,
+			},
+			AuthorizationExpiryInterval: time.Minute,
+			SchedulerPollInterval:       30 * time.Second,
+			Limits: map[string]TierLimits{
+				TierUnverified: {
+					DailyAmount:   map[string]string{"IDR": "5000000.00", "SGD": "400.00", "USD": "300.00"},
+					MonthlyAmount: map[string]string{"IDR": "20000000.00", "SGD": "1600.00", "USD": "1200.00"},
+					DailyCount:    50,
+					MonthlyCount:  500,
+				},
+				TierVerified: {
+					DailyAmount:   map[string]string{"IDR": "50000000.00", "SGD": "4000.00", "USD": "3000.00"},
+					MonthlyAmount: map[string]string{"IDR": "200000000.00", "SGD": "16000.00", "USD": "12000.00"},
+					DailyCount:    200,
+					MonthlyCount:  2000,
+				},
+			},
+		},
+		Gateway: GatewayConfig{
+			HTTPAddr:        ":8080",
+			UserAddr:        "localhost:50052",
+			WalletAddr:      "localhost:50051",
+			RequestTimeout:  time.Second,
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+		},
+	}
+}
+
+// Load resolves the configuration from defaults, the optional file named by
+// EWALLET_CONFIG and the environment. It does not validate; call the
+// Validate method of the section the service uses.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv(FileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: reading %s: %w", path, err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("config: parsing %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// applyEnv overrides cfg with every environment variable that is set.
+func applyEnv(cfg *Config) error {
+	strs := []struct {
+		env string
+		dst *string
+	}{
+		{"EWALLET_USER_DSN", &cfg.User.DSN},
+		{"EWALLET_USER_LISTEN_ADDR", &cfg.User.ListenAddr},
+		{"EWALLET_USER_WALLET_ADDR", &cfg.User.WalletAddr},
+		{"EWALLET_WALLET_DSN", &cfg.Wallet.DSN},
+		{"EWALLET_WALLET_LISTEN_ADDR", &cfg.Wallet.ListenAddr},
+		{"EWALLET_WALLET_FX_RATES_FILE", &cfg.Wallet.FXRatesFile},
+		{"EWALLET_WALLET_RISK_RULES_FILE", &cfg.Wallet.RiskRulesFile},
+		{"EWALLET_WALLET_OUTBOX_PUBLISHER", &cfg.Wallet.Outbox.Publisher},
+		{"EWALLET_WALLET_OUTBOX_WEBHOOK_URL", &cfg.Wallet.Outbox.WebhookURL},
+		{"EWALLET_GATEWAY_HTTP_ADDR", &cfg.Gateway.HTTPAddr},
+		{"EWALLET_GATEWAY_USER_ADDR", &cfg.Gateway.UserAddr},
+		{"EWALLET_GATEWAY_WALLET_ADDR", &cfg.Gateway.WalletAddr},
+		{"EWALLET_JWT_SECRET", &cfg.Gateway.JWTSecret},
+	}
+	for _, s := range strs {
+		if v, ok := os.LookupEnv(s.env); ok {
+			*s.dst = v
+		}
+	}
+
+	durations := []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"EWALLET_USER_WALLET_TIMEOUT", &cfg.User.WalletTimeout},
+		{"EWALLET_GATEWAY_REQUEST_TIMEOUT", &cfg.Gateway.RequestTimeout},
+		{"EWALLET_ACCESS_TOKEN_TTL", &cfg.Gateway.AccessTokenTTL},
+		{"EWALLET_REFRESH_TOKEN_TTL", &cfg.Gateway.RefreshTokenTTL},
+		{"EWALLET_WALLET_OUTBOX_WEBHOOK_TIMEOUT", &cfg.Wallet.Outbox.WebhookTimeout},
+		{"EWALLET_WALLET_OUTBOX_POLL_INTERVAL", &cfg.Wallet.Outbox.PollInterval},
+		{"EWALLET_WALLET_WEBHOOK_TIMEOUT", &cfg.Wallet.Webhooks.Timeout},
+		{"EWALLET_WALLET_WEBHOOK_POLL_INTERVAL", &cfg.Wallet.Webhooks.PollInterval},
+		{"EWALLET_WALLET_AUTHORIZATION_EXPIRY_INTERVAL", &cfg.Wallet.AuthorizationExpiryInterval},
+		{"EWALLET_WALLET_SCHEDULER_POLL_INTERVAL", &cfg.Wallet.SchedulerPollInterval},
+	}
+	for _, d := range durations {
+		v, ok := os.LookupEnv(d.env)
+		if !ok {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", d.env, err)
+		}
+		*d.dst = parsed
+	}
+
+	ints := []struct {
+		env string
+		dst *int
+	}{
+		{"EWALLET_WALLET_OUTBOX_MAX_ATTEMPTS", &cfg.Wallet.Outbox.MaxAttempts},
+		{"EWALLET_WALLET_WEBHOOK_MAX_ATTEMPTS", &cfg.Wallet.Webhooks.MaxAttempts},
+	}
+	for _, i := range ints {
+		v, ok := os.LookupEnv(i.env)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", i.env, err)
+		}
+		*i.dst = parsed
+	}
+
+	// Lists are comma separated.
+	if v, ok := os.LookupEnv("EWALLET_GATEWAY_SUPPORT_USER_IDS"); ok {
+		cfg.Gateway.SupportUserIDs = nil
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return fmt.Errorf("config: EWALLET_GATEWAY_SUPPORT_USER_IDS: %w", err)
+			}
+			cfg.Gateway.SupportUserIDs = append(cfg.Gateway.SupportUserIDs, uint32(id))
+		}
+	}
+	return nil
+}
+
+// problems collects validation failures for one section.
+type problems []string
+
+func (p *problems) require(ok bool, setting, env string) {
+	if !ok {
+		*p = append(*p, fmt.Sprintf("%s is required (set %s or %s in the config file)", setting, env, setting))
+	}
+}
+
+func (p *problems) positive(d time.Duration, setting, env string) {
+	if d <= 0 {
+		*p = append(*p, fmt.Sprintf("%s must be a positive duration (%s)", setting, env))
+	}
+}
+
+func (p problems) err(section string) error {
+	if len(p) == 0 {
+		return nil
+	}
+	return errors.New("config: invalid " + section + " settings: " + strings.Join(p, "; "))
+}
+
+// Validate checks the user service settings.
+func (c UserConfig) Validate() error {
+	var p problems
+	p.require(c.DSN != "", "user.dsn", "EWALLET_USER_DSN")
+	p.require(c.ListenAddr != "", "user.listen_addr", "EWALLET_USER_LISTEN_ADDR")
+	p.require(c.WalletAddr != "", "user.wallet_addr", "EWALLET_USER_WALLET_ADDR")
+	p.positive(c.WalletTimeout, "user.wallet_timeout", "EWALLET_USER_WALLET_TIMEOUT")
+	return p.err("user")
+}
+
+// Validate checks the wallet service settings.
+func (c WalletConfig) Validate() error {
+	var p problems
+	p.require(c.DSN != "", "wallet.dsn", "EWALLET_WALLET_DSN")
+	p.require(c.ListenAddr != "", "wallet.listen_addr", "EWALLET_WALLET_LISTEN_ADDR")
+	switch c.Outbox.Publisher {
+	case "", "log":
+	case "webhook":
+		p.require(c.Outbox.WebhookURL != "", "wallet.outbox.webhook_url", "EWALLET_WALLET_OUTBOX_WEBHOOK_URL")
+		p.positive(c.Outbox.WebhookTimeout, "wallet.outbox.webhook_timeout", "EWALLET_WALLET_OUTBOX_WEBHOOK_TIMEOUT")
+	default:
+		p = append(p, fmt.Sprintf("wallet.outbox.publisher must be empty, log or webhook, not %q", c.Outbox.Publisher))
+	}
+	p.positive(c.Outbox.PollInterval, "wallet.outbox.poll_interval", "EWALLET_WALLET_OUTBOX_POLL_INTERVAL")
+	if c.Outbox.MaxAttempts <= 0 {
+		p = append(p, "wallet.outbox.max_attempts must be positive (EWALLET_WALLET_OUTBOX_MAX_ATTEMPTS)")
+	}
+	p.positive(c.Webhooks.Timeout, "wallet.webhooks.timeout", "EWALLET_WALLET_WEBHOOK_TIMEOUT")
+	p.positive(c.Webhooks.PollInterval, "wallet.webhooks.poll_interval", "EWALLET_WALLET_WEBHOOK_POLL_INTERVAL")
+	if c.Webhooks.MaxAttempts <= 0 {
+		p = append(p, "wallet.webhooks.max_attempts must be positive (EWALLET_WALLET_WEBHOOK_MAX_ATTEMPTS)")
+	}
+	p.positive(c.AuthorizationExpiryInterval, "wallet.authorization_expiry_interval", "EWALLET_WALLET_AUTHORIZATION_EXPIRY_INTERVAL")
+	p.positive(c.SchedulerPollInterval, "wallet.scheduler_poll_interval", "EWALLET_WALLET_SCHEDULER_POLL_INTERVAL")
+	if _, ok := c.Limits[TierUnverified]; !ok {
+		p = append(p, "wallet.limits must define the "+TierUnverified+" tier")
+	}
+	for tier, limits := range c.Limits {
+		for name, amounts := range map[string]map[string]string{"daily_amount": limits.DailyAmount, "monthly_amount": limits.MonthlyAmount} {
+			for currency, text := range amounts {
+				if amount, err := money.Parse(text); err != nil || amount < 0 || !money.IsSupportedCurrency(currency) {
+					p = append(p, fmt.Sprintf("wallet.limits.%s.%s.%s must be a non-negative amount in a supported currency", tier, name, currency))
+				}
+			}
+		}
+		if limits.DailyCount < 0 || limits.MonthlyCount < 0 {
+			p = append(p, fmt.Sprintf("wallet.limits.%s counts must not be negative", tier))
+		}
+	}
+	return p.err("wallet")
+}
+
+// Validate checks the gateway settings.
+func (c GatewayConfig) Validate() error {
+	var p problems
+	p.require(c.HTTPAddr != "", "gateway.http_addr", "EWALLET_GATEWAY_HTTP_ADDR")
+	p.require(c.UserAddr != "", "gateway.user_addr", "EWALLET_GATEWAY_USER_ADDR")
+	p.require(c.WalletAddr != "", "gateway.wallet_addr", "EWALLET_GATEWAY_WALLET_ADDR")
+	p.require(c.JWTSecret != "", "gateway.jwt_secret", "EWALLET_JWT_SECRET")
+	if c.JWTSecret != "" && len(c.JWTSecret) < 32 {
+		p = append(p, "gateway.jwt_secret must be at least 32 bytes")
+	}
+	p.positive(c.RequestTimeout, "gateway.request_timeout", "EWALLET_GATEWAY_REQUEST_TIMEOUT")
+	p.positive(c.AccessTokenTTL, "gateway.access_token_ttl", "EWALLET_ACCESS_TOKEN_TTL")
+	p.positive(c.RefreshTokenTTL, "gateway.refresh_token_ttl", "EWALLET_REFRESH_TOKEN_TTL")
+	return p.err("gateway")
+}
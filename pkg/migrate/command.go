@@ -0,0 +1,59 @@
+package migrate
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// Usage describes the arguments accepted by Run.
+const Usage = "migrate up|down|status [-steps N]"
+
+// Run implements the "migrate" subcommand shared by the service binaries:
+//
+//	migrate up [-steps N]    apply pending migrations (all by default)
+//	migrate down [-steps N]  revert the latest migrations (one by default)
+//	migrate status           list migrations and whether they are applied
+func Run(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s", Usage)
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	steps := fs.Int("steps", 0, "number of migrations to apply or revert")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		done, err := m.Up(ctx, *steps)
+		for _, mig := range done {
+			fmt.Fprintf(out, "applied  %04d_%s\n", mig.Version, mig.Name)
+		}
+		return err
+	case "down":
+		done, err := m.Down(ctx, *steps)
+		for _, mig := range done {
+			fmt.Fprintf(out, "reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		applied, err := m.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.Migrations() {
+			state := "pending"
+			if applied[mig.Version] {
+				state = "applied"
+			}
+			fmt.Fprintf(out, "%-8s %04d_%s\n", state, mig.Version, mig.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate action %q; usage: %s", action, Usage)
+	}
+}
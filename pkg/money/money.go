@@ -0,0 +1,154 @@
+// Package money represents monetary amounts as integer minor units so that
+// balances never drift the way binary floating point does.
+//
+// Rounding rules: amounts are never rounded implicitly. Parsing rejects any
+// value with more fractional digits than the currency has minor units, and
+// values that do not fit in an int64. The only rounding is in currency
+// conversion (Rate.Convert), which rounds half to even to the minor unit.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of minor units in one major unit. Every currency the
+// wallet handles (IDR, SGD, USD) has two decimal places.
+const Scale = 100
+
+// scaleDigits is the number of fractional digits implied by Scale.
+const scaleDigits = 2
+
+// Amount is a monetary amount in minor units (for example cents or sen).
+type Amount int64
+
+var (
+	// ErrInvalidAmount is returned when a string is not a plain decimal number.
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrTooPrecise is returned when an amount has more fractional digits than
+	// the currency's minor unit allows.
+	ErrTooPrecise = errors.New("amount has more than 2 decimal places")
+	// ErrOverflow is returned when an amount does not fit in an int64.
+	ErrOverflow = errors.New("amount out of range")
+)
+
+// Parse converts a decimal string such as "12", "12.5" or "-0.05" into an
+// Amount. Exponents, NaN and infinities are rejected.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > scaleDigits {
+		return 0, ErrTooPrecise
+	}
+	frac += strings.Repeat("0", scaleDigits-len(frac))
+
+	if whole == "" {
+		whole = "0"
+	}
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrOverflow
+	}
+	if neg {
+		minor = -minor
+	}
+	return Amount(minor), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount in minor units.
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// String formats the amount as a decimal with exactly two fractional digits.
+func (a Amount) String() string {
+	sign, v := "", uint64(a)
+	if a < 0 {
+		sign, v = "-", uint64(-(a+1))+1
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, v/Scale, scaleDigits, v%Scale)
+}
+
+// MarshalJSON encodes the amount as a decimal string, e.g. "12.50", so that
+// JSON clients never see a binary floating point value.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts either a JSON string ("12.50") or a JSON number
+// (12.5). Numbers are parsed from their literal text, never through float64.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	parsed, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// UnmarshalParam parses an amount from a URL query or form value, so that
+// gin can bind it like any other field.
+func (a *Amount) UnmarshalParam(param string) error {
+	parsed, err := Parse(param)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// DefaultCurrency is the currency of wallets created without one.
+const DefaultCurrency = "IDR"
+
+// currencies lists the ISO 4217 codes the wallet supports. All of them have
+// two decimal places, matching Scale. A new currency also needs default
+// spending limits in config.Default, or its wallets are not limited.
+var currencies = map[string]bool{
+	"IDR": true,
+	"SGD": true,
+	"USD": true,
+}
+
+// IsSupportedCurrency reports whether code is a supported ISO 4217 code.
+func IsSupportedCurrency(code string) bool {
+	return currencies[code]
+}
@@ -0,0 +1,136 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RateScale is the number of rate units in 1. Exchange rates carry ten
+// decimal places, enough for both 16250 (USD to IDR) and 0.0000615 (IDR to
+// USD) without losing precision.
+const RateScale = 10_000_000_000
+
+// rateDigits is the number of fractional digits implied by RateScale.
+const rateDigits = 10
+
+// Rate is an exchange rate in units of 1/RateScale: converting an amount
+// multiplies it by the rate.
+type Rate int64
+
+// One is the rate between a currency and itself.
+const One Rate = RateScale
+
+// ErrInvalidRate is returned when a string is not a positive decimal rate.
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// ParseRate converts a decimal string such as "16250" or "0.0000615" into a
+// Rate. The rate must be positive and have at most ten decimal places.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if (whole == "" && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > rateDigits {
+		return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidRate, s, rateDigits)
+	}
+	frac += strings.Repeat("0", rateDigits-len(frac))
+
+	if whole == "" {
+		whole = "0"
+	}
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidRate, s)
+	}
+	if units == 0 {
+		return 0, fmt.Errorf("%w: %q is not positive", ErrInvalidRate, s)
+	}
+	return Rate(units), nil
+}
+
+// String formats the rate with its significant fractional digits, e.g.
+// "16250" or "0.0000615".
+func (r Rate) String() string {
+	whole := fmt.Sprintf("%d", int64(r)/RateScale)
+	frac := strings.TrimRight(fmt.Sprintf("%0*d", rateDigits, int64(r)%RateScale), "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// Inverse returns 1/r, rounded half to even at the tenth decimal place.
+func (r Rate) Inverse() (Rate, error) {
+	if r <= 0 {
+		return 0, fmt.Errorf("%w: %s has no inverse", ErrInvalidRate, r)
+	}
+	scale := big.NewInt(RateScale)
+	num := new(big.Int).Mul(scale, scale)
+	inv := divHalfEven(num, big.NewInt(int64(r)))
+	if !inv.IsInt64() || inv.Sign() == 0 {
+		return 0, fmt.Errorf("%w: inverse of %s is out of range", ErrInvalidRate, r)
+	}
+	return Rate(inv.Int64()), nil
+}
+
+// Convert returns a*r, rounded half to even to the nearest minor unit.
+func (r Rate) Convert(a Amount) (Amount, error) {
+	product := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(r)))
+	converted := divHalfEven(product, big.NewInt(RateScale))
+	if !converted.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return Amount(converted.Int64()), nil
+}
+
+// divHalfEven returns x/y rounded half to even. y must be positive.
+func divHalfEven(x, y *big.Int) *big.Int {
+	q, m := new(big.Int).DivMod(x, y, new(big.Int))
+	// DivMod is Euclidean: m >= 0 and q is rounded towards negative infinity.
+	switch new(big.Int).Lsh(m, 1).Cmp(y) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// Value stores the rate as a decimal string, suitable for a numeric column.
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Scan reads a rate from a numeric column.
+func (r *Rate) Scan(src interface{}) error {
+	var text string
+	switch v := src.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case int64:
+		text = strconv.FormatInt(v, 10)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidRate, src)
+	}
+	parsed, err := ParseRate(text)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// MarshalJSON encodes the rate as a decimal string.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(r.String())), nil
+}
@@ -0,0 +1,116 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{"16250", 16250 * One, false},
+		{"0.0000615", 615_000, false},
+		{"1.3425", 13_425_000_000, false},
+		{".5", One / 2, false},
+		{"2.50000000000", 25_000_000_000, false},
+		{"0.00000000001", 0, true},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"", 0, true},
+		{"1e3", 0, true},
+		{"9223372036", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("ParseRate(%q) error = %v, want %v", tt.in, err, ErrInvalidRate)
+		}
+	}
+}
+
+func TestConvertRoundsHalfToEven(t *testing.T) {
+	half := One / 2
+	tests := []struct {
+		name   string
+		amount Amount
+		rate   Rate
+		want   Amount
+	}{
+		{"tie rounds down to even", 1, half, 0},
+		{"tie rounds up to even", 3, half, 2},
+		{"tie at 2.5", 5, half, 2},
+		{"tie at 3.5", 7, half, 4},
+		{"below tie", 1, half - 1, 0},
+		{"above tie", 1, half + 1, 1},
+		{"negative tie to even", -3, half, -2},
+		{"negative tie to zero", -1, half, 0},
+		{"negative above tie", -1, half + 1, -1},
+		{"identity", 123_456, One, 123_456},
+		{"usd to idr", 1001, 16250 * One, 16_266_250},
+		{"idr to usd", 1_625_000, 615_000, 100},
+		{"fractional rate tie", 300, 162_501_250_000_000, 4_875_038},
+		{"zero", 0, 16250 * One, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rate.Convert(tt.amount)
+			if err != nil || got != tt.want {
+				t.Fatalf("%s.Convert(%d) = %d, %v; want %d", tt.rate, tt.amount, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertOverflow(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Amount
+		rate   Rate
+	}{
+		{"max amount doubled", math.MaxInt64, 2 * One},
+		{"min amount doubled", math.MinInt64, 2 * One},
+		{"large amount at large rate", math.MaxInt64 / 10_000, 16250 * One},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.rate.Convert(tt.amount); !errors.Is(err, ErrOverflow) {
+				t.Fatalf("%s.Convert(%d) = %d, %v; want %v", tt.rate, tt.amount, got, err, ErrOverflow)
+			}
+		})
+	}
+
+	// The largest amount that still fits converts exactly.
+	if got, err := One.Convert(math.MaxInt64); err != nil || got != math.MaxInt64 {
+		t.Fatalf("One.Convert(MaxInt64) = %d, %v", got, err)
+	}
+}
+
+func TestInverse(t *testing.T) {
+	tests := []struct {
+		rate    Rate
+		want    Rate
+		wantErr bool
+	}{
+		{One, One, false},
+		{2 * One, One / 2, false},
+		{16250 * One, 615_385, false},
+		{3 * One, 3_333_333_333, false},
+		// 1/0.0000000003 overflows an int64 once scaled.
+		{3, 0, true},
+		{0, 0, true},
+		{-One, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.rate.Inverse()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Rate(%d).Inverse() = %d, %v; want %d, error %v", int64(tt.rate), got, err, tt.want, tt.wantErr)
+		}
+	}
+}
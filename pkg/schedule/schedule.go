@@ -0,0 +1,195 @@
+// Package schedule parses the recurrence of scheduled transfers.
+//
+// A spec is either an interval or a five-field cron expression evaluated in
+// UTC:
+//
+//	@every 168h          every 168 hours after the previous run
+//	@hourly, @daily, @weekly, @monthly, @yearly
+//	0 9 1 * *            09:00 on the first of every month
+//	30 7 * * 1-5         07:30 on weekdays
+//
+// Cron fields are minute, hour, day of month, month and day of week (0 or 7
+// is Sunday). Each accepts *, a value, a range a-b, a step */n or a-b/n, and
+// comma-separated lists of those. As in cron, when both the day of month and
+// the day of week are restricted, a day matching either one is due.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSpec is returned for a spec that cannot be parsed.
+var ErrInvalidSpec = errors.New("invalid schedule")
+
+// MinInterval is the shortest @every interval accepted.
+const MinInterval = time.Minute
+
+// horizon bounds the search for the next run of a cron expression that
+// rarely or never matches, such as "0 0 30 2 *".
+const horizon = 5 * 366 * 24 * time.Hour
+
+// Schedule yields the run times of a recurrence.
+type Schedule interface {
+	// Next returns the first run time strictly after after, or the zero
+	// time if there is none within five years.
+	Next(after time.Time) time.Time
+}
+
+// Parse parses a spec as described in the package documentation.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+		}
+		if d < MinInterval {
+			return nil, fmt.Errorf("%w: interval must be at least %s", ErrInvalidSpec, MinInterval)
+		}
+		return every(d.Truncate(time.Second)), nil
+	}
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@yearly":
+		spec = "0 0 1 1 *"
+	}
+	return parseCron(spec)
+}
+
+// every runs at a fixed interval after the previous run.
+type every time.Duration
+
+func (e every) Next(after time.Time) time.Time {
+	return after.Truncate(time.Second).Add(time.Duration(e))
+}
+
+// cron holds one bit per allowed value of each field.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record an unrestricted day field, which changes
+	// how the two day fields combine.
+	domStar, dowStar bool
+}
+
+// field bounds, in the order fields appear in a spec.
+var fields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func parseCron(spec string) (Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%w: %q must have 5 fields or be @every <duration>, @hourly, @daily, @weekly, @monthly or @yearly", ErrInvalidSpec, spec)
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseField(part, fields[i].min, fields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSpec, fields[i].name, err)
+		}
+		bits[i] = b
+	}
+	// 7 is another name for Sunday.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &cron{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseField returns the set of values a comma-separated field allows.
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepStr)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("bad value %q", loStr)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("bad value %q", hiStr)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", item, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(horizon)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
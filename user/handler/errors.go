@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	services "ewallet/user/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus converts an error returned by the user service into a gRPC
+// status, choosing the code from the domain error it wraps. msg describes
+// the failed operation.
+func toStatus(err error, msg string) error {
+	switch {
+	case errors.Is(err, services.ErrTooManyIDs),
+		errors.Is(err, services.ErrPasswordEmpty),
+		errors.Is(err, services.ErrPasswordTooLong):
+		return status.Errorf(codes.InvalidArgument, "%s: %v", msg, err)
+	case errors.Is(err, services.ErrUserNotFound):
+		return status.Errorf(codes.NotFound, "%s: %v", msg, err)
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return status.Errorf(codes.Unauthenticated, "%s: %v", msg, err)
+	case errors.Is(err, services.ErrUserHasOpenWallets):
+		return status.Errorf(codes.FailedPrecondition, "%s: %v", msg, err)
+	case errors.Is(err, services.ErrDuplicateUsername),
+		errors.Is(err, services.ErrDuplicateEmail):
+		return status.Errorf(codes.AlreadyExists, "%s: %v", msg, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Errorf(codes.DeadlineExceeded, "%s: %v", msg, err)
+	case errors.Is(err, context.Canceled):
+		return status.Errorf(codes.Canceled, "%s: %v", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", msg, err)
+	}
+}
@@ -6,8 +6,6 @@ import (
 	pb "ewallet/user/proto"
 	services "ewallet/user/service"
 
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -29,14 +27,13 @@ func (h *UserHandler) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 
 	createdUser, err := h.service.CreateUser(user.Username, user.Password, user.Email)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create user: %v", err)
+		return nil, toStatus(err, "failed to create user")
 	}
 
 	return &pb.CreateUserResponse{
 		User: &pb.User{
 			UserId:    uint32(createdUser.UserID),
 			Username:  createdUser.Username,
-			Password:  createdUser.Password,
 			Email:     createdUser.Email,
 			CreatedAt: timestamppb.New(createdUser.CreatedAt),
 		},
@@ -46,37 +43,56 @@ func (h *UserHandler) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 func (h *UserHandler) GetUserByID(ctx context.Context, req *pb.GetUserByIDRequest) (*pb.GetUserByIDResponse, error) {
 	user, err := h.service.GetUserByID(uint(req.UserId))
 	if err != nil {
-		if err.Error() == "user not found" {
-			return nil, status.Errorf(codes.NotFound, "user not found")
-		}
-		return nil, status.Errorf(codes.Internal, "failed to get user by ID: %v", err)
+		return nil, toStatus(err, "failed to get user by ID")
 	}
 
 	return &pb.GetUserByIDResponse{
 		User: &pb.User{
 			UserId:    uint32(user.UserID),
 			Username:  user.Username,
-			Password:  user.Password,
 			Email:     user.Email,
 			CreatedAt: timestamppb.New(user.CreatedAt),
 		},
 	}, nil
 }
 
+// GetUsersByIDs handles the gRPC request to look up several users at once
+func (h *UserHandler) GetUsersByIDs(ctx context.Context, req *pb.GetUsersByIDsRequest) (*pb.GetUsersByIDsResponse, error) {
+	ids := make([]uint, len(req.UserIds))
+	for i, id := range req.UserIds {
+		ids[i] = uint(id)
+	}
+
+	users, err := h.service.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, toStatus(err, "failed to get users by IDs")
+	}
+
+	pbUsers := make([]*pb.User, 0, len(users))
+	for _, user := range users {
+		pbUsers = append(pbUsers, &pb.User{
+			UserId:    uint32(user.UserID),
+			Username:  user.Username,
+			Email:     user.Email,
+			CreatedAt: timestamppb.New(user.CreatedAt),
+		})
+	}
+
+	return &pb.GetUsersByIDsResponse{
+		Users: pbUsers,
+	}, nil
+}
+
 func (h *UserHandler) GetUserByUsername(ctx context.Context, req *pb.GetUserByUsernameRequest) (*pb.GetUserByUsernameResponse, error) {
 	user, err := h.service.GetUserByUsername(req.Username)
 	if err != nil {
-		if err.Error() == "user not found" {
-			return nil, status.Errorf(codes.NotFound, "user not found")
-		}
-		return nil, status.Errorf(codes.Internal, "failed to get user by username: %v", err)
+		return nil, toStatus(err, "failed to get user by username")
 	}
 
 	return &pb.GetUserByUsernameResponse{
 		User: &pb.User{
 			UserId:    uint32(user.UserID),
 			Username:  user.Username,
-			Password:  user.Password,
 			Email:     user.Email,
 			CreatedAt: timestamppb.New(user.CreatedAt),
 		},
@@ -93,14 +109,13 @@ func (h *UserHandler) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 
 	err := h.service.UpdateUser(user)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update user: %v", err)
+		return nil, toStatus(err, "failed to update user")
 	}
 
 	return &pb.UpdateUserResponse{
 		User: &pb.User{
 			UserId:    uint32(user.UserID),
 			Username:  user.Username,
-			Password:  user.Password,
 			Email:     user.Email,
 			CreatedAt: timestamppb.New(user.CreatedAt),
 		},
@@ -110,10 +125,27 @@ func (h *UserHandler) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 func (h *UserHandler) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
 	err := h.service.DeleteUser(uint(req.UserId))
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to delete user: %v", err)
+		return nil, toStatus(err, "failed to delete user")
 	}
 
 	return &pb.DeleteUserResponse{
 		Message: "User deleted successfully",
 	}, nil
 }
+
+// Login handles the gRPC request to verify a username and password
+func (h *UserHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	user, err := h.service.Login(req.Username, req.Password)
+	if err != nil {
+		return nil, toStatus(err, "failed to log in")
+	}
+
+	return &pb.LoginResponse{
+		User: &pb.User{
+			UserId:    uint32(user.UserID),
+			Username:  user.Username,
+			Email:     user.Email,
+			CreatedAt: timestamppb.New(user.CreatedAt),
+		},
+	}, nil
+}
@@ -0,0 +1,12 @@
+-- Baseline schema of the user database as it existed before versioned
+-- migrations. IF NOT EXISTS lets databases created by hand adopt the
+-- migration history without changes.
+CREATE TABLE IF NOT EXISTS users (
+    user_id    serial      PRIMARY KEY,
+    username   text        NOT NULL,
+    password   text        NOT NULL,
+    email      text        NOT NULL,
+    created_at timestamptz DEFAULT current_timestamp,
+    CONSTRAINT uni_users_username UNIQUE (username),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
@@ -0,0 +1,8 @@
+// Package migrations holds the versioned SQL schema of the user database.
+// Files are named NNNN_description.up.sql and NNNN_description.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
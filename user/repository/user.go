@@ -5,10 +5,29 @@ import (
 	"errors"
 	models "ewallet/user/entity"
 	services "ewallet/user/service"
+	"strings"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
+// uniqueViolation is the PostgreSQL error code for a unique constraint
+// violation.
+const uniqueViolation = "23505"
+
+// translateUniqueViolation maps a duplicate username or email to the
+// matching service error and returns other errors unchanged.
+func translateUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+	if strings.Contains(pgErr.ConstraintName, "email") {
+		return services.ErrDuplicateEmail
+	}
+	return services.ErrDuplicateUsername
+}
+
 type GormDBIface interface {
 	WithContext(ctx context.Context) *gorm.DB
 	Create(value interface{}) *gorm.DB
@@ -28,7 +47,7 @@ func NewUserRepository(db GormDBIface) services.IUserRepository {
 
 func (r *userRepository) CreateUser(ctx context.Context, user *models.User) (models.User, error) {
 	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
-		return models.User{}, err
+		return models.User{}, translateUniqueViolation(err)
 	}
 	return *user, nil
 }
@@ -38,19 +57,28 @@ func (r *userRepository) GetUserByID(ctx context.Context, userID uint) (models.U
 
 	if err := r.db.WithContext(ctx).First(&user, "user_id = ?", userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return models.User{}, errors.New("user not found")
+			return models.User{}, services.ErrUserNotFound
 		}
 		return models.User{}, err
 	}
 	return user, nil
 }
 
+func (r *userRepository) GetUsersByIDs(ctx context.Context, userIDs []uint) ([]models.User, error) {
+	var users []models.User
+
+	if err := r.db.WithContext(ctx).Find(&users, "user_id IN ?", userIDs).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
 	var user models.User
 
 	if err := r.db.WithContext(ctx).First(&user, "username = ?", username).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return models.User{}, errors.New("user not found")
+			return models.User{}, services.ErrUserNotFound
 		}
 		return models.User{}, err
 	}
@@ -58,15 +86,23 @@ func (r *userRepository) GetUserByUsername(ctx context.Context, username string)
 }
 
 func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) error {
-	if err := r.db.WithContext(ctx).Model(&models.User{}).Where("user_id = ?", user.UserID).Updates(user).Error; err != nil {
-		return err
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("user_id = ?", user.UserID).Updates(user)
+	if result.Error != nil {
+		return translateUniqueViolation(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return services.ErrUserNotFound
 	}
 	return nil
 }
 
 func (r *userRepository) DeleteUser(ctx context.Context, userID uint) error {
-	if err := r.db.WithContext(ctx).Delete(&models.User{}, "user_id = ?", userID).Error; err != nil {
-		return err
+	result := r.db.WithContext(ctx).Delete(&models.User{}, "user_id = ?", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return services.ErrUserNotFound
 	}
 	return nil
 }
@@ -0,0 +1,15 @@
+package services
+
+import "errors"
+
+// Domain errors returned by the user service. Callers should test for them
+// with errors.Is.
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrDuplicateUsername = errors.New("username already exists")
+	ErrDuplicateEmail    = errors.New("email already exists")
+	ErrTooManyIDs        = errors.New("too many IDs in one request")
+	// ErrUserHasOpenWallets is returned when deleting a user who holds a
+	// wallet that is not closed.
+	ErrUserHasOpenWallets = errors.New("user has wallets that are not closed")
+)
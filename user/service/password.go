@@ -0,0 +1,63 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned by Login when the username does not
+// exist or the password does not match. The two cases are deliberately
+// indistinguishable to callers.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrPasswordEmpty is returned when a new password is empty.
+var ErrPasswordEmpty = errors.New("password must not be empty")
+
+// ErrPasswordTooLong is returned when a new password is longer than bcrypt
+// can hash.
+var ErrPasswordTooLong = errors.New("password must be at most 72 bytes")
+
+// maxPasswordBytes is the longest password bcrypt accepts.
+const maxPasswordBytes = 72
+
+// dummyHash is compared against when a login names an unknown user, so
+// that the response takes as long as for a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password for unknown users"), bcrypt.DefaultCost)
+
+// validatePassword checks a new password before it is hashed.
+func validatePassword(password string) error {
+	if password == "" {
+		return ErrPasswordEmpty
+	}
+	if len(password) > maxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	return nil
+}
+
+// hashPassword returns the bcrypt hash stored in place of a password.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// isPasswordHash reports whether a stored password is a bcrypt hash rather
+// than a legacy plaintext value written before hashing was introduced.
+func isPasswordHash(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// checkPassword compares a candidate password with the stored value, which
+// may be a bcrypt hash or a legacy plaintext password.
+func checkPassword(stored, candidate string) bool {
+	if isPasswordHash(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(candidate)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(candidate)) == 1
+}
@@ -2,29 +2,56 @@ package services
 
 import (
 	"context"
+	"errors"
 	models "ewallet/user/entity"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type IUserRepository interface {
 	CreateUser(ctx context.Context, user *models.User) (models.User, error)
 	GetUserByID(ctx context.Context, id uint) (models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []uint) ([]models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, id uint) error
 }
 
+// IWalletService looks up a user's wallets in the wallet service.
+type IWalletService interface {
+	// GetOpenWalletIDs returns the IDs of the user's wallets that are not
+	// closed.
+	GetOpenWalletIDs(ctx context.Context, userID uint) ([]int32, error)
+}
+
 type UserService struct {
 	userRepository IUserRepository
+	wallets        IWalletService
+	walletTimeout  time.Duration
 }
 
-func NewUserService(userRepository IUserRepository) *UserService {
-	return &UserService{userRepository: userRepository}
+// NewUserService returns a user service. walletTimeout bounds each call to
+// the wallet service.
+func NewUserService(userRepository IUserRepository, wallets IWalletService, walletTimeout time.Duration) *UserService {
+	return &UserService{userRepository: userRepository, wallets: wallets, walletTimeout: walletTimeout}
 }
 
+// CreateUser stores a new user. The password is stored as a bcrypt hash.
 func (s *UserService) CreateUser(username, password, email string) (*models.User, error) {
+	if err := validatePassword(password); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
 	user := &models.User{
 		Username: username,
-		Password: password,
+		Password: hash,
 		Email:    email,
 	}
 
@@ -43,6 +70,21 @@ func (s *UserService) GetUserByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// MaxBatchSize is the largest number of IDs GetUsersByIDs accepts.
+const MaxBatchSize = 500
+
+// GetUsersByIDs returns the users with the given IDs. IDs that do not exist
+// are skipped, so the result may be shorter than ids.
+func (s *UserService) GetUsersByIDs(ids []uint) ([]models.User, error) {
+	if len(ids) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: %d, at most %d", ErrTooManyIDs, len(ids), MaxBatchSize)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.userRepository.GetUsersByIDs(context.Background(), ids)
+}
+
 func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
 	user, err := s.userRepository.GetUserByUsername(context.Background(), username)
 	if err != nil {
@@ -51,10 +93,81 @@ func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
+// UpdateUser updates the non-empty fields of user. A new password is hashed
+// before it is stored.
 func (s *UserService) UpdateUser(user *models.User) error {
+	if user.Password != "" {
+		if err := validatePassword(user.Password); err != nil {
+			return err
+		}
+		hash, err := hashPassword(user.Password)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		user.Password = hash
+	}
 	return s.userRepository.UpdateUser(context.Background(), user)
 }
 
+// Login verifies a username and password and returns the matching user.
+// Unknown usernames cost a bcrypt comparison too, so response times do not
+// reveal which usernames exist. Users whose password predates hashing are
+// still accepted once, and their password is rehashed on the spot.
+//
+// A password no new password could be, empty or longer than bcrypt hashes,
+// is refused before the lookup: it matches no hash, and an empty one would
+// match an empty legacy password.
+func (s *UserService) Login(username, password string) (*models.User, error) {
+	if validatePassword(password) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	user, err := s.userRepository.GetUserByUsername(context.Background(), username)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if !checkPassword(user.Password, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !isPasswordHash(user.Password) {
+		hash, err := hashPassword(password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		err = s.userRepository.UpdateUser(context.Background(), &models.User{UserID: user.UserID, Password: hash})
+		if err != nil {
+			// The login itself succeeded; the rehash is retried next time.
+			log.Printf("failed to rehash legacy password for user %d: %v", user.UserID, err)
+		} else {
+			user.Password = hash
+		}
+	}
+	return &user, nil
+}
+
+// DeleteUser deletes a user whose wallets are all closed. While a wallet is
+// open it fails with ErrUserHasOpenWallets, so that no wallet is left
+// behind able to receive money; support closes the wallets first, sweeping
+// their balances where needed.
+//
+// The wallet service keeps no record of users, so the check and the delete
+// cannot share a transaction: a wallet created between the two outlives its
+// user. Callers must stop the user from signing in, and so from opening
+// wallets through the gateway, before deleting them.
 func (s *UserService) DeleteUser(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.walletTimeout)
+	defer cancel()
+	open, err := s.wallets.GetOpenWalletIDs(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check wallets: %w", err)
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("user %d still has wallets %v: %w", id, open, ErrUserHasOpenWallets)
+	}
 	return s.userRepository.DeleteUser(context.Background(), id)
 }
@@ -0,0 +1,66 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	services "ewallet/user/service"
+	"testing"
+	"time"
+)
+
+// userRepo records the users deleted. Other methods are not implemented.
+type userRepo struct {
+	services.IUserRepository
+	deleted []uint
+}
+
+func (r *userRepo) DeleteUser(ctx context.Context, id uint) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+// walletLister answers GetOpenWalletIDs with open or err, noting whether
+// the call had a deadline.
+type walletLister struct {
+	open        []int32
+	err         error
+	hadDeadline bool
+}
+
+func (w *walletLister) GetOpenWalletIDs(ctx context.Context, userID uint) ([]int32, error) {
+	_, w.hadDeadline = ctx.Deadline()
+	return w.open, w.err
+}
+
+func TestDeleteUser(t *testing.T) {
+	unavailable := errors.New("wallet service unavailable")
+	tests := []struct {
+		name        string
+		open        []int32
+		walletErr   error
+		wantErr     error
+		wantDeleted bool
+	}{
+		{"no wallets open", nil, nil, nil, true},
+		{"wallets open", []int32{3, 4}, nil, services.ErrUserHasOpenWallets, false},
+		{"wallet service down", nil, unavailable, unavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &userRepo{}
+			wallets := &walletLister{open: tt.open, err: tt.walletErr}
+			svc := services.NewUserService(repo, wallets, time.Second)
+
+			err := svc.DeleteUser(1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteUser() error = %v, want %v", err, tt.wantErr)
+			}
+			if deleted := len(repo.deleted) == 1; deleted != tt.wantDeleted {
+				t.Fatalf("user deleted: %t, want %t", deleted, tt.wantDeleted)
+			}
+			if !wallets.hadDeadline {
+				t.Fatalf("wallet service asked without a deadline")
+			}
+		})
+	}
+}
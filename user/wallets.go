@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+
+	pb "ewallet/user/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// walletClosed is the status the wallet service reports for a closed
+// wallet.
+const walletClosed = "closed"
+
+// walletClient asks the wallet service about a user's wallets.
+type walletClient struct {
+	client pb.TransactionServiceClient
+}
+
+// newWalletClient connects to the wallet service at addr.
+func newWalletClient(addr string) (*walletClient, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &walletClient{client: pb.NewTransactionServiceClient(conn)}, nil
+}
+
+// GetOpenWalletIDs implements services.IWalletService.
+func (w *walletClient) GetOpenWalletIDs(ctx context.Context, userID uint) ([]int32, error) {
+	res, err := w.client.GetWalletsByUserID(ctx, &pb.GetWalletsByUserIDRequest{UserId: int32(userID)})
+	if err != nil {
+		return nil, err
+	}
+	var open []int32
+	for _, wallet := range res.GetWallets() {
+		if wallet.GetStatus() != walletClosed {
+			open = append(open, wallet.GetId())
+		}
+	}
+	return open, nil
+}
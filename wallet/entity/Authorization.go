@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"ewallet/pkg/money"
+	"time"
+)
+
+// Authorization statuses. An authorization is open while authorized; every
+// other status is final.
+const (
+	AuthorizationAuthorized = "authorized"
+	AuthorizationCaptured   = "captured"
+	AuthorizationVoided     = "voided"
+	AuthorizationExpired    = "expired"
+)
+
+// Authorization is the first phase of a two-phase payment: Amount is held
+// on the wallet, reducing its available balance but not its ledger balance,
+// until the merchant captures it, voids it or it expires. A capture of at
+// most Amount debits the wallet and releases the whole hold.
+type Authorization struct {
+	AuthorizationID uint         `gorm:"primaryKey;autoIncrement"`
+	WalletID        int          `gorm:"not null;index"`
+	MerchantID      string       `gorm:"type:varchar(64);not null;default:''"`
+	Amount          money.Amount `gorm:"type:bigint;not null"`
+	CapturedAmount  money.Amount `gorm:"type:bigint;not null;default:0"`
+	Status          string       `gorm:"type:varchar(12);not null;default:authorized"`
+	// TransactionID is the payment recorded by the capture.
+	TransactionID *uint
+	// IdempotencyKey, when set, makes a retried authorization return this
+	// one instead of holding the amount again.
+	IdempotencyKey *string   `gorm:"type:varchar(255);uniqueIndex"`
+	ExpiresAt      time.Time `gorm:"not null"`
+	CreatedAt      time.Time `gorm:"default:current_timestamp"`
+	UpdatedAt      time.Time `gorm:"default:current_timestamp"`
+}
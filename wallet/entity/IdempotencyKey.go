@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"ewallet/pkg/money"
+	"time"
+)
+
+// IdempotencyKey records the result of a money-moving request so that a
+// retried request carrying the same key is answered without moving money
+// again. The request fields are kept to reject a key reused for a different
+// request.
+type IdempotencyKey struct {
+	Key                  string       `gorm:"primaryKey;type:varchar(255)"`
+	Operation            string       `gorm:"type:varchar(20);not null"`
+	WalletID             int          `gorm:"not null"`
+	CounterpartyWalletID int          `gorm:"not null;default:0"`
+	MerchantID           string       `gorm:"type:varchar(64);not null;default:''"`
+	RefundOf             uint         `gorm:"not null;default:0"`
+	Amount               money.Amount `gorm:"type:bigint;not null"`
+	TransactionID        uint         `gorm:"not null"`
+	CreatedAt            time.Time    `gorm:"default:current_timestamp"`
+}
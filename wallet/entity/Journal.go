@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"ewallet/pkg/money"
+	"fmt"
+	"time"
+)
+
+// Journal entry types.
+const (
+	EntryTypeTopUp          = "topup"
+	EntryTypePayment        = "payment"
+	EntryTypeTransfer       = "transfer"
+	EntryTypeOpeningBalance = "opening_balance"
+	EntryTypeAdjustment     = "adjustment"
+	EntryTypeRefund         = "refund"
+)
+
+// Posting directions. A wallet account is a liability of the platform, so a
+// credit increases its balance and a debit decreases it.
+const (
+	Debit  = "debit"
+	Credit = "credit"
+)
+
+// System accounts that sit on the other side of money entering or leaving
+// the wallets.
+const (
+	// AccountTopUpFunding is debited when a wallet is topped up.
+	AccountTopUpFunding = "system:topup_funding"
+	// AccountMerchantSettlement is credited when a wallet pays a merchant.
+	AccountMerchantSettlement = "system:merchant_settlement"
+	// AccountOpeningBalance holds the balances that existed before the
+	// journal was introduced.
+	AccountOpeningBalance = "system:opening_balance"
+	// AccountReconciliation absorbs corrections made by the reconciliation
+	// job when a stored balance is repaired.
+	AccountReconciliation = "system:reconciliation"
+	// AccountFXConversion sits between the two currencies of a cross-currency
+	// transfer: it is credited in the source currency and debited in the
+	// destination currency.
+	AccountFXConversion = "system:fx_conversion"
+)
+
+// WalletAccount returns the ledger account name of a wallet.
+func WalletAccount(walletID int) string {
+	return fmt.Sprintf("wallet:%d", walletID)
+}
+
+// JournalEntry groups the postings of one money movement. The debits and
+// credits of an entry always sum to the same amount.
+type JournalEntry struct {
+	EntryID   uint      `gorm:"primaryKey;autoIncrement"`
+	EntryType string    `gorm:"type:varchar(20);not null"`
+	CreatedAt time.Time `gorm:"default:current_timestamp"`
+	Postings  []Posting `gorm:"foreignKey:EntryID"`
+}
+
+// Posting is one leg of a journal entry against a single account. WalletID
+// is 0 for system accounts. Amount is in Currency; an entry balances
+// separately in each currency it touches.
+type Posting struct {
+	PostingID uint         `gorm:"primaryKey;autoIncrement"`
+	EntryID   uint         `gorm:"not null;index"`
+	Account   string       `gorm:"type:varchar(64);not null;index"`
+	WalletID  int          `gorm:"not null;default:0;index"`
+	Direction string       `gorm:"type:varchar(6);not null"`
+	Amount    money.Amount `gorm:"type:bigint;not null"`
+	Currency  string       `gorm:"type:char(3);not null"`
+	CreatedAt time.Time    `gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"ewallet/pkg/money"
+	"time"
+)
+
+// TierUnverified is the tier of a user until support assigns another one.
+const TierUnverified = "unverified"
+
+// UserTier assigns a user to a spending limit tier, such as "verified"
+// after KYC. Users without a row are TierUnverified.
+type UserTier struct {
+	UserID    uint      `gorm:"primaryKey"`
+	Tier      string    `gorm:"type:varchar(32);not null"`
+	UpdatedAt time.Time `gorm:"default:current_timestamp"`
+}
+
+// WalletLimit overrides the tier limits of one wallet. A nil field keeps
+// the tier's limit; zero removes the limit.
+type WalletLimit struct {
+	WalletID      int           `gorm:"primaryKey"`
+	DailyAmount   *money.Amount `gorm:"type:bigint"`
+	MonthlyAmount *money.Amount `gorm:"type:bigint"`
+	DailyCount    *int
+	MonthlyCount  *int
+	UpdatedAt     time.Time `gorm:"default:current_timestamp"`
+}
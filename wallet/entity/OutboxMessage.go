@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Domain event types published through the outbox.
+const (
+	EventWalletCredited      = "WalletCredited"
+	EventWalletDebited       = "WalletDebited"
+	EventTransferCompleted   = "TransferCompleted"
+	EventWalletStatusChanged = "WalletStatusChanged"
+)
+
+// Outbox message statuses. A message is pending until it is published, or
+// dead once it has failed the maximum number of attempts.
+const (
+	OutboxPending   = "pending"
+	OutboxPublished = "published"
+	OutboxDead      = "dead"
+)
+
+// OutboxMessage is a domain event waiting to be published. It is written in
+// the same database transaction as the ledger rows it describes, so an event
+// exists if and only if the money moved.
+type OutboxMessage struct {
+	ID          int64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventType   string          `gorm:"type:varchar(40);not null" json:"type"`
+	AggregateID string          `gorm:"type:varchar(64);not null" json:"aggregate_id"`
+	Payload     json.RawMessage `gorm:"type:jsonb;not null" json:"data"`
+	Status      string          `gorm:"type:varchar(10);not null;default:pending" json:"-"`
+	Attempts    int             `gorm:"not null;default:0" json:"-"`
+	// NextAttemptAt is when the relay may next try the message. While a relay
+	// is publishing it, it is pushed forward as a lease.
+	NextAttemptAt time.Time  `gorm:"not null;default:current_timestamp" json:"-"`
+	LastError     string     `gorm:"type:text;not null;default:''" json:"-"`
+	CreatedAt     time.Time  `gorm:"default:current_timestamp" json:"occurred_at"`
+	PublishedAt   *time.Time `json:"-"`
+}
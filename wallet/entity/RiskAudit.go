@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"ewallet/pkg/money"
+	"time"
+)
+
+// RiskAudit records a top-up, payment or transfer that the risk checks
+// denied or flagged for review. Rules is the comma-separated list of the
+// rules that fired. A denied attempt left no transaction behind, so the
+// audit row is its only trace.
+type RiskAudit struct {
+	ID                   int64        `gorm:"primaryKey;autoIncrement"`
+	WalletID             int          `gorm:"not null"`
+	Operation            string       `gorm:"type:varchar(20);not null"`
+	Amount               money.Amount `gorm:"type:bigint;not null"`
+	Currency             string       `gorm:"type:char(3);not null"`
+	CounterpartyWalletID int          `gorm:"not null;default:0"`
+	MerchantID           string       `gorm:"type:varchar(64);not null;default:''"`
+	Decision             string       `gorm:"type:varchar(10);not null"`
+	Rules                string       `gorm:"type:text;not null;default:''"`
+	Reason               string       `gorm:"type:text;not null;default:''"`
+	CreatedAt            time.Time    `gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"ewallet/pkg/money"
+	"time"
+)
+
+// Scheduled transfer statuses. Only active schedules run; cancelled and
+// completed are final.
+const (
+	ScheduleActive    = "active"
+	SchedulePaused    = "paused"
+	ScheduleCancelled = "cancelled"
+	ScheduleCompleted = "completed"
+)
+
+// Scheduled transfer run statuses.
+const (
+	ScheduleRunSucceeded = "succeeded"
+	ScheduleRunFailed    = "failed"
+)
+
+// ScheduledTransfer is a standing order: Amount moves from FromWalletID to
+// ToWalletID at every occurrence of Schedule, a spec understood by package
+// schedule. UserID owns the source wallet.
+type ScheduledTransfer struct {
+	ID           int64        `gorm:"primaryKey;autoIncrement"`
+	UserID       uint         `gorm:"not null;index"`
+	FromWalletID int          `gorm:"not null"`
+	ToWalletID   int          `gorm:"not null"`
+	Amount       money.Amount `gorm:"type:bigint;not null"`
+	Schedule     string       `gorm:"type:varchar(100);not null"`
+	Description  string       `gorm:"type:varchar(255);not null;default:''"`
+	Status       string       `gorm:"type:varchar(12);not null;default:active"`
+	// NextRunAt is the occurrence the scheduler executes next.
+	NextRunAt time.Time `gorm:"not null"`
+	LastRunAt *time.Time
+	// LeaseUntil hides a schedule from other schedulers while one of them
+	// is executing it.
+	LeaseUntil *time.Time
+	// MaxRuns ends the schedule after that many runs, successful or not;
+	// 0 means it runs until cancelled.
+	MaxRuns   int       `gorm:"not null;default:0"`
+	RunCount  int       `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `gorm:"default:current_timestamp"`
+}
+
+// ScheduledTransferRun records one execution of a scheduled transfer.
+type ScheduledTransferRun struct {
+	ID         int64 `gorm:"primaryKey;autoIncrement"`
+	ScheduleID int64 `gorm:"not null;index"`
+	// ScheduledFor is the occurrence executed, which may be earlier than
+	// CreatedAt when the scheduler catches up.
+	ScheduledFor time.Time `gorm:"not null"`
+	Status       string    `gorm:"type:varchar(12);not null"`
+	// TransactionID is the debit of the source wallet on success.
+	TransactionID *uint
+	// Error says why a failed run did not transfer.
+	Error     string    `gorm:"type:text;not null;default:''"`
+	CreatedAt time.Time `gorm:"default:current_timestamp"`
+}
@@ -1,14 +1,36 @@
 package entity
 
 import (
+	"ewallet/pkg/money"
 	"time"
 )
 
+// Transaction is one wallet's side of a money movement. Amount is in the
+// wallet's own currency. The Original and Converted fields describe the
+// movement as a whole: for a cross-currency transfer both legs carry the
+// amount debited in the source currency, the amount credited in the
+// destination currency and the rate applied; otherwise both amounts equal
+// Amount and the rate is 1.
 type Transaction struct {
-	TransactionID   uint      `gorm:"primaryKey;autoIncrement"`
-	WalletID        int       `gorm:"not null;index"`
-	Amount          float64   `gorm:"type:decimal(10,2);not null"`
-	TransactionType string    `gorm:"type:varchar(20);not null"`
-	CreatedAt       time.Time `gorm:"default:current_timestamp"`
-	WalletIDSource  int       `gorm:"column:wallet_id_source"`
+	TransactionID   uint         `gorm:"primaryKey;autoIncrement"`
+	WalletID        int          `gorm:"not null;index"`
+	Amount          money.Amount `gorm:"type:bigint;not null"`
+	TransactionType string       `gorm:"type:varchar(20);not null"`
+	CreatedAt       time.Time    `gorm:"default:current_timestamp"`
+	WalletIDSource  int          `gorm:"column:wallet_id_source"`
+	JournalEntryID  *uint        `gorm:"index"`
+	// MerchantID names the merchant paid by a payment; it is empty for
+	// every other movement.
+	MerchantID string `gorm:"type:varchar(64);not null;default:''"`
+	// RefundOf is set on a refund row to the transaction of the same wallet
+	// it reverses. RefundedAmount, on the reversed transaction, is the part
+	// of Amount refunded so far.
+	RefundOf       *uint        `gorm:"index"`
+	RefundedAmount money.Amount `gorm:"type:bigint;not null;default:0"`
+
+	OriginalAmount    money.Amount `gorm:"type:bigint;not null"`
+	OriginalCurrency  string       `gorm:"type:char(3);not null"`
+	ConvertedAmount   money.Amount `gorm:"type:bigint;not null"`
+	ConvertedCurrency string       `gorm:"type:char(3);not null"`
+	FxRate            money.Rate   `gorm:"type:numeric(20,10);not null"`
 }
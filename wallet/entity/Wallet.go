@@ -1,13 +1,59 @@
 package entity
 
 import (
+	"ewallet/pkg/money"
 	"time"
 )
 
+// Wallet is a balance held by a user in one currency. A user may have
+// several wallets; exactly one of them is the default, used when a request
+// names the user but not the wallet.
+//
+// Balance is the ledger balance. HeldBalance is the part of it reserved by
+// open payment authorizations, which cannot be spent until they are
+// captured, voided or expire.
+//
+// Only an active wallet takes part in money movements. Support freezes a
+// wallet while it is investigated and closes it for good; StatusReason
+// explains the last change.
 type Wallet struct {
-	Walletid  int32     `gorm:"primaryKey;column:wallet_id"`
-	UserID    uint      `gorm:"not null"`
-	Balance   float64   `gorm:"default:0.00"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	Walletid     int32        `gorm:"primaryKey;column:wallet_id"`
+	UserID       uint         `gorm:"not null"`
+	Balance      money.Amount `gorm:"type:bigint;not null;default:0"`
+	HeldBalance  money.Amount `gorm:"type:bigint;not null;default:0"`
+	Currency     string       `gorm:"type:char(3);not null;default:IDR"`
+	Name         string       `gorm:"type:varchar(50);not null;default:Main"`
+	IsDefault    bool         `gorm:"not null;default:false"`
+	Status       string       `gorm:"type:varchar(10);not null;default:active"`
+	StatusReason string       `gorm:"type:text;not null;default:''"`
+	CreatedAt    time.Time    `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time    `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// Wallet statuses. An active wallet can be frozen and unfrozen; either can
+// be closed, which is final.
+const (
+	WalletActive = "active"
+	WalletFrozen = "frozen"
+	WalletClosed = "closed"
+)
+
+// WalletStatusChange records a change of a wallet's status, who made it and
+// why.
+type WalletStatusChange struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement"`
+	WalletID   int    `gorm:"not null"`
+	FromStatus string `gorm:"type:varchar(10);not null"`
+	ToStatus   string `gorm:"type:varchar(10);not null"`
+	Reason     string `gorm:"type:text;not null"`
+	// ChangedBy is the support user who made the change.
+	ChangedBy uint
+	// SweepTransactionID is the transfer that emptied a closed wallet.
+	SweepTransactionID *uint
+	CreatedAt          time.Time `gorm:"default:current_timestamp"`
+}
+
+// Available returns the balance that can be spent.
+func (w Wallet) Available() money.Amount {
+	return w.Balance - w.HeldBalance
 }
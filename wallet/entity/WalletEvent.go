@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"ewallet/pkg/money"
+	"time"
+)
+
+// Wallet event types.
+const (
+	EventTopUp       = "topup"
+	EventPayment     = "payment"
+	EventTransferIn  = "transfer_in"
+	EventTransferOut = "transfer_out"
+	EventRefundIn    = "refund_in"
+	EventRefundOut   = "refund_out"
+)
+
+// WalletEvent records a committed change to one wallet's balance. Events are
+// written in the same database transaction as the change while the wallet
+// row is locked, so within one wallet EventID follows commit order and gives
+// subscribers a position to resume from.
+type WalletEvent struct {
+	EventID       int64        `gorm:"primaryKey;autoIncrement"`
+	WalletID      int          `gorm:"not null"`
+	EventType     string       `gorm:"type:varchar(20);not null"`
+	TransactionID uint         `gorm:"not null"`
+	Amount        money.Amount `gorm:"type:bigint;not null"`
+	// Balance is the wallet balance right after the change.
+	Balance   money.Amount `gorm:"type:bigint;not null"`
+	Currency  string       `gorm:"type:char(3);not null"`
+	CreatedAt time.Time    `gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types delivered to merchant webhooks.
+const (
+	WebhookPaymentSucceeded = "payment.succeeded"
+	WebhookPaymentRefunded  = "payment.refunded"
+)
+
+// Webhook delivery statuses. A delivery is pending until the merchant
+// acknowledges it, or failed once it has used up its attempts.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// MerchantWebhook is the endpoint a merchant registered for asynchronous
+// payment notifications. Secret signs every delivery.
+type MerchantWebhook struct {
+	MerchantID string    `gorm:"primaryKey;type:varchar(64)"`
+	URL        string    `gorm:"type:text;not null"`
+	Secret     string    `gorm:"type:varchar(128);not null"`
+	CreatedAt  time.Time `gorm:"default:current_timestamp"`
+	UpdatedAt  time.Time `gorm:"default:current_timestamp"`
+}
+
+// WebhookDelivery is one event owed to a merchant, together with the outcome
+// of its latest attempt. It is written in the database transaction that
+// moved the money, so a merchant is notified of every committed payment.
+type WebhookDelivery struct {
+	ID         int64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	MerchantID string          `gorm:"type:varchar(64);not null" json:"merchant_id"`
+	EventType  string          `gorm:"type:varchar(40);not null" json:"type"`
+	Payload    json.RawMessage `gorm:"type:jsonb;not null" json:"data"`
+	Status     string          `gorm:"type:varchar(10);not null;default:pending" json:"-"`
+	Attempts   int             `gorm:"not null;default:0" json:"-"`
+	// NextAttemptAt is when the dispatcher may next try the delivery. While
+	// it is being sent, it is pushed forward as a lease.
+	NextAttemptAt time.Time `gorm:"not null;default:current_timestamp" json:"-"`
+	// LastStatusCode is the HTTP status of the latest attempt, 0 if the
+	// merchant could not be reached.
+	LastStatusCode int        `gorm:"not null;default:0" json:"-"`
+	LastError      string     `gorm:"type:text;not null;default:''" json:"-"`
+	CreatedAt      time.Time  `gorm:"default:current_timestamp" json:"created_at"`
+	DeliveredAt    *time.Time `json:"-"`
+}
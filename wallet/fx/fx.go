@@ -0,0 +1,108 @@
+// Package fx provides exchange rate sources for cross-currency transfers.
+//
+// Static keeps rates in memory and is what tests and single-node setups use.
+// LoadFile fills a Static from a YAML file mapping "FROM/TO" pairs to decimal
+// rates:
+//
+//	USD/IDR: "16250"
+//	SGD/IDR: "12100.50"
+//	USD/SGD: "1.3425"
+//
+// A pair that is missing is derived from its reverse, so USD/IDR also
+// provides IDR/USD.
+package fx
+
+import (
+	"context"
+	"ewallet/pkg/money"
+	"ewallet/wallet/service"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Static is an in-memory RateProvider. It is safe for concurrent use.
+type Static struct {
+	mu    sync.RWMutex
+	rates map[string]money.Rate
+}
+
+// NewStatic returns a provider holding rates, keyed by "FROM/TO" pairs such
+// as "USD/IDR".
+func NewStatic(rates map[string]money.Rate) (*Static, error) {
+	s := &Static{rates: make(map[string]money.Rate, len(rates))}
+	for pair, rate := range rates {
+		from, to, err := splitPair(pair)
+		if err != nil {
+			return nil, err
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("fx: %s: %w", pair, money.ErrInvalidRate)
+		}
+		s.rates[from+"/"+to] = rate
+	}
+	return s, nil
+}
+
+// LoadFile reads rates from a YAML file; see the package documentation for
+// the format.
+func LoadFile(path string) (*Static, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fx: reading %s: %w", path, err)
+	}
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("fx: parsing %s: %w", path, err)
+	}
+
+	rates := make(map[string]money.Rate, len(raw))
+	for pair, text := range raw {
+		rate, err := money.ParseRate(text)
+		if err != nil {
+			return nil, fmt.Errorf("fx: %s in %s: %w", pair, path, err)
+		}
+		rates[pair] = rate
+	}
+	return NewStatic(rates)
+}
+
+// Set replaces the rate for one pair.
+func (s *Static) Set(from, to string, rate money.Rate) error {
+	if rate <= 0 {
+		return fmt.Errorf("fx: %s/%s: %w", from, to, money.ErrInvalidRate)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates[from+"/"+to] = rate
+	return nil
+}
+
+// Rate implements service.RateProvider.
+func (s *Static) Rate(ctx context.Context, from, to string) (money.Rate, error) {
+	if from == to {
+		return money.One, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if rate, ok := s.rates[from+"/"+to]; ok {
+		return rate, nil
+	}
+	if reverse, ok := s.rates[to+"/"+from]; ok {
+		return reverse.Inverse()
+	}
+	return 0, fmt.Errorf("%s/%s: %w", from, to, service.ErrRateUnavailable)
+}
+
+// splitPair validates a "FROM/TO" key.
+func splitPair(pair string) (string, string, error) {
+	from, to, ok := strings.Cut(pair, "/")
+	if !ok || !money.IsSupportedCurrency(from) || !money.IsSupportedCurrency(to) || from == to {
+		return "", "", fmt.Errorf("fx: %q is not a pair of two different supported currencies", pair)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	pb "ewallet/wallet/proto"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AuthorizePayment handles the gRPC request to hold funds for a later
+// capture
+func (h *TransactionHandler) AuthorizePayment(ctx context.Context, req *pb.AuthorizePaymentRequest) (*pb.AuthorizePaymentResponse, error) {
+	ttl := time.Duration(req.TtlSeconds) * time.Second
+
+	authorization, err := h.service.AuthorizePayment(ctx, int(req.WalletId), money.Amount(req.Amount), req.MerchantId, ttl, req.IdempotencyKey)
+	if err != nil {
+		return nil, toStatus(err, "failed to authorize payment")
+	}
+
+	return &pb.AuthorizePaymentResponse{
+		Authorization: toPBAuthorization(authorization),
+	}, nil
+}
+
+// CapturePayment handles the gRPC request to capture an authorization
+func (h *TransactionHandler) CapturePayment(ctx context.Context, req *pb.CapturePaymentRequest) (*pb.CapturePaymentResponse, error) {
+	transaction, err := h.service.CapturePayment(ctx, uint(req.AuthorizationId), money.Amount(req.Amount))
+	if err != nil {
+		return nil, toStatus(err, "failed to capture payment")
+	}
+
+	return &pb.CapturePaymentResponse{
+		Transaction: toPBTransaction(transaction),
+	}, nil
+}
+
+// VoidAuthorization handles the gRPC request to release an authorization
+func (h *TransactionHandler) VoidAuthorization(ctx context.Context, req *pb.VoidAuthorizationRequest) (*pb.VoidAuthorizationResponse, error) {
+	authorization, err := h.service.VoidAuthorization(ctx, uint(req.AuthorizationId))
+	if err != nil {
+		return nil, toStatus(err, "failed to void authorization")
+	}
+
+	return &pb.VoidAuthorizationResponse{
+		Authorization: toPBAuthorization(authorization),
+	}, nil
+}
+
+// GetAuthorization handles the gRPC request to get an authorization by ID
+func (h *TransactionHandler) GetAuthorization(ctx context.Context, req *pb.GetAuthorizationRequest) (*pb.GetAuthorizationResponse, error) {
+	authorization, err := h.service.GetAuthorization(ctx, uint(req.AuthorizationId))
+	if err != nil {
+		return nil, toStatus(err, "failed to get authorization")
+	}
+
+	return &pb.GetAuthorizationResponse{
+		Authorization: toPBAuthorization(authorization),
+	}, nil
+}
+
+// toPBAuthorization converts an authorization to its protobuf form
+func toPBAuthorization(authorization entity.Authorization) *pb.Authorization {
+	pbAuthorization := &pb.Authorization{
+		AuthorizationId: uint32(authorization.AuthorizationID),
+		WalletId:        int32(authorization.WalletID),
+		MerchantId:      authorization.MerchantID,
+		Amount:          authorization.Amount.Minor(),
+		CapturedAmount:  authorization.CapturedAmount.Minor(),
+		Status:          authorization.Status,
+		ExpiresAt:       timestamppb.New(authorization.ExpiresAt),
+		CreatedAt:       timestamppb.New(authorization.CreatedAt),
+		UpdatedAt:       timestamppb.New(authorization.UpdatedAt),
+	}
+	if authorization.TransactionID != nil {
+		pbAuthorization.TransactionId = uint32(*authorization.TransactionID)
+	}
+	return pbAuthorization
+}
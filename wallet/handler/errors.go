@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/service"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus converts an error returned by the transaction service into a
+// gRPC status, choosing the code from the domain error it wraps. msg
+// describes the failed operation.
+func toStatus(err error, msg string) error {
+	var validationErr *service.ValidationError
+	var limitErr *service.LimitError
+	switch {
+	case errors.As(err, &validationErr):
+		return invalidArgument(validationErr)
+	case errors.As(err, &limitErr):
+		return limitExceeded(limitErr, msg)
+	case errors.Is(err, service.ErrWalletNotFound),
+		errors.Is(err, service.ErrTransactionNotFound),
+		errors.Is(err, service.ErrWebhookNotFound),
+		errors.Is(err, service.ErrWebhookDeliveryNotFound),
+		errors.Is(err, service.ErrAuthorizationNotFound),
+		errors.Is(err, service.ErrScheduleNotFound):
+		return status.Errorf(codes.NotFound, "%s: %v", msg, err)
+	case errors.Is(err, service.ErrInsufficientFunds),
+		errors.Is(err, service.ErrRateUnavailable),
+		errors.Is(err, service.ErrNotRefundable),
+		errors.Is(err, service.ErrRefundExceedsRemaining),
+		errors.Is(err, service.ErrAuthorizationClosed),
+		errors.Is(err, service.ErrAuthorizationExpired),
+		errors.Is(err, service.ErrCaptureExceedsAuthorization),
+		errors.Is(err, service.ErrScheduleClosed),
+		errors.Is(err, service.ErrWalletFrozen),
+		errors.Is(err, service.ErrWalletClosed),
+		errors.Is(err, service.ErrWalletNotEmpty):
+		return status.Errorf(codes.FailedPrecondition, "%s: %v", msg, err)
+	case errors.Is(err, service.ErrRiskDenied):
+		return status.Errorf(codes.PermissionDenied, "%s: %v", msg, err)
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return status.Errorf(codes.AlreadyExists, "%s: %v", msg, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Errorf(codes.DeadlineExceeded, "%s: %v", msg, err)
+	case errors.Is(err, context.Canceled):
+		return status.Errorf(codes.Canceled, "%s: %v", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", msg, err)
+	}
+}
+
+// invalidArgument builds an InvalidArgument status carrying a BadRequest
+// detail with one entry per rejected field
+func invalidArgument(validationErr *service.ValidationError) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range validationErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	st, err := status.New(codes.InvalidArgument, validationErr.Error()).WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, validationErr.Error())
+	}
+	return st.Err()
+}
+
+// LimitExceededReason is the ErrorInfo reason of a FailedPrecondition status
+// caused by a spending limit.
+const LimitExceededReason = "LIMIT_EXCEEDED"
+
+// limitExceeded builds a FailedPrecondition status carrying an ErrorInfo
+// detail that tells which limit was hit, so clients can tell it from
+// insufficient funds
+func limitExceeded(limitErr *service.LimitError, msg string) error {
+	info := &errdetails.ErrorInfo{
+		Reason: LimitExceededReason,
+		Domain: "wallet",
+		Metadata: map[string]string{
+			"wallet_id": strconv.Itoa(limitErr.WalletID),
+			"limit":     limitErr.Limit,
+			"max":       strconv.FormatInt(limitErr.Max, 10),
+			"used":      strconv.FormatInt(limitErr.Used, 10),
+			"requested": strconv.FormatInt(limitErr.Requested, 10),
+			"resets_at": limitErr.ResetsAt.Format(time.RFC3339),
+		},
+	}
+	if limitErr.Currency != "" {
+		info.Metadata["currency"] = limitErr.Currency
+		info.Metadata["max"] = money.Amount(limitErr.Max).String()
+		info.Metadata["used"] = money.Amount(limitErr.Used).String()
+		info.Metadata["requested"] = money.Amount(limitErr.Requested).String()
+	}
+
+	message := fmt.Sprintf("%s: %v", msg, limitErr)
+	st, err := status.New(codes.FailedPrecondition, message).WithDetails(info)
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, message)
+	}
+	return st.Err()
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	pb "ewallet/wallet/proto"
+	"ewallet/wallet/service"
+)
+
+// GetWalletLimits handles the gRPC request to get a wallet's spending
+// limits and usage
+func (h *TransactionHandler) GetWalletLimits(ctx context.Context, req *pb.GetWalletLimitsRequest) (*pb.GetWalletLimitsResponse, error) {
+	limits, err := h.service.GetWalletLimits(ctx, int(req.WalletId))
+	if err != nil {
+		return nil, toStatus(err, "failed to get wallet limits")
+	}
+	return &pb.GetWalletLimitsResponse{Limits: toPBWalletLimits(limits)}, nil
+}
+
+// SetUserTier handles the gRPC request to assign a user's limit tier
+func (h *TransactionHandler) SetUserTier(ctx context.Context, req *pb.SetUserTierRequest) (*pb.SetUserTierResponse, error) {
+	if err := h.service.SetUserTier(ctx, uint(req.UserId), req.Tier); err != nil {
+		return nil, toStatus(err, "failed to set user tier")
+	}
+	return &pb.SetUserTierResponse{UserId: req.UserId, Tier: req.Tier}, nil
+}
+
+// SetWalletLimits handles the gRPC request to override a wallet's tier
+// limits
+func (h *TransactionHandler) SetWalletLimits(ctx context.Context, req *pb.SetWalletLimitsRequest) (*pb.SetWalletLimitsResponse, error) {
+	limit := entity.WalletLimit{WalletID: int(req.WalletId)}
+	if req.DailyAmount != nil {
+		amount := money.Amount(req.GetDailyAmount())
+		limit.DailyAmount = &amount
+	}
+	if req.MonthlyAmount != nil {
+		amount := money.Amount(req.GetMonthlyAmount())
+		limit.MonthlyAmount = &amount
+	}
+	if req.DailyCount != nil {
+		count := int(req.GetDailyCount())
+		limit.DailyCount = &count
+	}
+	if req.MonthlyCount != nil {
+		count := int(req.GetMonthlyCount())
+		limit.MonthlyCount = &count
+	}
+
+	limits, err := h.service.SetWalletLimits(ctx, limit)
+	if err != nil {
+		return nil, toStatus(err, "failed to set wallet limits")
+	}
+	return &pb.SetWalletLimitsResponse{Limits: toPBWalletLimits(limits)}, nil
+}
+
+// toPBWalletLimits converts a wallet's limits and usage to their protobuf
+// form
+func toPBWalletLimits(limits service.WalletLimits) *pb.WalletLimits {
+	return &pb.WalletLimits{
+		WalletId:          int32(limits.WalletID),
+		Currency:          limits.Currency,
+		Tier:              limits.Tier,
+		DailyAmount:       int64(limits.Limits.DailyAmount),
+		MonthlyAmount:     int64(limits.Limits.MonthlyAmount),
+		DailyCount:        int32(limits.Limits.DailyCount),
+		MonthlyCount:      int32(limits.Limits.MonthlyCount),
+		UsedDailyAmount:   int64(limits.Usage.DailyAmount),
+		UsedMonthlyAmount: int64(limits.Usage.MonthlyAmount),
+		UsedDailyCount:    int32(limits.Usage.DailyCount),
+		UsedMonthlyCount:  int32(limits.Usage.MonthlyCount),
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	pb "ewallet/wallet/proto"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateScheduledTransfer handles the gRPC request to create a standing
+// order
+func (h *TransactionHandler) CreateScheduledTransfer(ctx context.Context, req *pb.CreateScheduledTransferRequest) (*pb.CreateScheduledTransferResponse, error) {
+	transfer, err := h.schedules.CreateScheduledTransfer(ctx, entity.ScheduledTransfer{
+		FromWalletID: int(req.FromWalletId),
+		ToWalletID:   int(req.ToWalletId),
+		Amount:       money.Amount(req.Amount),
+		Schedule:     req.Schedule,
+		Description:  req.Description,
+		MaxRuns:      int(req.MaxRuns),
+	})
+	if err != nil {
+		return nil, toStatus(err, "failed to create scheduled transfer")
+	}
+	return &pb.CreateScheduledTransferResponse{ScheduledTransfer: toPBScheduledTransfer(transfer)}, nil
+}
+
+// GetScheduledTransfer handles the gRPC request to get a standing order by
+// ID
+func (h *TransactionHandler) GetScheduledTransfer(ctx context.Context, req *pb.GetScheduledTransferRequest) (*pb.GetScheduledTransferResponse, error) {
+	transfer, err := h.schedules.GetScheduledTransfer(ctx, req.ScheduleId)
+	if err != nil {
+		return nil, toStatus(err, "failed to get scheduled transfer")
+	}
+	return &pb.GetScheduledTransferResponse{ScheduledTransfer: toPBScheduledTransfer(transfer)}, nil
+}
+
+// GetScheduledTransfersByUserID handles the gRPC request to list a user's
+// standing orders
+func (h *TransactionHandler) GetScheduledTransfersByUserID(ctx context.Context, req *pb.GetScheduledTransfersByUserIDRequest) (*pb.GetScheduledTransfersByUserIDResponse, error) {
+	transfers, err := h.schedules.GetScheduledTransfersByUserID(ctx, int(req.UserId))
+	if err != nil {
+		return nil, toStatus(err, "failed to get scheduled transfers")
+	}
+
+	var pbTransfers []*pb.ScheduledTransfer
+	for _, transfer := range transfers {
+		pbTransfers = append(pbTransfers, toPBScheduledTransfer(transfer))
+	}
+	return &pb.GetScheduledTransfersByUserIDResponse{ScheduledTransfers: pbTransfers}, nil
+}
+
+// PauseScheduledTransfer handles the gRPC request to pause a standing order
+func (h *TransactionHandler) PauseScheduledTransfer(ctx context.Context, req *pb.PauseScheduledTransferRequest) (*pb.PauseScheduledTransferResponse, error) {
+	transfer, err := h.schedules.PauseScheduledTransfer(ctx, req.ScheduleId)
+	if err != nil {
+		return nil, toStatus(err, "failed to pause scheduled transfer")
+	}
+	return &pb.PauseScheduledTransferResponse{ScheduledTransfer: toPBScheduledTransfer(transfer)}, nil
+}
+
+// ResumeScheduledTransfer handles the gRPC request to resume a paused
+// standing order
+func (h *TransactionHandler) ResumeScheduledTransfer(ctx context.Context, req *pb.ResumeScheduledTransferRequest) (*pb.ResumeScheduledTransferResponse, error) {
+	transfer, err := h.schedules.ResumeScheduledTransfer(ctx, req.ScheduleId)
+	if err != nil {
+		return nil, toStatus(err, "failed to resume scheduled transfer")
+	}
+	return &pb.ResumeScheduledTransferResponse{ScheduledTransfer: toPBScheduledTransfer(transfer)}, nil
+}
+
+// CancelScheduledTransfer handles the gRPC request to cancel a standing
+// order
+func (h *TransactionHandler) CancelScheduledTransfer(ctx context.Context, req *pb.CancelScheduledTransferRequest) (*pb.CancelScheduledTransferResponse, error) {
+	transfer, err := h.schedules.CancelScheduledTransfer(ctx, req.ScheduleId)
+	if err != nil {
+		return nil, toStatus(err, "failed to cancel scheduled transfer")
+	}
+	return &pb.CancelScheduledTransferResponse{ScheduledTransfer: toPBScheduledTransfer(transfer)}, nil
+}
+
+// GetScheduledTransferRuns handles the gRPC request to list a standing
+// order's run history
+func (h *TransactionHandler) GetScheduledTransferRuns(ctx context.Context, req *pb.GetScheduledTransferRunsRequest) (*pb.GetScheduledTransferRunsResponse, error) {
+	runs, err := h.schedules.GetScheduledTransferRuns(ctx, req.ScheduleId, int(req.Limit))
+	if err != nil {
+		return nil, toStatus(err, "failed to get scheduled transfer runs")
+	}
+
+	var pbRuns []*pb.ScheduledTransferRun
+	for _, run := range runs {
+		pbRun := &pb.ScheduledTransferRun{
+			Id:           run.ID,
+			ScheduleId:   run.ScheduleID,
+			ScheduledFor: timestamppb.New(run.ScheduledFor),
+			Status:       run.Status,
+			Error:        run.Error,
+			CreatedAt:    timestamppb.New(run.CreatedAt),
+		}
+		if run.TransactionID != nil {
+			pbRun.TransactionId = uint32(*run.TransactionID)
+		}
+		pbRuns = append(pbRuns, pbRun)
+	}
+	return &pb.GetScheduledTransferRunsResponse{Runs: pbRuns}, nil
+}
+
+// toPBScheduledTransfer converts a standing order to its protobuf form
+func toPBScheduledTransfer(transfer entity.ScheduledTransfer) *pb.ScheduledTransfer {
+	pbTransfer := &pb.ScheduledTransfer{
+		Id:           transfer.ID,
+		UserId:       uint32(transfer.UserID),
+		FromWalletId: int32(transfer.FromWalletID),
+		ToWalletId:   int32(transfer.ToWalletID),
+		Amount:       int64(transfer.Amount),
+		Schedule:     transfer.Schedule,
+		Description:  transfer.Description,
+		Status:       transfer.Status,
+		NextRunAt:    timestamppb.New(transfer.NextRunAt),
+		MaxRuns:      int32(transfer.MaxRuns),
+		RunCount:     int32(transfer.RunCount),
+		CreatedAt:    timestamppb.New(transfer.CreatedAt),
+		UpdatedAt:    timestamppb.New(transfer.UpdatedAt),
+	}
+	if transfer.LastRunAt != nil {
+		pbTransfer.LastRunAt = timestamppb.New(*transfer.LastRunAt)
+	}
+	return pbTransfer
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"ewallet/pkg/money"
 	"ewallet/wallet/entity"
 	pb "ewallet/wallet/proto"
 	"ewallet/wallet/service"
@@ -15,35 +16,14 @@ import (
 // TransactionHandler implements the gRPC service defined in the protobuf file
 type TransactionHandler struct {
 	pb.UnimplementedTransactionServiceServer
-	service service.ITransactionService
+	service   service.ITransactionService
+	webhooks  *service.WebhookService
+	schedules *service.SchedulerService
 }
 
 // NewTransactionHandler creates a new instance of TransactionHandler
-func NewTransactionHandler(svc service.ITransactionService) *TransactionHandler {
-	return &TransactionHandler{service: svc}
-}
-
-// CreateTransaction handles the gRPC request to create a transaction
-func (h *TransactionHandler) CreateTransaction(ctx context.Context, req *pb.CreateTransactionRequest) (*pb.CreateTransactionResponse, error) {
-	transaction := &entity.Transaction{
-		WalletID:        int(req.Transaction.WalletId),
-		Amount:          float64(req.Transaction.Amount),
-		TransactionType: req.Transaction.TransactionType,
-		CreatedAt:       req.Transaction.CreatedAt.AsTime(),
-	}
-	createdTransaction, err := h.service.CreateTransaction(ctx, transaction)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create transaction: %v", err)
-	}
-	return &pb.CreateTransactionResponse{
-		Transaction: &pb.Transaction{
-			TransactionId:   uint32(createdTransaction.TransactionID),
-			WalletId:        int32(createdTransaction.WalletID),
-			Amount:          float32(createdTransaction.Amount),
-			TransactionType: createdTransaction.TransactionType,
-			CreatedAt:       timestamppb.New(createdTransaction.CreatedAt),
-		},
-	}, nil
+func NewTransactionHandler(svc service.ITransactionService, webhooks *service.WebhookService, schedules *service.SchedulerService) *TransactionHandler {
+	return &TransactionHandler{service: svc, webhooks: webhooks, schedules: schedules}
 }
 
 // GetTransaction handles the gRPC request to get a transaction by ID
@@ -55,42 +35,29 @@ func (h *TransactionHandler) GetTransaction(ctx context.Context, req *pb.GetTran
 
 	transaction, err := h.service.GetTransaction(ctx, int32(transactionID))
 	if err != nil {
-		if err.Error() == "transaction not found" {
-			return nil, status.Errorf(codes.NotFound, "transaction not found")
-		}
-		return nil, status.Errorf(codes.Internal, "failed to get transaction: %v", err)
+		return nil, toStatus(err, "failed to get transaction")
 	}
 	return &pb.GetTransactionResponse{
-		Transaction: &pb.Transaction{
-			TransactionId:   uint32(transaction.TransactionID),
-			WalletId:        int32(transaction.WalletID),
-			Amount:          float32(transaction.Amount),
-			TransactionType: transaction.TransactionType,
-			CreatedAt:       timestamppb.New(transaction.CreatedAt),
-			Walletidsource:  int32(transaction.WalletIDSource),
-		},
+		Transaction: toPBTransaction(transaction),
 	}, nil
 }
 
-// CreateWallet handles the gRPC request to create a wallet
+// CreateWallet handles the gRPC request to create a wallet. Timestamps sent
+// by the client are ignored; the database sets them.
 func (h *TransactionHandler) CreateWallet(ctx context.Context, req *pb.CreateWalletRequest) (*pb.CreateWalletResponse, error) {
 	wallet := &entity.Wallet{
 		UserID:    uint(req.Wallet.UserId),
-		Balance:   float64(req.Wallet.Balance),
-		CreatedAt: req.Wallet.CreatedAt.AsTime(),
-		UpdatedAt: req.Wallet.UpdatedAt.AsTime(),
+		Balance:   money.Amount(req.Wallet.Balance),
+		Currency:  req.Wallet.Currency,
+		Name:      req.Wallet.Name,
+		IsDefault: req.Wallet.IsDefault,
 	}
 	createdWallet, err := h.service.CreateWallet(ctx, wallet)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create wallet: %v", err)
+		return nil, toStatus(err, "failed to create wallet")
 	}
 	return &pb.CreateWalletResponse{
-		Wallet: &pb.Wallet{
-			UserId:    uint32(createdWallet.UserID),
-			Balance:   float32(createdWallet.Balance),
-			CreatedAt: timestamppb.New(createdWallet.CreatedAt),
-			UpdatedAt: timestamppb.New(createdWallet.UpdatedAt),
-		},
+		Wallet: toPBWallet(createdWallet),
 	}, nil
 }
 
@@ -98,74 +65,100 @@ func (h *TransactionHandler) CreateWallet(ctx context.Context, req *pb.CreateWal
 func (h *TransactionHandler) TransferWallet(ctx context.Context, req *pb.TransferWalletRequest) (*pb.TransferWalletResponse, error) {
 	fromWalletID := int(req.FromWalletId)
 	toWalletID := int(req.ToWalletId)
-	amount := req.Amount
+	amount := money.Amount(req.Amount)
 
-	err := h.service.TransferWallet(ctx, fromWalletID, toWalletID, float64(amount))
+	transaction, err := h.service.TransferWallet(ctx, fromWalletID, toWalletID, amount, req.IdempotencyKey)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to transfer wallet: %v", err)
+		return nil, toStatus(err, "failed to transfer wallet")
 	}
 
 	return &pb.TransferWalletResponse{
-		Message: "Transfer successful",
+		Message:     "Transfer successful",
+		Transaction: toPBTransaction(transaction),
 	}, nil
 }
 
 // TopUp handles the gRPC request to top up a wallet
 func (h *TransactionHandler) TopUp(ctx context.Context, req *pb.TopUpRequest) (*pb.TopUpResponse, error) {
 	walletID := int(req.WalletId)
-	amount := req.Amount
+	amount := money.Amount(req.Amount)
 
-	err := h.service.TopUp(ctx, walletID, float64(amount))
+	transaction, err := h.service.TopUp(ctx, walletID, amount, req.IdempotencyKey)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to top up wallet: %v", err)
-	}
-
-	// Fetch the updated wallet to return the latest transaction details
-	updatedWallet, err := h.service.GetWalletByID(ctx, walletID)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get updated wallet: %v", err)
-	}
-
-	// Create the transaction record for the top-up
-	transaction := &pb.Transaction{
-		WalletId:        int32(updatedWallet.Walletid),
-		Amount:          float32(amount),
-		TransactionType: "in",
-		CreatedAt:       timestamppb.Now(),
+		return nil, toStatus(err, "failed to top up wallet")
 	}
 
 	return &pb.TopUpResponse{
-		Transaction: transaction,
+		Transaction: toPBTransaction(transaction),
 	}, nil
 }
 
 // Payment handles the gRPC request to make a payment from a wallet
 func (h *TransactionHandler) Payment(ctx context.Context, req *pb.PaymentRequest) (*pb.PaymentResponse, error) {
 	walletID := int(req.WalletId)
-	amount := req.Amount
+	amount := money.Amount(req.Amount)
 
-	err := h.service.Payment(ctx, walletID, float64(amount))
+	transaction, err := h.service.Payment(ctx, walletID, amount, req.MerchantId, req.IdempotencyKey)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to make payment: %v", err)
+		return nil, toStatus(err, "failed to make payment")
 	}
 
-	// Fetch the updated wallet to return the latest transaction details
-	updatedWallet, err := h.service.GetWalletByID(ctx, walletID)
+	return &pb.PaymentResponse{
+		Transaction: toPBTransaction(transaction),
+	}, nil
+}
+
+// Refund handles the gRPC request to refund a payment or a transfer
+func (h *TransactionHandler) Refund(ctx context.Context, req *pb.RefundRequest) (*pb.RefundResponse, error) {
+	transaction, err := h.service.Refund(ctx, uint(req.TransactionId), money.Amount(req.Amount), req.IdempotencyKey)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get updated wallet: %v", err)
+		return nil, toStatus(err, "failed to refund transaction")
 	}
 
-	// Create the transaction record for the payment
-	transaction := &pb.Transaction{
-		WalletId:        int32(updatedWallet.Walletid),
-		Amount:          float32(amount),
-		TransactionType: "out",
-		CreatedAt:       timestamppb.Now(),
+	return &pb.RefundResponse{
+		Transaction: toPBTransaction(transaction),
+	}, nil
+}
+
+// toPBTransaction converts a ledger row to its protobuf form
+func toPBTransaction(transaction entity.Transaction) *pb.Transaction {
+	pbTransaction := &pb.Transaction{
+		TransactionId:   uint32(transaction.TransactionID),
+		WalletId:        int32(transaction.WalletID),
+		Amount:          transaction.Amount.Minor(),
+		TransactionType: transaction.TransactionType,
+		CreatedAt:       timestamppb.New(transaction.CreatedAt),
+		Walletidsource:  int32(transaction.WalletIDSource),
+
+		OriginalAmount:    transaction.OriginalAmount.Minor(),
+		OriginalCurrency:  transaction.OriginalCurrency,
+		ConvertedAmount:   transaction.ConvertedAmount.Minor(),
+		ConvertedCurrency: transaction.ConvertedCurrency,
+		FxRate:            transaction.FxRate.String(),
+		MerchantId:        transaction.MerchantID,
+		RefundedAmount:    transaction.RefundedAmount.Minor(),
+	}
+	if transaction.RefundOf != nil {
+		pbTransaction.RefundOf = uint32(*transaction.RefundOf)
 	}
+	return pbTransaction
+}
 
-	return &pb.PaymentResponse{
-		Transaction: transaction,
-	}, nil
+// toPBWallet converts a wallet to its protobuf form
+func toPBWallet(wallet entity.Wallet) *pb.Wallet {
+	return &pb.Wallet{
+		Id:               int32(wallet.Walletid),
+		UserId:           uint32(wallet.UserID),
+		Balance:          wallet.Balance.Minor(),
+		Currency:         wallet.Currency,
+		Name:             wallet.Name,
+		IsDefault:        wallet.IsDefault,
+		AvailableBalance: wallet.Available().Minor(),
+		Status:           wallet.Status,
+		StatusReason:     wallet.StatusReason,
+		CreatedAt:        timestamppb.New(wallet.CreatedAt),
+		UpdatedAt:        timestamppb.New(wallet.UpdatedAt),
+	}
 }
 
 // GetWalletByUserID handles the gRPC request to get a wallet by user ID
@@ -174,47 +167,66 @@ func (h *TransactionHandler) GetWalletByUserID(ctx context.Context, req *pb.GetW
 
 	wallet, err := h.service.GetWalletByUserID(ctx, userID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get wallet by user ID: %v", err)
+		return nil, toStatus(err, "failed to get wallet by user ID")
+	}
+
+	return &pb.GetWalletByUserIDResponse{
+		Wallets: toPBWallet(wallet),
+	}, nil
+}
+
+// GetWalletsByUserID handles the gRPC request to list every wallet of a user
+func (h *TransactionHandler) GetWalletsByUserID(ctx context.Context, req *pb.GetWalletsByUserIDRequest) (*pb.GetWalletsByUserIDResponse, error) {
+	wallets, err := h.service.GetWalletsByUserID(ctx, int(req.UserId))
+	if err != nil {
+		return nil, toStatus(err, "failed to get wallets by user ID")
 	}
 
-	// Convert the wallet to the protobuf format
-	pbWallet := &pb.Wallet{
-		Id:        int32(wallet.Walletid),
-		UserId:    uint32(wallet.UserID),
-		Balance:   float32(wallet.Balance),
-		CreatedAt: timestamppb.New(wallet.CreatedAt),
-		UpdatedAt: timestamppb.New(wallet.UpdatedAt),
+	pbWallets := make([]*pb.Wallet, 0, len(wallets))
+	for _, wallet := range wallets {
+		pbWallets = append(pbWallets, toPBWallet(wallet))
 	}
 
-	return &pb.GetWalletByUserIDResponse{
-		Wallets: pbWallet,
+	return &pb.GetWalletsByUserIDResponse{
+		Wallets: pbWallets,
 	}, nil
 }
 
-// GetTransactionByUserID handles the gRPC request to get transactions by user ID
+// GetTransactionByUserID handles the gRPC request to get one page of a
+// user's transactions
 func (h *TransactionHandler) GetTransactionByUserID(ctx context.Context, req *pb.GetTransactionByUserIDRequest) (*pb.GetTransactionByUserIDResponse, error) {
 	userID := int(req.UserId)
 
-	transactions, err := h.service.GetTransactionByUserID(ctx, userID)
+	query := service.TransactionQuery{
+		PageSize:             int(req.PageSize),
+		Cursor:               req.Cursor,
+		Type:                 req.TransactionType,
+		CounterpartyWalletID: int(req.CounterpartyWalletId),
+		CounterpartyUserID:   int(req.CounterpartyUserId),
+		MinAmount:            money.Amount(req.MinAmount),
+		MaxAmount:            money.Amount(req.MaxAmount),
+	}
+	if req.CreatedFrom != nil {
+		query.CreatedFrom = req.CreatedFrom.AsTime()
+	}
+	if req.CreatedTo != nil {
+		query.CreatedTo = req.CreatedTo.AsTime()
+	}
+
+	page, err := h.service.GetTransactionByUserID(ctx, userID, query)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get transactions by user ID: %v", err)
+		return nil, toStatus(err, "failed to get transactions by user ID")
 	}
 
 	// Convert the transactions to the protobuf format
 	var pbTransactions []*pb.Transaction
-	for _, transaction := range transactions {
-		pbTransactions = append(pbTransactions, &pb.Transaction{
-			TransactionId:   uint32(transaction.TransactionID),
-			WalletId:        int32(transaction.WalletID),
-			Amount:          float32(transaction.Amount),
-			TransactionType: transaction.TransactionType,
-			CreatedAt:       timestamppb.New(transaction.CreatedAt),
-			Walletidsource:  int32(transaction.WalletIDSource),
-		})
+	for _, transaction := range page.Transactions {
+		pbTransactions = append(pbTransactions, toPBTransaction(transaction))
 	}
 
 	return &pb.GetTransactionByUserIDResponse{
 		Transactions: pbTransactions,
+		NextCursor:   page.NextCursor,
 	}, nil
 }
 
@@ -223,19 +235,52 @@ func (h *TransactionHandler) GetWalletByID(ctx context.Context, req *pb.GetWalle
 
 	wallet, err := h.service.GetWalletByID(ctx, id)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get wallet by user ID: %v", err)
+		return nil, toStatus(err, "failed to get wallet by ID")
 	}
 
-	// Convert the wallet to the protobuf format
-	pbWallet := &pb.Wallet{
-		Id:        int32(wallet.Walletid),
-		UserId:    uint32(wallet.UserID),
-		Balance:   float32(wallet.Balance),
-		CreatedAt: timestamppb.New(wallet.CreatedAt),
-		UpdatedAt: timestamppb.New(wallet.UpdatedAt),
+	return &pb.GetWalletByIdrespon{
+		Wallet: toPBWallet(wallet),
+	}, nil
+}
+
+// GetWalletsByIDs handles the gRPC request to look up several wallets at once
+func (h *TransactionHandler) GetWalletsByIDs(ctx context.Context, req *pb.GetWalletsByIDsRequest) (*pb.GetWalletsByIDsResponse, error) {
+	ids := make([]int, len(req.Ids))
+	for i, id := range req.Ids {
+		ids[i] = int(id)
 	}
 
-	return &pb.GetWalletByIdrespon{
-		Wallet: pbWallet,
+	wallets, err := h.service.GetWalletsByIDs(ctx, ids)
+	if err != nil {
+		return nil, toStatus(err, "failed to get wallets by IDs")
+	}
+
+	pbWallets := make([]*pb.Wallet, 0, len(wallets))
+	for _, wallet := range wallets {
+		pbWallets = append(pbWallets, toPBWallet(wallet))
+	}
+
+	return &pb.GetWalletsByIDsResponse{
+		Wallets: pbWallets,
 	}, nil
 }
+
+// SubscribeWalletEvents handles the gRPC request to stream a wallet's events
+func (h *TransactionHandler) SubscribeWalletEvents(req *pb.SubscribeWalletEventsRequest, stream pb.TransactionService_SubscribeWalletEventsServer) error {
+	err := h.service.SubscribeWalletEvents(stream.Context(), int(req.WalletId), req.AfterEventId, func(event entity.WalletEvent) error {
+		return stream.Send(&pb.WalletEvent{
+			EventId:       event.EventID,
+			WalletId:      int32(event.WalletID),
+			EventType:     event.EventType,
+			TransactionId: uint32(event.TransactionID),
+			Amount:        event.Amount.Minor(),
+			Balance:       event.Balance.Minor(),
+			Currency:      event.Currency,
+			CreatedAt:     timestamppb.New(event.CreatedAt),
+		})
+	})
+	if err != nil {
+		return toStatus(err, "wallet event stream ended")
+	}
+	return nil
+}
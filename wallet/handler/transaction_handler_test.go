@@ -0,0 +1,56 @@
+package handler_test
+
+import (
+	"context"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/handler"
+	pb "ewallet/wallet/proto"
+	"ewallet/wallet/service"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// walletService records the wallet passed to CreateWallet. Other methods
+// are not implemented.
+type walletService struct {
+	service.ITransactionService
+	created entity.Wallet
+}
+
+func (s *walletService) CreateWallet(ctx context.Context, wallet *entity.Wallet) (entity.Wallet, error) {
+	s.created = *wallet
+	wallet.Walletid = 7
+	return *wallet, nil
+}
+
+func TestCreateWalletLeavesTimestampsToTheDatabase(t *testing.T) {
+	past := timestamppb.New(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name      string
+		createdAt *timestamppb.Timestamp
+		updatedAt *timestamppb.Timestamp
+	}{
+		{"no timestamps", nil, nil},
+		{"client timestamps", past, past},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &walletService{}
+			h := handler.NewTransactionHandler(svc, nil, nil)
+			_, err := h.CreateWallet(context.Background(), &pb.CreateWalletRequest{Wallet: &pb.Wallet{
+				UserId:    1,
+				Currency:  "IDR",
+				CreatedAt: tt.createdAt,
+				UpdatedAt: tt.updatedAt,
+			}})
+			if err != nil {
+				t.Fatalf("CreateWallet() error = %v", err)
+			}
+			if !svc.created.CreatedAt.IsZero() || !svc.created.UpdatedAt.IsZero() {
+				t.Fatalf("wallet created with timestamps %s and %s, want zero", svc.created.CreatedAt, svc.created.UpdatedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"context"
+	pb "ewallet/wallet/proto"
+)
+
+// SetWalletStatus handles the gRPC request to freeze or unfreeze a wallet
+func (h *TransactionHandler) SetWalletStatus(ctx context.Context, req *pb.SetWalletStatusRequest) (*pb.SetWalletStatusResponse, error) {
+	wallet, err := h.service.SetWalletStatus(ctx, int(req.WalletId), req.Status, req.Reason, uint(req.ChangedBy))
+	if err != nil {
+		return nil, toStatus(err, "failed to set wallet status")
+	}
+	return &pb.SetWalletStatusResponse{Wallet: toPBWallet(wallet)}, nil
+}
+
+// CloseWallet handles the gRPC request to close a wallet
+func (h *TransactionHandler) CloseWallet(ctx context.Context, req *pb.CloseWalletRequest) (*pb.CloseWalletResponse, error) {
+	wallet, err := h.service.CloseWallet(ctx, int(req.WalletId), int(req.SweepToWalletId), req.Reason, uint(req.ChangedBy))
+	if err != nil {
+		return nil, toStatus(err, "failed to close wallet")
+	}
+	return &pb.CloseWalletResponse{Wallet: toPBWallet(wallet)}, nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"ewallet/wallet/entity"
+	pb "ewallet/wallet/proto"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RegisterWebhook handles the gRPC request to set a merchant's webhook
+// endpoint
+func (h *TransactionHandler) RegisterWebhook(ctx context.Context, req *pb.RegisterWebhookRequest) (*pb.RegisterWebhookResponse, error) {
+	webhook, err := h.webhooks.RegisterWebhook(ctx, req.MerchantId, req.Url, req.Secret)
+	if err != nil {
+		return nil, toStatus(err, "failed to register webhook")
+	}
+
+	return &pb.RegisterWebhookResponse{
+		Webhook: &pb.Webhook{
+			MerchantId: webhook.MerchantID,
+			Url:        webhook.URL,
+			Secret:     webhook.Secret,
+			CreatedAt:  timestamppb.New(webhook.CreatedAt),
+			UpdatedAt:  timestamppb.New(webhook.UpdatedAt),
+		},
+	}, nil
+}
+
+// GetWebhookDeliveries handles the gRPC request to list a merchant's
+// webhook deliveries
+func (h *TransactionHandler) GetWebhookDeliveries(ctx context.Context, req *pb.GetWebhookDeliveriesRequest) (*pb.GetWebhookDeliveriesResponse, error) {
+	deliveries, err := h.webhooks.GetWebhookDeliveries(ctx, req.MerchantId, int(req.Limit))
+	if err != nil {
+		return nil, toStatus(err, "failed to get webhook deliveries")
+	}
+
+	var pbDeliveries []*pb.WebhookDelivery
+	for _, delivery := range deliveries {
+		pbDeliveries = append(pbDeliveries, toPBWebhookDelivery(delivery))
+	}
+	return &pb.GetWebhookDeliveriesResponse{Deliveries: pbDeliveries}, nil
+}
+
+// ReplayWebhookDelivery handles the gRPC request to send a delivery again
+func (h *TransactionHandler) ReplayWebhookDelivery(ctx context.Context, req *pb.ReplayWebhookDeliveryRequest) (*pb.ReplayWebhookDeliveryResponse, error) {
+	delivery, err := h.webhooks.ReplayWebhookDelivery(ctx, req.DeliveryId)
+	if err != nil {
+		return nil, toStatus(err, "failed to replay webhook delivery")
+	}
+
+	return &pb.ReplayWebhookDeliveryResponse{Delivery: toPBWebhookDelivery(delivery)}, nil
+}
+
+// toPBWebhookDelivery converts a delivery log entry to its protobuf form
+func toPBWebhookDelivery(delivery entity.WebhookDelivery) *pb.WebhookDelivery {
+	pbDelivery := &pb.WebhookDelivery{
+		Id:             delivery.ID,
+		MerchantId:     delivery.MerchantID,
+		EventType:      delivery.EventType,
+		Payload:        string(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       int32(delivery.Attempts),
+		LastStatusCode: int32(delivery.LastStatusCode),
+		LastError:      delivery.LastError,
+		NextAttemptAt:  timestamppb.New(delivery.NextAttemptAt),
+		CreatedAt:      timestamppb.New(delivery.CreatedAt),
+	}
+	if delivery.DeliveredAt != nil {
+		pbDelivery.DeliveredAt = timestamppb.New(*delivery.DeliveredAt)
+	}
+	return pbDelivery
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"ewallet/pkg/config"
+	"ewallet/pkg/money"
+	"ewallet/wallet/service"
+	"fmt"
+)
+
+// newLimitPolicy converts the configured tiers into the policy enforced by
+// the transaction service.
+func newLimitPolicy(tiers map[string]config.TierLimits) (service.LimitPolicy, error) {
+	policy := make(service.LimitPolicy, len(tiers))
+	for tier, limits := range tiers {
+		daily, err := parseAmounts(limits.DailyAmount)
+		if err != nil {
+			return nil, fmt.Errorf("limits.%s.daily_amount: %w", tier, err)
+		}
+		monthly, err := parseAmounts(limits.MonthlyAmount)
+		if err != nil {
+			return nil, fmt.Errorf("limits.%s.monthly_amount: %w", tier, err)
+		}
+		policy[tier] = service.TierLimits{
+			DailyAmount:   daily,
+			MonthlyAmount: monthly,
+			DailyCount:    limits.DailyCount,
+			MonthlyCount:  limits.MonthlyCount,
+		}
+	}
+	return policy, nil
+}
+
+// parseAmounts parses decimal amounts keyed by currency.
+func parseAmounts(texts map[string]string) (map[string]money.Amount, error) {
+	amounts := make(map[string]money.Amount, len(texts))
+	for currency, text := range texts {
+		amount, err := money.Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", currency, err)
+		}
+		amounts[currency] = amount
+	}
+	return amounts, nil
+}
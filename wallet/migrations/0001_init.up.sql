@@ -0,0 +1,21 @@
+-- Baseline schema of the wallet database as it existed before versioned
+-- migrations. IF NOT EXISTS lets databases created by hand adopt the
+-- migration history without changes.
+CREATE TABLE IF NOT EXISTS wallets (
+    wallet_id  serial        PRIMARY KEY,
+    user_id    integer       NOT NULL,
+    balance    decimal(10,2) DEFAULT 0.00,
+    created_at timestamptz   DEFAULT current_timestamp,
+    updated_at timestamptz   DEFAULT current_timestamp
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    transaction_id   serial        PRIMARY KEY,
+    wallet_id        integer       NOT NULL,
+    amount           decimal(10,2) NOT NULL,
+    transaction_type varchar(20)   NOT NULL,
+    created_at       timestamptz   DEFAULT current_timestamp,
+    wallet_id_source integer
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_wallet_id ON transactions (wallet_id);
@@ -0,0 +1,8 @@
+ALTER TABLE wallets
+    ALTER COLUMN balance DROP NOT NULL,
+    ALTER COLUMN balance DROP DEFAULT,
+    ALTER COLUMN balance TYPE decimal(10,2) USING balance / 100.0,
+    ALTER COLUMN balance SET DEFAULT 0.00;
+
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE decimal(10,2) USING amount / 100.0;
@@ -0,0 +1,13 @@
+-- Store money as integer minor units (1/100 of the currency unit) instead of
+-- decimal columns. Existing values are exact to two decimal places, so the
+-- multiplication below never needs to round.
+UPDATE wallets SET balance = 0 WHERE balance IS NULL;
+
+ALTER TABLE wallets
+    ALTER COLUMN balance DROP DEFAULT,
+    ALTER COLUMN balance TYPE bigint USING round(balance * 100)::bigint,
+    ALTER COLUMN balance SET DEFAULT 0,
+    ALTER COLUMN balance SET NOT NULL;
+
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100)::bigint;
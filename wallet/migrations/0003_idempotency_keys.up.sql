@@ -0,0 +1,12 @@
+-- Results of TopUp, Payment and TransferWallet keyed by the client supplied
+-- idempotency key. The primary key makes a concurrent replay fail instead of
+-- moving money twice.
+CREATE TABLE idempotency_keys (
+    key                    varchar(255) PRIMARY KEY,
+    operation              varchar(20)  NOT NULL,
+    wallet_id              integer      NOT NULL,
+    counterparty_wallet_id integer      NOT NULL DEFAULT 0,
+    amount                 bigint       NOT NULL,
+    transaction_id         bigint       NOT NULL REFERENCES transactions (transaction_id),
+    created_at             timestamptz  NOT NULL DEFAULT current_timestamp
+);
@@ -0,0 +1,44 @@
+-- Double-entry journal. Every money movement is a journal entry whose
+-- postings debit one account and credit another by the same total.
+CREATE TABLE journal_entries (
+    entry_id   bigserial   PRIMARY KEY,
+    entry_type varchar(20) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT current_timestamp
+);
+
+CREATE TABLE postings (
+    posting_id bigserial   PRIMARY KEY,
+    entry_id   bigint      NOT NULL REFERENCES journal_entries (entry_id),
+    account    varchar(64) NOT NULL,
+    wallet_id  integer     NOT NULL DEFAULT 0,
+    direction  varchar(6)  NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount     bigint      NOT NULL CHECK (amount > 0),
+    created_at timestamptz NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX idx_postings_entry_id ON postings (entry_id);
+CREATE INDEX idx_postings_account ON postings (account);
+CREATE INDEX idx_postings_wallet_id ON postings (wallet_id);
+
+ALTER TABLE transactions
+    ADD COLUMN journal_entry_id bigint REFERENCES journal_entries (entry_id);
+
+CREATE INDEX idx_transactions_journal_entry_id ON transactions (journal_entry_id);
+
+-- Carry existing balances into the journal so that every wallet's postings
+-- sum to its stored balance from day one.
+DO $$
+DECLARE
+    w     record;
+    entry bigint;
+BEGIN
+    FOR w IN SELECT wallet_id, balance FROM wallets WHERE balance <> 0 LOOP
+        INSERT INTO journal_entries (entry_type) VALUES ('opening_balance')
+            RETURNING entry_id INTO entry;
+        INSERT INTO postings (entry_id, account, wallet_id, direction, amount) VALUES
+            (entry, 'system:opening_balance', 0,
+             CASE WHEN w.balance > 0 THEN 'debit' ELSE 'credit' END, abs(w.balance)),
+            (entry, 'wallet:' || w.wallet_id, w.wallet_id,
+             CASE WHEN w.balance > 0 THEN 'credit' ELSE 'debit' END, abs(w.balance));
+    END LOOP;
+END $$;
@@ -0,0 +1,7 @@
+DROP INDEX idx_wallets_user_id;
+DROP INDEX idx_wallets_user_default;
+
+ALTER TABLE wallets
+    DROP COLUMN is_default,
+    DROP COLUMN name,
+    DROP COLUMN currency;
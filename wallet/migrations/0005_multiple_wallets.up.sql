@@ -0,0 +1,12 @@
+-- Users may hold several wallets, each with a currency and a name. The
+-- oldest existing wallet of every user becomes their default wallet.
+ALTER TABLE wallets
+    ADD COLUMN currency   char(3)     NOT NULL DEFAULT 'IDR',
+    ADD COLUMN name       varchar(50) NOT NULL DEFAULT 'Main',
+    ADD COLUMN is_default boolean     NOT NULL DEFAULT false;
+
+UPDATE wallets SET is_default = true
+WHERE wallet_id IN (SELECT min(wallet_id) FROM wallets GROUP BY user_id);
+
+CREATE UNIQUE INDEX idx_wallets_user_default ON wallets (user_id) WHERE is_default;
+CREATE INDEX idx_wallets_user_id ON wallets (user_id);
@@ -0,0 +1,9 @@
+ALTER TABLE transactions
+    DROP CONSTRAINT chk_transactions_fx_rate,
+    DROP COLUMN fx_rate,
+    DROP COLUMN converted_currency,
+    DROP COLUMN converted_amount,
+    DROP COLUMN original_currency,
+    DROP COLUMN original_amount;
+
+ALTER TABLE postings DROP COLUMN currency;
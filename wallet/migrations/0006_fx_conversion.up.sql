@@ -0,0 +1,49 @@
+-- Cross-currency transfers. Postings carry their currency so that each
+-- journal entry can balance per currency, and ledger rows record the
+-- original amount, the converted amount and the rate applied.
+ALTER TABLE postings ADD COLUMN currency char(3);
+
+UPDATE postings p SET currency = w.currency
+FROM wallets w
+WHERE p.wallet_id <> 0 AND w.wallet_id = p.wallet_id;
+
+-- Every entry so far moved money within one currency, so a system posting
+-- takes the currency of the wallet posting in the same entry.
+UPDATE postings p SET currency = pw.currency
+FROM postings pw
+WHERE p.wallet_id = 0 AND pw.entry_id = p.entry_id AND pw.wallet_id <> 0;
+
+UPDATE postings SET currency = 'IDR' WHERE currency IS NULL;
+ALTER TABLE postings ALTER COLUMN currency SET NOT NULL;
+
+ALTER TABLE transactions
+    ADD COLUMN original_amount    bigint,
+    ADD COLUMN original_currency  char(3),
+    ADD COLUMN converted_amount   bigint,
+    ADD COLUMN converted_currency char(3),
+    ADD COLUMN fx_rate            numeric(20,10);
+
+UPDATE transactions t SET
+    original_amount    = t.amount,
+    original_currency  = w.currency,
+    converted_amount   = t.amount,
+    converted_currency = w.currency,
+    fx_rate            = 1
+FROM wallets w
+WHERE w.wallet_id = t.wallet_id;
+
+UPDATE transactions SET
+    original_amount    = amount,
+    original_currency  = 'IDR',
+    converted_amount   = amount,
+    converted_currency = 'IDR',
+    fx_rate            = 1
+WHERE original_amount IS NULL;
+
+ALTER TABLE transactions
+    ALTER COLUMN original_amount    SET NOT NULL,
+    ALTER COLUMN original_currency  SET NOT NULL,
+    ALTER COLUMN converted_amount   SET NOT NULL,
+    ALTER COLUMN converted_currency SET NOT NULL,
+    ALTER COLUMN fx_rate            SET NOT NULL,
+    ADD CONSTRAINT chk_transactions_fx_rate CHECK (fx_rate > 0);
@@ -0,0 +1,3 @@
+DROP INDEX idx_transactions_wallet_history;
+
+ALTER TABLE transactions ALTER COLUMN created_at DROP NOT NULL;
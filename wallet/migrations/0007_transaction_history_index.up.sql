@@ -0,0 +1,8 @@
+-- Transaction history is read per wallet, newest first, by keyset on
+-- (created_at, transaction_id). A NULL created_at would fall outside every
+-- page, so the column becomes required.
+UPDATE transactions SET created_at = current_timestamp WHERE created_at IS NULL;
+ALTER TABLE transactions ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX idx_transactions_wallet_history
+    ON transactions (wallet_id, created_at DESC, transaction_id DESC);
@@ -0,0 +1,14 @@
+-- Balance changes of each wallet, in commit order, for subscribers of the
+-- event stream. Subscribers resume by event_id.
+CREATE TABLE wallet_events (
+    event_id       bigserial   PRIMARY KEY,
+    wallet_id      integer     NOT NULL REFERENCES wallets (wallet_id),
+    event_type     varchar(20) NOT NULL,
+    transaction_id bigint      NOT NULL,
+    amount         bigint      NOT NULL,
+    balance        bigint      NOT NULL,
+    currency       char(3)     NOT NULL,
+    created_at     timestamptz NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX idx_wallet_events_wallet_id ON wallet_events (wallet_id, event_id);
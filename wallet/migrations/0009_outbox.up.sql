@@ -0,0 +1,19 @@
+-- Transactional outbox: domain events written with the ledger rows they
+-- describe and published afterwards by the relay, at least once.
+CREATE TABLE outbox_messages (
+    id              bigserial   PRIMARY KEY,
+    event_type      varchar(40) NOT NULL,
+    aggregate_id    varchar(64) NOT NULL,
+    payload         jsonb       NOT NULL,
+    status          varchar(10) NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'published', 'dead')),
+    attempts        integer     NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT current_timestamp,
+    last_error      text        NOT NULL DEFAULT '',
+    created_at      timestamptz NOT NULL DEFAULT current_timestamp,
+    published_at    timestamptz
+);
+
+-- The relay only ever scans pending messages that are due.
+CREATE INDEX idx_outbox_messages_due ON outbox_messages (next_attempt_at, id)
+    WHERE status = 'pending';
@@ -0,0 +1,4 @@
+DROP TABLE webhook_deliveries;
+DROP TABLE merchant_webhooks;
+ALTER TABLE idempotency_keys DROP COLUMN merchant_id;
+ALTER TABLE transactions DROP COLUMN merchant_id;
@@ -0,0 +1,35 @@
+-- Payments may name the merchant being paid.
+ALTER TABLE transactions ADD COLUMN merchant_id varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ADD COLUMN merchant_id varchar(64) NOT NULL DEFAULT '';
+
+-- One webhook endpoint per merchant.
+CREATE TABLE merchant_webhooks (
+    merchant_id varchar(64) PRIMARY KEY,
+    url         text         NOT NULL,
+    secret      varchar(128) NOT NULL,
+    created_at  timestamptz  NOT NULL DEFAULT current_timestamp,
+    updated_at  timestamptz  NOT NULL DEFAULT current_timestamp
+);
+
+-- Delivery log: every event owed to a merchant and the outcome of its
+-- latest attempt.
+CREATE TABLE webhook_deliveries (
+    id               bigserial   PRIMARY KEY,
+    merchant_id      varchar(64) NOT NULL,
+    event_type       varchar(40) NOT NULL,
+    payload          jsonb       NOT NULL,
+    status           varchar(10) NOT NULL DEFAULT 'pending'
+                     CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts         integer     NOT NULL DEFAULT 0,
+    next_attempt_at  timestamptz NOT NULL DEFAULT current_timestamp,
+    last_status_code integer     NOT NULL DEFAULT 0,
+    last_error       text        NOT NULL DEFAULT '',
+    created_at       timestamptz NOT NULL DEFAULT current_timestamp,
+    delivered_at     timestamptz
+);
+
+CREATE INDEX idx_webhook_deliveries_merchant ON webhook_deliveries (merchant_id, id);
+
+-- The dispatcher only ever scans pending deliveries that are due.
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at, id)
+    WHERE status = 'pending';
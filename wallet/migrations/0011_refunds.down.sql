@@ -0,0 +1,5 @@
+ALTER TABLE idempotency_keys DROP COLUMN refund_of;
+DROP INDEX idx_transactions_refund_of;
+ALTER TABLE transactions DROP CONSTRAINT transactions_refunded_amount_check;
+ALTER TABLE transactions DROP COLUMN refunded_amount;
+ALTER TABLE transactions DROP COLUMN refund_of;
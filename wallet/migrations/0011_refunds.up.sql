@@ -0,0 +1,9 @@
+-- Refund rows point at the transaction they reverse; reversed transactions
+-- track how much of them has been refunded.
+ALTER TABLE transactions ADD COLUMN refund_of integer REFERENCES transactions (transaction_id);
+ALTER TABLE transactions ADD COLUMN refunded_amount bigint NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD CONSTRAINT transactions_refunded_amount_check
+    CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+CREATE INDEX idx_transactions_refund_of ON transactions (refund_of);
+
+ALTER TABLE idempotency_keys ADD COLUMN refund_of integer NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE authorizations;
+ALTER TABLE wallets DROP CONSTRAINT wallets_held_balance_check;
+ALTER TABLE wallets DROP COLUMN held_balance;
@@ -0,0 +1,27 @@
+-- Funds reserved by open payment authorizations. The available balance is
+-- balance - held_balance.
+ALTER TABLE wallets ADD COLUMN held_balance bigint NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD CONSTRAINT wallets_held_balance_check
+    CHECK (held_balance >= 0 AND held_balance <= balance);
+
+CREATE TABLE authorizations (
+    authorization_id serial       PRIMARY KEY,
+    wallet_id        integer      NOT NULL REFERENCES wallets (wallet_id),
+    merchant_id      varchar(64)  NOT NULL DEFAULT '',
+    amount           bigint       NOT NULL CHECK (amount > 0),
+    captured_amount  bigint       NOT NULL DEFAULT 0,
+    status           varchar(12)  NOT NULL DEFAULT 'authorized'
+                     CHECK (status IN ('authorized', 'captured', 'voided', 'expired')),
+    transaction_id   integer      REFERENCES transactions (transaction_id),
+    idempotency_key  varchar(255) UNIQUE,
+    expires_at       timestamptz  NOT NULL,
+    created_at       timestamptz  NOT NULL DEFAULT current_timestamp,
+    updated_at       timestamptz  NOT NULL DEFAULT current_timestamp,
+    CHECK (captured_amount >= 0 AND captured_amount <= amount)
+);
+
+CREATE INDEX idx_authorizations_wallet_id ON authorizations (wallet_id);
+
+-- The expiry sweep only scans open authorizations.
+CREATE INDEX idx_authorizations_open ON authorizations (expires_at)
+    WHERE status = 'authorized';
@@ -0,0 +1,38 @@
+-- Standing orders: recurring transfers executed by the scheduler.
+CREATE TABLE scheduled_transfers (
+    id             bigserial    PRIMARY KEY,
+    user_id        integer      NOT NULL,
+    from_wallet_id integer      NOT NULL REFERENCES wallets (wallet_id),
+    to_wallet_id   integer      NOT NULL REFERENCES wallets (wallet_id),
+    amount         bigint       NOT NULL CHECK (amount > 0),
+    schedule       varchar(100) NOT NULL,
+    description    varchar(255) NOT NULL DEFAULT '',
+    status         varchar(12)  NOT NULL DEFAULT 'active'
+                   CHECK (status IN ('active', 'paused', 'cancelled', 'completed')),
+    next_run_at    timestamptz  NOT NULL,
+    last_run_at    timestamptz,
+    lease_until    timestamptz,
+    max_runs       integer      NOT NULL DEFAULT 0 CHECK (max_runs >= 0),
+    run_count      integer      NOT NULL DEFAULT 0,
+    created_at     timestamptz  NOT NULL DEFAULT current_timestamp,
+    updated_at     timestamptz  NOT NULL DEFAULT current_timestamp,
+    CHECK (from_wallet_id <> to_wallet_id)
+);
+
+CREATE INDEX idx_scheduled_transfers_user_id ON scheduled_transfers (user_id);
+
+-- The scheduler only scans active schedules.
+CREATE INDEX idx_scheduled_transfers_due ON scheduled_transfers (next_run_at)
+    WHERE status = 'active';
+
+-- Run history. An occurrence is recorded once, whatever its outcome.
+CREATE TABLE scheduled_transfer_runs (
+    id             bigserial   PRIMARY KEY,
+    schedule_id    bigint      NOT NULL REFERENCES scheduled_transfers (id),
+    scheduled_for  timestamptz NOT NULL,
+    status         varchar(12) NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    transaction_id integer     REFERENCES transactions (transaction_id),
+    error          text        NOT NULL DEFAULT '',
+    created_at     timestamptz NOT NULL DEFAULT current_timestamp,
+    UNIQUE (schedule_id, scheduled_for)
+);
@@ -0,0 +1,3 @@
+DROP INDEX idx_transactions_wallet_spending;
+DROP TABLE wallet_limits;
+DROP TABLE user_tiers;
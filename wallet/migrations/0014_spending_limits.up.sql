@@ -0,0 +1,23 @@
+-- Spending limit tier of each user; users without a row are unverified.
+CREATE TABLE user_tiers (
+    user_id    integer     PRIMARY KEY,
+    tier       varchar(32) NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT current_timestamp
+);
+
+-- Per-wallet overrides of the tier limits. NULL keeps the tier's limit and
+-- 0 removes it.
+CREATE TABLE wallet_limits (
+    wallet_id      integer     PRIMARY KEY REFERENCES wallets (wallet_id),
+    daily_amount   bigint      CHECK (daily_amount >= 0),
+    monthly_amount bigint      CHECK (monthly_amount >= 0),
+    daily_count    integer     CHECK (daily_count >= 0),
+    monthly_count  integer     CHECK (monthly_count >= 0),
+    updated_at     timestamptz NOT NULL DEFAULT current_timestamp
+);
+
+-- Spending is summed over a wallet's debits since the start of the day or
+-- month.
+CREATE INDEX idx_transactions_wallet_spending
+    ON transactions (wallet_id, created_at)
+    WHERE transaction_type = 'out' AND refund_of IS NULL;
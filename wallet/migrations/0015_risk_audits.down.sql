@@ -0,0 +1,2 @@
+DROP INDEX idx_transactions_wallet_top_ups;
+DROP TABLE risk_audits;
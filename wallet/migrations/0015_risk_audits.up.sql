@@ -0,0 +1,22 @@
+-- Top-ups, payments and transfers denied or flagged for review by the risk
+-- checks.
+CREATE TABLE risk_audits (
+    id                     bigserial   PRIMARY KEY,
+    wallet_id              integer     NOT NULL REFERENCES wallets (wallet_id),
+    operation              varchar(20) NOT NULL,
+    amount                 bigint      NOT NULL,
+    currency               char(3)     NOT NULL,
+    counterparty_wallet_id integer     NOT NULL DEFAULT 0,
+    merchant_id            varchar(64) NOT NULL DEFAULT '',
+    decision               varchar(10) NOT NULL CHECK (decision IN ('review', 'deny')),
+    rules                  text        NOT NULL DEFAULT '',
+    reason                 text        NOT NULL DEFAULT '',
+    created_at             timestamptz NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX idx_risk_audits_wallet ON risk_audits (wallet_id, created_at);
+
+-- Top-ups are screened against the wallet's earlier top-ups.
+CREATE INDEX idx_transactions_wallet_top_ups
+    ON transactions (wallet_id, created_at)
+    WHERE transaction_type = 'in' AND wallet_id_source = 0 AND refund_of IS NULL;
@@ -0,0 +1,20 @@
+-- Lifecycle of a wallet: only active wallets move money. Support freezes a
+-- wallet while it is investigated and closes it for good.
+ALTER TABLE wallets
+    ADD COLUMN status        varchar(10) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'frozen', 'closed')),
+    ADD COLUMN status_reason text        NOT NULL DEFAULT '';
+
+-- Who changed a wallet's status, when and why.
+CREATE TABLE wallet_status_changes (
+    id                   bigserial   PRIMARY KEY,
+    wallet_id            integer     NOT NULL REFERENCES wallets (wallet_id),
+    from_status          varchar(10) NOT NULL,
+    to_status            varchar(10) NOT NULL,
+    reason               text        NOT NULL,
+    changed_by           integer,
+    sweep_transaction_id integer     REFERENCES transactions (transaction_id),
+    created_at           timestamptz NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX idx_wallet_status_changes_wallet ON wallet_status_changes (wallet_id, created_at);
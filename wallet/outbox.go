@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ewallet/pkg/config"
+	"ewallet/wallet/publisher"
+	"ewallet/wallet/service"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// newPublisher returns the outbox publisher selected by the configuration,
+// or nil when publishing is disabled.
+func newPublisher(cfg config.OutboxConfig) service.Publisher {
+	switch cfg.Publisher {
+	case "log":
+		return publisher.Log{}
+	case "webhook":
+		return publisher.NewWebhook(cfg.WebhookURL, cfg.WebhookTimeout)
+	default:
+		return nil
+	}
+}
+
+// runOutbox implements the "outbox" subcommand for handling dead letters:
+// "outbox dead" prints them as JSON and "outbox requeue [-id N]" makes one
+// or all of them pending again.
+func runOutbox(relay *service.OutboxRelay, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: outbox dead | requeue [-id N]")
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "dead":
+		messages, err := relay.DeadLetters(ctx)
+		if err != nil {
+			return err
+		}
+		type deadLetter struct {
+			ID        int64           `json:"id"`
+			Type      string          `json:"type"`
+			Attempts  int             `json:"attempts"`
+			LastError string          `json:"last_error"`
+			Data      json.RawMessage `json:"data"`
+		}
+		report := make([]deadLetter, 0, len(messages))
+		for _, m := range messages {
+			report = append(report, deadLetter{ID: m.ID, Type: m.EventType, Attempts: m.Attempts, LastError: m.LastError, Data: m.Payload})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+
+	case "requeue":
+		fs := flag.NewFlagSet("outbox requeue", flag.ContinueOnError)
+		id := fs.Int64("id", 0, "requeue only this message; all dead messages by default")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		n, err := relay.Requeue(ctx, *id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("requeued %d message(s)\n", n)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown outbox command %q", args[0])
+	}
+}
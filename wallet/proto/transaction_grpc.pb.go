@@ -19,15 +19,37 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	TransactionService_CreateTransaction_FullMethodName      = "/ewallet.TransactionService/CreateTransaction"
-	TransactionService_GetTransaction_FullMethodName         = "/ewallet.TransactionService/GetTransaction"
-	TransactionService_CreateWallet_FullMethodName           = "/ewallet.TransactionService/CreateWallet"
-	TransactionService_TransferWallet_FullMethodName         = "/ewallet.TransactionService/TransferWallet"
-	TransactionService_TopUp_FullMethodName                  = "/ewallet.TransactionService/TopUp"
-	TransactionService_Payment_FullMethodName                = "/ewallet.TransactionService/Payment"
-	TransactionService_GetWalletByUserID_FullMethodName      = "/ewallet.TransactionService/GetWalletByUserID"
-	TransactionService_GetTransactionByUserID_FullMethodName = "/ewallet.TransactionService/GetTransactionByUserID"
-	TransactionService_GetWalletByID_FullMethodName          = "/ewallet.TransactionService/GetWalletByID"
+	TransactionService_GetTransaction_FullMethodName                = "/ewallet.TransactionService/GetTransaction"
+	TransactionService_CreateWallet_FullMethodName                  = "/ewallet.TransactionService/CreateWallet"
+	TransactionService_TransferWallet_FullMethodName                = "/ewallet.TransactionService/TransferWallet"
+	TransactionService_TopUp_FullMethodName                         = "/ewallet.TransactionService/TopUp"
+	TransactionService_Payment_FullMethodName                       = "/ewallet.TransactionService/Payment"
+	TransactionService_AuthorizePayment_FullMethodName              = "/ewallet.TransactionService/AuthorizePayment"
+	TransactionService_CapturePayment_FullMethodName                = "/ewallet.TransactionService/CapturePayment"
+	TransactionService_VoidAuthorization_FullMethodName             = "/ewallet.TransactionService/VoidAuthorization"
+	TransactionService_GetAuthorization_FullMethodName              = "/ewallet.TransactionService/GetAuthorization"
+	TransactionService_Refund_FullMethodName                        = "/ewallet.TransactionService/Refund"
+	TransactionService_GetWalletByUserID_FullMethodName             = "/ewallet.TransactionService/GetWalletByUserID"
+	TransactionService_GetWalletsByUserID_FullMethodName            = "/ewallet.TransactionService/GetWalletsByUserID"
+	TransactionService_GetTransactionByUserID_FullMethodName        = "/ewallet.TransactionService/GetTransactionByUserID"
+	TransactionService_GetWalletByID_FullMethodName                 = "/ewallet.TransactionService/GetWalletByID"
+	TransactionService_GetWalletLimits_FullMethodName               = "/ewallet.TransactionService/GetWalletLimits"
+	TransactionService_SetUserTier_FullMethodName                   = "/ewallet.TransactionService/SetUserTier"
+	TransactionService_SetWalletLimits_FullMethodName               = "/ewallet.TransactionService/SetWalletLimits"
+	TransactionService_SetWalletStatus_FullMethodName               = "/ewallet.TransactionService/SetWalletStatus"
+	TransactionService_CloseWallet_FullMethodName                   = "/ewallet.TransactionService/CloseWallet"
+	TransactionService_GetWalletsByIDs_FullMethodName               = "/ewallet.TransactionService/GetWalletsByIDs"
+	TransactionService_SubscribeWalletEvents_FullMethodName         = "/ewallet.TransactionService/SubscribeWalletEvents"
+	TransactionService_RegisterWebhook_FullMethodName               = "/ewallet.TransactionService/RegisterWebhook"
+	TransactionService_GetWebhookDeliveries_FullMethodName          = "/ewallet.TransactionService/GetWebhookDeliveries"
+	TransactionService_ReplayWebhookDelivery_FullMethodName         = "/ewallet.TransactionService/ReplayWebhookDelivery"
+	TransactionService_CreateScheduledTransfer_FullMethodName       = "/ewallet.TransactionService/CreateScheduledTransfer"
+	TransactionService_GetScheduledTransfer_FullMethodName          = "/ewallet.TransactionService/GetScheduledTransfer"
+	TransactionService_GetScheduledTransfersByUserID_FullMethodName = "/ewallet.TransactionService/GetScheduledTransfersByUserID"
+	TransactionService_PauseScheduledTransfer_FullMethodName        = "/ewallet.TransactionService/PauseScheduledTransfer"
+	TransactionService_ResumeScheduledTransfer_FullMethodName       = "/ewallet.TransactionService/ResumeScheduledTransfer"
+	TransactionService_CancelScheduledTransfer_FullMethodName       = "/ewallet.TransactionService/CancelScheduledTransfer"
+	TransactionService_GetScheduledTransferRuns_FullMethodName      = "/ewallet.TransactionService/GetScheduledTransferRuns"
 )
 
 // TransactionServiceClient is the client API for TransactionService service.
@@ -36,15 +58,71 @@ const (
 //
 // The wallet service definition.
 type TransactionServiceClient interface {
-	CreateTransaction(ctx context.Context, in *CreateTransactionRequest, opts ...grpc.CallOption) (*CreateTransactionResponse, error)
 	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error)
 	CreateWallet(ctx context.Context, in *CreateWalletRequest, opts ...grpc.CallOption) (*CreateWalletResponse, error)
 	TransferWallet(ctx context.Context, in *TransferWalletRequest, opts ...grpc.CallOption) (*TransferWalletResponse, error)
 	TopUp(ctx context.Context, in *TopUpRequest, opts ...grpc.CallOption) (*TopUpResponse, error)
 	Payment(ctx context.Context, in *PaymentRequest, opts ...grpc.CallOption) (*PaymentResponse, error)
+	// AuthorizePayment holds an amount on a wallet for a merchant; it is paid
+	// by CapturePayment or released by VoidAuthorization or on expiry.
+	AuthorizePayment(ctx context.Context, in *AuthorizePaymentRequest, opts ...grpc.CallOption) (*AuthorizePaymentResponse, error)
+	// CapturePayment pays all or part of an authorization and releases the
+	// rest of its hold.
+	CapturePayment(ctx context.Context, in *CapturePaymentRequest, opts ...grpc.CallOption) (*CapturePaymentResponse, error)
+	VoidAuthorization(ctx context.Context, in *VoidAuthorizationRequest, opts ...grpc.CallOption) (*VoidAuthorizationResponse, error)
+	GetAuthorization(ctx context.Context, in *GetAuthorizationRequest, opts ...grpc.CallOption) (*GetAuthorizationResponse, error)
+	// Refund reverses all or part of a payment or a transfer, crediting the
+	// wallet that paid.
+	Refund(ctx context.Context, in *RefundRequest, opts ...grpc.CallOption) (*RefundResponse, error)
+	// GetWalletByUserID returns the user's default wallet.
 	GetWalletByUserID(ctx context.Context, in *GetWalletByUserIDRequest, opts ...grpc.CallOption) (*GetWalletByUserIDResponse, error)
+	// GetWalletsByUserID returns every wallet of the user, default first.
+	GetWalletsByUserID(ctx context.Context, in *GetWalletsByUserIDRequest, opts ...grpc.CallOption) (*GetWalletsByUserIDResponse, error)
 	GetTransactionByUserID(ctx context.Context, in *GetTransactionByUserIDRequest, opts ...grpc.CallOption) (*GetTransactionByUserIDResponse, error)
 	GetWalletByID(ctx context.Context, in *GetWalletByIdrequest, opts ...grpc.CallOption) (*GetWalletByIdrespon, error)
+	// GetWalletLimits returns the spending limits in force on a wallet and
+	// what it spent in the current UTC day and month.
+	GetWalletLimits(ctx context.Context, in *GetWalletLimitsRequest, opts ...grpc.CallOption) (*GetWalletLimitsResponse, error)
+	// SetUserTier assigns the limit tier of all of a user's wallets, e.g.
+	// "verified" after KYC.
+	SetUserTier(ctx context.Context, in *SetUserTierRequest, opts ...grpc.CallOption) (*SetUserTierResponse, error)
+	// SetWalletLimits replaces a wallet's overrides of its tier limits.
+	SetWalletLimits(ctx context.Context, in *SetWalletLimitsRequest, opts ...grpc.CallOption) (*SetWalletLimitsResponse, error)
+	// SetWalletStatus freezes or unfreezes a wallet. Frozen and closed
+	// wallets take part in no top-up, payment, transfer or refund.
+	SetWalletStatus(ctx context.Context, in *SetWalletStatusRequest, opts ...grpc.CallOption) (*SetWalletStatusResponse, error)
+	// CloseWallet closes a wallet for good. Its balance must be zero unless
+	// sweep_to_wallet_id names an active wallet to transfer it to.
+	CloseWallet(ctx context.Context, in *CloseWalletRequest, opts ...grpc.CallOption) (*CloseWalletResponse, error)
+	// GetWalletsByIDs returns the wallets that exist among ids, in no
+	// particular order. Unknown IDs are skipped rather than reported.
+	GetWalletsByIDs(ctx context.Context, in *GetWalletsByIDsRequest, opts ...grpc.CallOption) (*GetWalletsByIDsResponse, error)
+	// SubscribeWalletEvents streams an event for every committed top-up,
+	// payment and transfer on a wallet until the client cancels.
+	SubscribeWalletEvents(ctx context.Context, in *SubscribeWalletEventsRequest, opts ...grpc.CallOption) (TransactionService_SubscribeWalletEventsClient, error)
+	// RegisterWebhook sets the endpoint notified of a merchant's payments,
+	// replacing any previous one.
+	RegisterWebhook(ctx context.Context, in *RegisterWebhookRequest, opts ...grpc.CallOption) (*RegisterWebhookResponse, error)
+	// GetWebhookDeliveries returns a merchant's most recent deliveries, newest
+	// first.
+	GetWebhookDeliveries(ctx context.Context, in *GetWebhookDeliveriesRequest, opts ...grpc.CallOption) (*GetWebhookDeliveriesResponse, error)
+	// ReplayWebhookDelivery sends a delivery again with a fresh attempt count.
+	ReplayWebhookDelivery(ctx context.Context, in *ReplayWebhookDeliveryRequest, opts ...grpc.CallOption) (*ReplayWebhookDeliveryResponse, error)
+	// CreateScheduledTransfer stores a standing order that transfers between
+	// two wallets at every occurrence of a schedule.
+	CreateScheduledTransfer(ctx context.Context, in *CreateScheduledTransferRequest, opts ...grpc.CallOption) (*CreateScheduledTransferResponse, error)
+	GetScheduledTransfer(ctx context.Context, in *GetScheduledTransferRequest, opts ...grpc.CallOption) (*GetScheduledTransferResponse, error)
+	// GetScheduledTransfersByUserID returns every scheduled transfer of the
+	// user, newest first.
+	GetScheduledTransfersByUserID(ctx context.Context, in *GetScheduledTransfersByUserIDRequest, opts ...grpc.CallOption) (*GetScheduledTransfersByUserIDResponse, error)
+	PauseScheduledTransfer(ctx context.Context, in *PauseScheduledTransferRequest, opts ...grpc.CallOption) (*PauseScheduledTransferResponse, error)
+	// ResumeScheduledTransfer reactivates a paused schedule, skipping the
+	// occurrences missed while it was paused.
+	ResumeScheduledTransfer(ctx context.Context, in *ResumeScheduledTransferRequest, opts ...grpc.CallOption) (*ResumeScheduledTransferResponse, error)
+	CancelScheduledTransfer(ctx context.Context, in *CancelScheduledTransferRequest, opts ...grpc.CallOption) (*CancelScheduledTransferResponse, error)
+	// GetScheduledTransferRuns returns a schedule's most recent runs, newest
+	// first.
+	GetScheduledTransferRuns(ctx context.Context, in *GetScheduledTransferRunsRequest, opts ...grpc.CallOption) (*GetScheduledTransferRunsResponse, error)
 }
 
 type transactionServiceClient struct {
@@ -55,16 +133,6 @@ func NewTransactionServiceClient(cc grpc.ClientConnInterface) TransactionService
 	return &transactionServiceClient{cc}
 }
 
-func (c *transactionServiceClient) CreateTransaction(ctx context.Context, in *CreateTransactionRequest, opts ...grpc.CallOption) (*CreateTransactionResponse, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(CreateTransactionResponse)
-	err := c.cc.Invoke(ctx, TransactionService_CreateTransaction_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
 func (c *transactionServiceClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetTransactionResponse)
@@ -115,6 +183,56 @@ func (c *transactionServiceClient) Payment(ctx context.Context, in *PaymentReque
 	return out, nil
 }
 
+func (c *transactionServiceClient) AuthorizePayment(ctx context.Context, in *AuthorizePaymentRequest, opts ...grpc.CallOption) (*AuthorizePaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthorizePaymentResponse)
+	err := c.cc.Invoke(ctx, TransactionService_AuthorizePayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) CapturePayment(ctx context.Context, in *CapturePaymentRequest, opts ...grpc.CallOption) (*CapturePaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CapturePaymentResponse)
+	err := c.cc.Invoke(ctx, TransactionService_CapturePayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) VoidAuthorization(ctx context.Context, in *VoidAuthorizationRequest, opts ...grpc.CallOption) (*VoidAuthorizationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VoidAuthorizationResponse)
+	err := c.cc.Invoke(ctx, TransactionService_VoidAuthorization_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) GetAuthorization(ctx context.Context, in *GetAuthorizationRequest, opts ...grpc.CallOption) (*GetAuthorizationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAuthorizationResponse)
+	err := c.cc.Invoke(ctx, TransactionService_GetAuthorization_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) Refund(ctx context.Context, in *RefundRequest, opts ...grpc.CallOption) (*RefundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefundResponse)
+	err := c.cc.Invoke(ctx, TransactionService_Refund_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *transactionServiceClient) GetWalletByUserID(ctx context.Context, in *GetWalletByUserIDRequest, opts ...grpc.CallOption) (*GetWalletByUserIDResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetWalletByUserIDResponse)
@@ -125,6 +243,16 @@ func (c *transactionServiceClient) GetWalletByUserID(ctx context.Context, in *Ge
 	return out, nil
 }
 
+func (c *transactionServiceClient) GetWalletsByUserID(ctx context.Context, in *GetWalletsByUserIDRequest, opts ...grpc.CallOption) (*GetWalletsByUserIDResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWalletsByUserIDResponse)
+	err := c.cc.Invoke(ctx, TransactionService_GetWalletsByUserID_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *transactionServiceClient) GetTransactionByUserID(ctx context.Context, in *GetTransactionByUserIDRequest, opts ...grpc.CallOption) (*GetTransactionByUserIDResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetTransactionByUserIDResponse)
@@ -145,21 +273,270 @@ func (c *transactionServiceClient) GetWalletByID(ctx context.Context, in *GetWal
 	return out, nil
 }
 
+func (c *transactionServiceClient) GetWalletLimits(ctx context.Context, in *GetWalletLimitsRequest, opts ...grpc.CallOption) (*GetWalletLimitsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWalletLimitsResponse)
+	err := c.cc.Invoke(ctx, TransactionService_GetWalletLimits_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) SetUserTier(ctx context.Context, in *SetUserTierRequest, opts ...grpc.CallOption) (*SetUserTierResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetUserTierResponse)
+	err := c.cc.Invoke(ctx, TransactionService_SetUserTier_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) SetWalletLimits(ctx context.Context, in *SetWalletLimitsRequest, opts ...grpc.CallOption) (*SetWalletLimitsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetWalletLimitsResponse)
+	err := c.cc.Invoke(ctx, TransactionService_SetWalletLimits_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) SetWalletStatus(ctx context.Context, in *SetWalletStatusRequest, opts ...grpc.CallOption) (*SetWalletStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetWalletStatusResponse)
+	err := c.cc.Invoke(ctx, TransactionService_SetWalletStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) CloseWallet(ctx context.Context, in *CloseWalletRequest, opts ...grpc.CallOption) (*CloseWalletResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CloseWalletResponse)
+	err := c.cc.Invoke(ctx, TransactionService_CloseWallet_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) GetWalletsByIDs(ctx context.Context, in *GetWalletsByIDsRequest, opts ...grpc.CallOption) (*GetWalletsByIDsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWalletsByIDsResponse)
+	err := c.cc.Invoke(ctx, TransactionService_GetWalletsByIDs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) SubscribeWalletEvents(ctx context.Context, in *SubscribeWalletEventsRequest, opts ...grpc.CallOption) (TransactionService_SubscribeWalletEventsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TransactionService_ServiceDesc.Streams[0], TransactionService_SubscribeWalletEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &transactionServiceSubscribeWalletEventsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TransactionService_SubscribeWalletEventsClient interface {
+	Recv() (*WalletEvent, error)
+	grpc.ClientStream
+}
+
+type transactionServiceSubscribeWalletEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *transactionServiceSubscribeWalletEventsClient) Recv() (*WalletEvent, error) {
+	m := new(WalletEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *transactionServiceClient) RegisterWebhook(ctx context.Context, in *RegisterWebhookRequest, opts ...grpc.CallOption) (*RegisterWebhookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterWebhookResponse)
+	err := c.cc.Invoke(ctx, TransactionService_RegisterWebhook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) GetWebhookDeliveries(ctx context.Context, in *GetWebhookDeliveriesRequest, opts ...grpc.CallOption) (*GetWebhookDeliveriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, TransactionService_GetWebhookDeliveries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) ReplayWebhookDelivery(ctx context.Context, in *ReplayWebhookDeliveryRequest, opts ...grpc.CallOption) (*ReplayWebhookDeliveryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReplayWebhookDeliveryResponse)
+	err := c.cc.Invoke(ctx, TransactionService_ReplayWebhookDelivery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) CreateScheduledTransfer(ctx context.Context, in *CreateScheduledTransferRequest, opts ...grpc.CallOption) (*CreateScheduledTransferResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateScheduledTransferResponse)
+	err := c.cc.Invoke(ctx, TransactionService_CreateScheduledTransfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) GetScheduledTransfer(ctx context.Context, in *GetScheduledTransferRequest, opts ...grpc.CallOption) (*GetScheduledTransferResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetScheduledTransferResponse)
+	err := c.cc.Invoke(ctx, TransactionService_GetScheduledTransfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) GetScheduledTransfersByUserID(ctx context.Context, in *GetScheduledTransfersByUserIDRequest, opts ...grpc.CallOption) (*GetScheduledTransfersByUserIDResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetScheduledTransfersByUserIDResponse)
+	err := c.cc.Invoke(ctx, TransactionService_GetScheduledTransfersByUserID_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) PauseScheduledTransfer(ctx context.Context, in *PauseScheduledTransferRequest, opts ...grpc.CallOption) (*PauseScheduledTransferResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PauseScheduledTransferResponse)
+	err := c.cc.Invoke(ctx, TransactionService_PauseScheduledTransfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) ResumeScheduledTransfer(ctx context.Context, in *ResumeScheduledTransferRequest, opts ...grpc.CallOption) (*ResumeScheduledTransferResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResumeScheduledTransferResponse)
+	err := c.cc.Invoke(ctx, TransactionService_ResumeScheduledTransfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) CancelScheduledTransfer(ctx context.Context, in *CancelScheduledTransferRequest, opts ...grpc.CallOption) (*CancelScheduledTransferResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelScheduledTransferResponse)
+	err := c.cc.Invoke(ctx, TransactionService_CancelScheduledTransfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionServiceClient) GetScheduledTransferRuns(ctx context.Context, in *GetScheduledTransferRunsRequest, opts ...grpc.CallOption) (*GetScheduledTransferRunsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetScheduledTransferRunsResponse)
+	err := c.cc.Invoke(ctx, TransactionService_GetScheduledTransferRuns_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TransactionServiceServer is the server API for TransactionService service.
 // All implementations must embed UnimplementedTransactionServiceServer
 // for forward compatibility
 //
 // The wallet service definition.
 type TransactionServiceServer interface {
-	CreateTransaction(context.Context, *CreateTransactionRequest) (*CreateTransactionResponse, error)
 	GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error)
 	CreateWallet(context.Context, *CreateWalletRequest) (*CreateWalletResponse, error)
 	TransferWallet(context.Context, *TransferWalletRequest) (*TransferWalletResponse, error)
 	TopUp(context.Context, *TopUpRequest) (*TopUpResponse, error)
 	Payment(context.Context, *PaymentRequest) (*PaymentResponse, error)
+	// AuthorizePayment holds an amount on a wallet for a merchant; it is paid
+	// by CapturePayment or released by VoidAuthorization or on expiry.
+	AuthorizePayment(context.Context, *AuthorizePaymentRequest) (*AuthorizePaymentResponse, error)
+	// CapturePayment pays all or part of an authorization and releases the
+	// rest of its hold.
+	CapturePayment(context.Context, *CapturePaymentRequest) (*CapturePaymentResponse, error)
+	VoidAuthorization(context.Context, *VoidAuthorizationRequest) (*VoidAuthorizationResponse, error)
+	GetAuthorization(context.Context, *GetAuthorizationRequest) (*GetAuthorizationResponse, error)
+	// Refund reverses all or part of a payment or a transfer, crediting the
+	// wallet that paid.
+	Refund(context.Context, *RefundRequest) (*RefundResponse, error)
+	// GetWalletByUserID returns the user's default wallet.
 	GetWalletByUserID(context.Context, *GetWalletByUserIDRequest) (*GetWalletByUserIDResponse, error)
+	// GetWalletsByUserID returns every wallet of the user, default first.
+	GetWalletsByUserID(context.Context, *GetWalletsByUserIDRequest) (*GetWalletsByUserIDResponse, error)
 	GetTransactionByUserID(context.Context, *GetTransactionByUserIDRequest) (*GetTransactionByUserIDResponse, error)
 	GetWalletByID(context.Context, *GetWalletByIdrequest) (*GetWalletByIdrespon, error)
+	// GetWalletLimits returns the spending limits in force on a wallet and
+	// what it spent in the current UTC day and month.
+	GetWalletLimits(context.Context, *GetWalletLimitsRequest) (*GetWalletLimitsResponse, error)
+	// SetUserTier assigns the limit tier of all of a user's wallets, e.g.
+	// "verified" after KYC.
+	SetUserTier(context.Context, *SetUserTierRequest) (*SetUserTierResponse, error)
+	// SetWalletLimits replaces a wallet's overrides of its tier limits.
+	SetWalletLimits(context.Context, *SetWalletLimitsRequest) (*SetWalletLimitsResponse, error)
+	// SetWalletStatus freezes or unfreezes a wallet. Frozen and closed
+	// wallets take part in no top-up, payment, transfer or refund.
+	SetWalletStatus(context.Context, *SetWalletStatusRequest) (*SetWalletStatusResponse, error)
+	// CloseWallet closes a wallet for good. Its balance must be zero unless
+	// sweep_to_wallet_id names an active wallet to transfer it to.
+	CloseWallet(context.Context, *CloseWalletRequest) (*CloseWalletResponse, error)
+	// GetWalletsByIDs returns the wallets that exist among ids, in no
+	// particular order. Unknown IDs are skipped rather than reported.
+	GetWalletsByIDs(context.Context, *GetWalletsByIDsRequest) (*GetWalletsByIDsResponse, error)
+	// SubscribeWalletEvents streams an event for every committed top-up,
+	// payment and transfer on a wallet until the client cancels.
+	SubscribeWalletEvents(*SubscribeWalletEventsRequest, TransactionService_SubscribeWalletEventsServer) error
+	// RegisterWebhook sets the endpoint notified of a merchant's payments,
+	// replacing any previous one.
+	RegisterWebhook(context.Context, *RegisterWebhookRequest) (*RegisterWebhookResponse, error)
+	// GetWebhookDeliveries returns a merchant's most recent deliveries, newest
+	// first.
+	GetWebhookDeliveries(context.Context, *GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesResponse, error)
+	// ReplayWebhookDelivery sends a delivery again with a fresh attempt count.
+	ReplayWebhookDelivery(context.Context, *ReplayWebhookDeliveryRequest) (*ReplayWebhookDeliveryResponse, error)
+	// CreateScheduledTransfer stores a standing order that transfers between
+	// two wallets at every occurrence of a schedule.
+	CreateScheduledTransfer(context.Context, *CreateScheduledTransferRequest) (*CreateScheduledTransferResponse, error)
+	GetScheduledTransfer(context.Context, *GetScheduledTransferRequest) (*GetScheduledTransferResponse, error)
+	// GetScheduledTransfersByUserID returns every scheduled transfer of the
+	// user, newest first.
+	GetScheduledTransfersByUserID(context.Context, *GetScheduledTransfersByUserIDRequest) (*GetScheduledTransfersByUserIDResponse, error)
+	PauseScheduledTransfer(context.Context, *PauseScheduledTransferRequest) (*PauseScheduledTransferResponse, error)
+	// ResumeScheduledTransfer reactivates a paused schedule, skipping the
+	// occurrences missed while it was paused.
+	ResumeScheduledTransfer(context.Context, *ResumeScheduledTransferRequest) (*ResumeScheduledTransferResponse, error)
+	CancelScheduledTransfer(context.Context, *CancelScheduledTransferRequest) (*CancelScheduledTransferResponse, error)
+	// GetScheduledTransferRuns returns a schedule's most recent runs, newest
+	// first.
+	GetScheduledTransferRuns(context.Context, *GetScheduledTransferRunsRequest) (*GetScheduledTransferRunsResponse, error)
 	mustEmbedUnimplementedTransactionServiceServer()
 }
 
@@ -167,9 +544,6 @@ type TransactionServiceServer interface {
 type UnimplementedTransactionServiceServer struct {
 }
 
-func (UnimplementedTransactionServiceServer) CreateTransaction(context.Context, *CreateTransactionRequest) (*CreateTransactionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateTransaction not implemented")
-}
 func (UnimplementedTransactionServiceServer) GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
 }
@@ -185,15 +559,84 @@ func (UnimplementedTransactionServiceServer) TopUp(context.Context, *TopUpReques
 func (UnimplementedTransactionServiceServer) Payment(context.Context, *PaymentRequest) (*PaymentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Payment not implemented")
 }
+func (UnimplementedTransactionServiceServer) AuthorizePayment(context.Context, *AuthorizePaymentRequest) (*AuthorizePaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthorizePayment not implemented")
+}
+func (UnimplementedTransactionServiceServer) CapturePayment(context.Context, *CapturePaymentRequest) (*CapturePaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CapturePayment not implemented")
+}
+func (UnimplementedTransactionServiceServer) VoidAuthorization(context.Context, *VoidAuthorizationRequest) (*VoidAuthorizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VoidAuthorization not implemented")
+}
+func (UnimplementedTransactionServiceServer) GetAuthorization(context.Context, *GetAuthorizationRequest) (*GetAuthorizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAuthorization not implemented")
+}
+func (UnimplementedTransactionServiceServer) Refund(context.Context, *RefundRequest) (*RefundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refund not implemented")
+}
 func (UnimplementedTransactionServiceServer) GetWalletByUserID(context.Context, *GetWalletByUserIDRequest) (*GetWalletByUserIDResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetWalletByUserID not implemented")
 }
+func (UnimplementedTransactionServiceServer) GetWalletsByUserID(context.Context, *GetWalletsByUserIDRequest) (*GetWalletsByUserIDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWalletsByUserID not implemented")
+}
 func (UnimplementedTransactionServiceServer) GetTransactionByUserID(context.Context, *GetTransactionByUserIDRequest) (*GetTransactionByUserIDResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetTransactionByUserID not implemented")
 }
 func (UnimplementedTransactionServiceServer) GetWalletByID(context.Context, *GetWalletByIdrequest) (*GetWalletByIdrespon, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetWalletByID not implemented")
 }
+func (UnimplementedTransactionServiceServer) GetWalletLimits(context.Context, *GetWalletLimitsRequest) (*GetWalletLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWalletLimits not implemented")
+}
+func (UnimplementedTransactionServiceServer) SetUserTier(context.Context, *SetUserTierRequest) (*SetUserTierResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserTier not implemented")
+}
+func (UnimplementedTransactionServiceServer) SetWalletLimits(context.Context, *SetWalletLimitsRequest) (*SetWalletLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetWalletLimits not implemented")
+}
+func (UnimplementedTransactionServiceServer) SetWalletStatus(context.Context, *SetWalletStatusRequest) (*SetWalletStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetWalletStatus not implemented")
+}
+func (UnimplementedTransactionServiceServer) CloseWallet(context.Context, *CloseWalletRequest) (*CloseWalletResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseWallet not implemented")
+}
+func (UnimplementedTransactionServiceServer) GetWalletsByIDs(context.Context, *GetWalletsByIDsRequest) (*GetWalletsByIDsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWalletsByIDs not implemented")
+}
+func (UnimplementedTransactionServiceServer) SubscribeWalletEvents(*SubscribeWalletEventsRequest, TransactionService_SubscribeWalletEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeWalletEvents not implemented")
+}
+func (UnimplementedTransactionServiceServer) RegisterWebhook(context.Context, *RegisterWebhookRequest) (*RegisterWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterWebhook not implemented")
+}
+func (UnimplementedTransactionServiceServer) GetWebhookDeliveries(context.Context, *GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhookDeliveries not implemented")
+}
+func (UnimplementedTransactionServiceServer) ReplayWebhookDelivery(context.Context, *ReplayWebhookDeliveryRequest) (*ReplayWebhookDeliveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayWebhookDelivery not implemented")
+}
+func (UnimplementedTransactionServiceServer) CreateScheduledTransfer(context.Context, *CreateScheduledTransferRequest) (*CreateScheduledTransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateScheduledTransfer not implemented")
+}
+func (UnimplementedTransactionServiceServer) GetScheduledTransfer(context.Context, *GetScheduledTransferRequest) (*GetScheduledTransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetScheduledTransfer not implemented")
+}
+func (UnimplementedTransactionServiceServer) GetScheduledTransfersByUserID(context.Context, *GetScheduledTransfersByUserIDRequest) (*GetScheduledTransfersByUserIDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetScheduledTransfersByUserID not implemented")
+}
+func (UnimplementedTransactionServiceServer) PauseScheduledTransfer(context.Context, *PauseScheduledTransferRequest) (*PauseScheduledTransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseScheduledTransfer not implemented")
+}
+func (UnimplementedTransactionServiceServer) ResumeScheduledTransfer(context.Context, *ResumeScheduledTransferRequest) (*ResumeScheduledTransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeScheduledTransfer not implemented")
+}
+func (UnimplementedTransactionServiceServer) CancelScheduledTransfer(context.Context, *CancelScheduledTransferRequest) (*CancelScheduledTransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelScheduledTransfer not implemented")
+}
+func (UnimplementedTransactionServiceServer) GetScheduledTransferRuns(context.Context, *GetScheduledTransferRunsRequest) (*GetScheduledTransferRunsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetScheduledTransferRuns not implemented")
+}
 func (UnimplementedTransactionServiceServer) mustEmbedUnimplementedTransactionServiceServer() {}
 
 // UnsafeTransactionServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -207,24 +650,6 @@ func RegisterTransactionServiceServer(s grpc.ServiceRegistrar, srv TransactionSe
 	s.RegisterService(&TransactionService_ServiceDesc, srv)
 }
 
-func _TransactionService_CreateTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateTransactionRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(TransactionServiceServer).CreateTransaction(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: TransactionService_CreateTransaction_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TransactionServiceServer).CreateTransaction(ctx, req.(*CreateTransactionRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
 func _TransactionService_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetTransactionRequest)
 	if err := dec(in); err != nil {
@@ -315,95 +740,532 @@ func _TransactionService_Payment_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TransactionService_GetWalletByUserID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetWalletByUserIDRequest)
+func _TransactionService_AuthorizePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizePaymentRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TransactionServiceServer).GetWalletByUserID(ctx, in)
+		return srv.(TransactionServiceServer).AuthorizePayment(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: TransactionService_GetWalletByUserID_FullMethodName,
+		FullMethod: TransactionService_AuthorizePayment_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TransactionServiceServer).GetWalletByUserID(ctx, req.(*GetWalletByUserIDRequest))
+		return srv.(TransactionServiceServer).AuthorizePayment(ctx, req.(*AuthorizePaymentRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TransactionService_GetTransactionByUserID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetTransactionByUserIDRequest)
+func _TransactionService_CapturePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapturePaymentRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TransactionServiceServer).GetTransactionByUserID(ctx, in)
+		return srv.(TransactionServiceServer).CapturePayment(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: TransactionService_GetTransactionByUserID_FullMethodName,
+		FullMethod: TransactionService_CapturePayment_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TransactionServiceServer).GetTransactionByUserID(ctx, req.(*GetTransactionByUserIDRequest))
+		return srv.(TransactionServiceServer).CapturePayment(ctx, req.(*CapturePaymentRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TransactionService_GetWalletByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetWalletByIdrequest)
+func _TransactionService_VoidAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoidAuthorizationRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TransactionServiceServer).GetWalletByID(ctx, in)
+		return srv.(TransactionServiceServer).VoidAuthorization(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: TransactionService_GetWalletByID_FullMethodName,
+		FullMethod: TransactionService_VoidAuthorization_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TransactionServiceServer).GetWalletByID(ctx, req.(*GetWalletByIdrequest))
+		return srv.(TransactionServiceServer).VoidAuthorization(ctx, req.(*VoidAuthorizationRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// TransactionService_ServiceDesc is the grpc.ServiceDesc for TransactionService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var TransactionService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "ewallet.TransactionService",
-	HandlerType: (*TransactionServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "CreateTransaction",
-			Handler:    _TransactionService_CreateTransaction_Handler,
-		},
-		{
-			MethodName: "GetTransaction",
-			Handler:    _TransactionService_GetTransaction_Handler,
-		},
-		{
-			MethodName: "CreateWallet",
-			Handler:    _TransactionService_CreateWallet_Handler,
-		},
-		{
-			MethodName: "TransferWallet",
-			Handler:    _TransactionService_TransferWallet_Handler,
-		},
-		{
-			MethodName: "TopUp",
-			Handler:    _TransactionService_TopUp_Handler,
-		},
+func _TransactionService_GetAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetAuthorization_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetAuthorization(ctx, req.(*GetAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_Refund_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).Refund(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_Refund_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).Refund(ctx, req.(*RefundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetWalletByUserID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWalletByUserIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetWalletByUserID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetWalletByUserID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetWalletByUserID(ctx, req.(*GetWalletByUserIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetWalletsByUserID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWalletsByUserIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetWalletsByUserID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetWalletsByUserID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetWalletsByUserID(ctx, req.(*GetWalletsByUserIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetTransactionByUserID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionByUserIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetTransactionByUserID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetTransactionByUserID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetTransactionByUserID(ctx, req.(*GetTransactionByUserIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetWalletByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWalletByIdrequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetWalletByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetWalletByID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetWalletByID(ctx, req.(*GetWalletByIdrequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetWalletLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWalletLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetWalletLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetWalletLimits_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetWalletLimits(ctx, req.(*GetWalletLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_SetUserTier_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserTierRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).SetUserTier(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_SetUserTier_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).SetUserTier(ctx, req.(*SetUserTierRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_SetWalletLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetWalletLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).SetWalletLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_SetWalletLimits_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).SetWalletLimits(ctx, req.(*SetWalletLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_SetWalletStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetWalletStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).SetWalletStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_SetWalletStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).SetWalletStatus(ctx, req.(*SetWalletStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_CloseWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).CloseWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_CloseWallet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).CloseWallet(ctx, req.(*CloseWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetWalletsByIDs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWalletsByIDsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetWalletsByIDs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetWalletsByIDs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetWalletsByIDs(ctx, req.(*GetWalletsByIDsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_SubscribeWalletEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeWalletEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TransactionServiceServer).SubscribeWalletEvents(m, &transactionServiceSubscribeWalletEventsServer{ServerStream: stream})
+}
+
+type TransactionService_SubscribeWalletEventsServer interface {
+	Send(*WalletEvent) error
+	grpc.ServerStream
+}
+
+type transactionServiceSubscribeWalletEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *transactionServiceSubscribeWalletEventsServer) Send(m *WalletEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TransactionService_RegisterWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).RegisterWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_RegisterWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).RegisterWebhook(ctx, req.(*RegisterWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetWebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetWebhookDeliveries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetWebhookDeliveries(ctx, req.(*GetWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_ReplayWebhookDelivery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplayWebhookDeliveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).ReplayWebhookDelivery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_ReplayWebhookDelivery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).ReplayWebhookDelivery(ctx, req.(*ReplayWebhookDeliveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_CreateScheduledTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateScheduledTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).CreateScheduledTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_CreateScheduledTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).CreateScheduledTransfer(ctx, req.(*CreateScheduledTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetScheduledTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScheduledTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetScheduledTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetScheduledTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetScheduledTransfer(ctx, req.(*GetScheduledTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetScheduledTransfersByUserID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScheduledTransfersByUserIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetScheduledTransfersByUserID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetScheduledTransfersByUserID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetScheduledTransfersByUserID(ctx, req.(*GetScheduledTransfersByUserIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_PauseScheduledTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseScheduledTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).PauseScheduledTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_PauseScheduledTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).PauseScheduledTransfer(ctx, req.(*PauseScheduledTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_ResumeScheduledTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeScheduledTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).ResumeScheduledTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_ResumeScheduledTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).ResumeScheduledTransfer(ctx, req.(*ResumeScheduledTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_CancelScheduledTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelScheduledTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).CancelScheduledTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_CancelScheduledTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).CancelScheduledTransfer(ctx, req.(*CancelScheduledTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransactionService_GetScheduledTransferRuns_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScheduledTransferRunsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionServiceServer).GetScheduledTransferRuns(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransactionService_GetScheduledTransferRuns_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionServiceServer).GetScheduledTransferRuns(ctx, req.(*GetScheduledTransferRunsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TransactionService_ServiceDesc is the grpc.ServiceDesc for TransactionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TransactionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ewallet.TransactionService",
+	HandlerType: (*TransactionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTransaction",
+			Handler:    _TransactionService_GetTransaction_Handler,
+		},
+		{
+			MethodName: "CreateWallet",
+			Handler:    _TransactionService_CreateWallet_Handler,
+		},
+		{
+			MethodName: "TransferWallet",
+			Handler:    _TransactionService_TransferWallet_Handler,
+		},
+		{
+			MethodName: "TopUp",
+			Handler:    _TransactionService_TopUp_Handler,
+		},
 		{
 			MethodName: "Payment",
 			Handler:    _TransactionService_Payment_Handler,
 		},
+		{
+			MethodName: "AuthorizePayment",
+			Handler:    _TransactionService_AuthorizePayment_Handler,
+		},
+		{
+			MethodName: "CapturePayment",
+			Handler:    _TransactionService_CapturePayment_Handler,
+		},
+		{
+			MethodName: "VoidAuthorization",
+			Handler:    _TransactionService_VoidAuthorization_Handler,
+		},
+		{
+			MethodName: "GetAuthorization",
+			Handler:    _TransactionService_GetAuthorization_Handler,
+		},
+		{
+			MethodName: "Refund",
+			Handler:    _TransactionService_Refund_Handler,
+		},
 		{
 			MethodName: "GetWalletByUserID",
 			Handler:    _TransactionService_GetWalletByUserID_Handler,
 		},
+		{
+			MethodName: "GetWalletsByUserID",
+			Handler:    _TransactionService_GetWalletsByUserID_Handler,
+		},
 		{
 			MethodName: "GetTransactionByUserID",
 			Handler:    _TransactionService_GetTransactionByUserID_Handler,
@@ -412,7 +1274,77 @@ var TransactionService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetWalletByID",
 			Handler:    _TransactionService_GetWalletByID_Handler,
 		},
+		{
+			MethodName: "GetWalletLimits",
+			Handler:    _TransactionService_GetWalletLimits_Handler,
+		},
+		{
+			MethodName: "SetUserTier",
+			Handler:    _TransactionService_SetUserTier_Handler,
+		},
+		{
+			MethodName: "SetWalletLimits",
+			Handler:    _TransactionService_SetWalletLimits_Handler,
+		},
+		{
+			MethodName: "SetWalletStatus",
+			Handler:    _TransactionService_SetWalletStatus_Handler,
+		},
+		{
+			MethodName: "CloseWallet",
+			Handler:    _TransactionService_CloseWallet_Handler,
+		},
+		{
+			MethodName: "GetWalletsByIDs",
+			Handler:    _TransactionService_GetWalletsByIDs_Handler,
+		},
+		{
+			MethodName: "RegisterWebhook",
+			Handler:    _TransactionService_RegisterWebhook_Handler,
+		},
+		{
+			MethodName: "GetWebhookDeliveries",
+			Handler:    _TransactionService_GetWebhookDeliveries_Handler,
+		},
+		{
+			MethodName: "ReplayWebhookDelivery",
+			Handler:    _TransactionService_ReplayWebhookDelivery_Handler,
+		},
+		{
+			MethodName: "CreateScheduledTransfer",
+			Handler:    _TransactionService_CreateScheduledTransfer_Handler,
+		},
+		{
+			MethodName: "GetScheduledTransfer",
+			Handler:    _TransactionService_GetScheduledTransfer_Handler,
+		},
+		{
+			MethodName: "GetScheduledTransfersByUserID",
+			Handler:    _TransactionService_GetScheduledTransfersByUserID_Handler,
+		},
+		{
+			MethodName: "PauseScheduledTransfer",
+			Handler:    _TransactionService_PauseScheduledTransfer_Handler,
+		},
+		{
+			MethodName: "ResumeScheduledTransfer",
+			Handler:    _TransactionService_ResumeScheduledTransfer_Handler,
+		},
+		{
+			MethodName: "CancelScheduledTransfer",
+			Handler:    _TransactionService_CancelScheduledTransfer_Handler,
+		},
+		{
+			MethodName: "GetScheduledTransferRuns",
+			Handler:    _TransactionService_GetScheduledTransferRuns_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeWalletEvents",
+			Handler:       _TransactionService_SubscribeWalletEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/transaction.proto",
 }
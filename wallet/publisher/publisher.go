@@ -0,0 +1,111 @@
+// Package publisher delivers outbox messages from the wallet service to
+// downstream consumers.
+//
+// Every publisher sends the same envelope:
+//
+//	{"id": 42, "type": "WalletCredited", "aggregate_id": "7",
+//	 "data": {...}, "occurred_at": "2024-07-01T10:00:00Z"}
+//
+// Delivery is at least once, so consumers should deduplicate by id.
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"ewallet/wallet/entity"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Memory keeps published messages in memory. It is meant for tests and
+// local development. It is safe for concurrent use.
+type Memory struct {
+	mu       sync.Mutex
+	messages []entity.OutboxMessage
+	// Fail, when set, is returned by Publish instead of storing the message.
+	Fail error
+}
+
+// NewMemory returns an empty in-memory publisher.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Publish implements service.Publisher.
+func (m *Memory) Publish(ctx context.Context, message entity.OutboxMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Fail != nil {
+		return m.Fail
+	}
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Messages returns a copy of the messages published so far.
+func (m *Memory) Messages() []entity.OutboxMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]entity.OutboxMessage(nil), m.messages...)
+}
+
+// Log writes each message to the standard logger. It lets a development
+// setup see the events without running a consumer.
+type Log struct{}
+
+// Publish implements service.Publisher.
+func (Log) Publish(ctx context.Context, message entity.OutboxMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	log.Printf("outbox event: %s", body)
+	return nil
+}
+
+// Webhook POSTs each message as JSON to a URL. Any 2xx response counts as
+// delivered; anything else, including a timeout, is retried by the relay.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a publisher posting to url, giving up on a single
+// delivery after timeout.
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Publish implements service.Publisher.
+func (w *Webhook) Publish(ctx context.Context, message entity.OutboxMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(message.ID, 10))
+	req.Header.Set("X-Event-Type", message.EventType)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s responded %s", w.url, resp.Status)
+	}
+	return nil
+}
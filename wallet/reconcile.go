@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"ewallet/wallet/service"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// runReconcile implements the "reconcile" subcommand: it recomputes every
+// wallet's balance from its transactions rows, prints a drift report and,
+// with -repair, fixes the stored balance of mismatched wallets.
+func runReconcile(svc *service.ReconciliationService, args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	format := fs.String("format", "json", "report format: json or csv")
+	repair := fs.Bool("repair", false, "set mismatched stored balances to the computed balance")
+	all := fs.Bool("all", false, "report every wallet, not only mismatched ones")
+	output := fs.String("o", "", "write the report to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown report format %q", *format)
+	}
+
+	checks, err := svc.Reconcile(context.Background(), *repair)
+	if err != nil {
+		return err
+	}
+
+	report := checks
+	if !*all {
+		report = nil
+		for _, check := range checks {
+			if check.Mismatch() || check.Repaired {
+				report = append(report, check)
+			}
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "csv" {
+		return writeReconcileCSV(w, report)
+	}
+	return writeReconcileJSON(w, report)
+}
+
+func writeReconcileJSON(w io.Writer, checks []service.WalletBalanceCheck) error {
+	if checks == nil {
+		checks = []service.WalletBalanceCheck{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(checks)
+}
+
+func writeReconcileCSV(w io.Writer, checks []service.WalletBalanceCheck) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"wallet_id", "stored_balance", "computed_balance", "ledger_balance", "delta", "repaired"}); err != nil {
+		return err
+	}
+	for _, check := range checks {
+		if err := cw.Write([]string{
+			strconv.Itoa(check.WalletID),
+			check.StoredBalance.String(),
+			check.ComputedBalance.String(),
+			check.LedgerBalance.String(),
+			check.Delta.String(),
+			strconv.FormatBool(check.Repaired),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
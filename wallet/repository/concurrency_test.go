@@ -0,0 +1,120 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/migrate"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/migrations"
+	"ewallet/wallet/repository"
+	"ewallet/wallet/service"
+	"os"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testDSNEnv names the database the tests below run against. They migrate
+// it to the latest version and add wallets, so point it at a scratch
+// database, never a real one.
+const testDSNEnv = "EWALLET_TEST_WALLET_DSN"
+
+// openTestDB connects to the database named by testDSNEnv and migrates it,
+// skipping the test when the variable is not set.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{SkipDefaultTransaction: true, TranslateError: true})
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrate.New(sqlDB, migrations.FS)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background(), 0); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// TestConcurrentPaymentsNeverOverdraw races more payments against one wallet
+// than its balance covers. Without the row lock two payments could both see
+// the same balance and both succeed; with it exactly as many succeed as the
+// balance allows and the rest are refused.
+func TestConcurrentPaymentsNeverOverdraw(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repository.NewTransactionRepository(db)
+	svc := service.NewTransactionService(repo, nil, nil, nil)
+
+	const (
+		balance  = money.Amount(100_00)
+		amount   = money.Amount(3_00)
+		payments = 50
+	)
+	wallet, err := svc.CreateWallet(ctx, &entity.Wallet{UserID: 1})
+	if err != nil {
+		t.Fatalf("CreateWallet() error = %v", err)
+	}
+	walletID := int(wallet.Walletid)
+	if _, err := svc.TopUp(ctx, walletID, balance, ""); err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, payments)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = svc.Payment(ctx, walletID, amount, "", "")
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, service.ErrInsufficientFunds):
+			t.Errorf("Payment() error = %v, want success or %v", err, service.ErrInsufficientFunds)
+		}
+	}
+	if want := int(balance / amount); succeeded != want {
+		t.Errorf("%d payments succeeded, want %d", succeeded, want)
+	}
+
+	got, err := svc.GetWalletByID(ctx, walletID)
+	if err != nil {
+		t.Fatalf("GetWalletByID() error = %v", err)
+	}
+	if want := balance - money.Amount(succeeded)*amount; got.Balance != want || got.Balance < 0 {
+		t.Errorf("wallet holds %s, want %s", got.Balance, want)
+	}
+
+	var posted money.Amount
+	err = db.Model(&entity.Posting{}).Where("account = ?", entity.WalletAccount(walletID)).
+		Select("coalesce(sum(case when direction = ? then amount else -amount end), 0)", entity.Credit).
+		Scan(&posted).Error
+	if err != nil {
+		t.Fatalf("failed to sum postings: %v", err)
+	}
+	if posted != got.Balance {
+		t.Errorf("ledger postings sum to %s, wallet holds %s", posted, got.Balance)
+	}
+}
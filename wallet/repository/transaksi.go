@@ -3,10 +3,14 @@ package repository
 import (
 	"context"
 	"errors"
+	"ewallet/pkg/money"
 	"ewallet/wallet/entity"
 	"ewallet/wallet/service"
+	"sort"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GormDBIface interface {
@@ -26,6 +30,15 @@ func NewTransactionRepository(db GormDBIface) service.ITransactionRepository {
 	return &transactionRepository{db: db}
 }
 
+// WithinTx runs fn inside a database transaction. The repository passed to fn
+// is bound to that transaction; it is committed when fn returns nil and rolled
+// back otherwise.
+func (r *transactionRepository) WithinTx(ctx context.Context, fn func(repo service.ITransactionRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&transactionRepository{db: tx})
+	})
+}
+
 func (r *transactionRepository) CreateWallet(ctx context.Context, wallet *entity.Wallet) (entity.Wallet, error) {
 	if err := r.db.WithContext(ctx).Create(wallet).Error; err != nil {
 		return entity.Wallet{}, err
@@ -44,54 +57,824 @@ func (r *transactionRepository) CreateTransaction(ctx context.Context, transacti
 func (r *transactionRepository) GetWalletByID(ctx context.Context, walletID int) (entity.Wallet, error) {
 	var wallet entity.Wallet
 
-	if err := r.db.WithContext(ctx).First(&wallet, "Wallet_id = ?", walletID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	if err := r.db.WithContext(ctx).First(&wallet, "Wallet_id = ?", walletID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Wallet{}, service.ErrWalletNotFound
+		}
+		return entity.Wallet{}, err
+	}
+	return wallet, nil
+}
+
+// GetWalletsByIDs retrieves the wallets among walletIDs that exist
+func (r *transactionRepository) GetWalletsByIDs(ctx context.Context, walletIDs []int) ([]entity.Wallet, error) {
+	var wallets []entity.Wallet
+
+	if err := r.db.WithContext(ctx).Find(&wallets, "wallet_id IN ?", walletIDs).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// GetWalletByIDForUpdate retrieves a wallet by its ID and holds a row lock on
+// it (SELECT ... FOR UPDATE) until the surrounding transaction ends. It must
+// be called from within WithinTx.
+func (r *transactionRepository) GetWalletByIDForUpdate(ctx context.Context, walletID int) (entity.Wallet, error) {
+	var wallet entity.Wallet
+
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "Wallet_id = ?", walletID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Wallet{}, service.ErrWalletNotFound
+		}
 		return entity.Wallet{}, err
 	}
 	return wallet, nil
 }
 
-// GetWalletByUserID retrieves wallets by user ID from the repository
+// GetWalletByUserID retrieves the default wallet of a user. Should a user
+// have no default, the oldest wallet is returned.
 func (r *transactionRepository) GetWalletByUserID(ctx context.Context, userID int) (entity.Wallet, error) {
 	var wallets entity.Wallet
 
-	if err := r.db.WithContext(ctx).Find(&wallets, "user_id = ?", userID).Error; err != nil {
+	if err := r.db.WithContext(ctx).Order("is_default DESC, wallet_id").First(&wallets, "user_id = ?", userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return entity.Wallet{}, errors.New("wallets not found")
+			return entity.Wallet{}, service.ErrWalletNotFound
 		}
 		return entity.Wallet{}, err
 	}
 	return wallets, nil
 }
 
+// GetWalletsByUserID retrieves every wallet of a user, default first
+func (r *transactionRepository) GetWalletsByUserID(ctx context.Context, userID int) ([]entity.Wallet, error) {
+	var wallets []entity.Wallet
+
+	if err := r.db.WithContext(ctx).Order("is_default DESC, wallet_id").Find(&wallets, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// ClearDefaultWallet unsets the default flag on every wallet of a user
+func (r *transactionRepository) ClearDefaultWallet(ctx context.Context, userID int) error {
+	if err := r.db.WithContext(ctx).Model(&entity.Wallet{}).Where("user_id = ? AND is_default", userID).Update("is_default", false).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetDefaultWallet sets the default flag on a wallet
+func (r *transactionRepository) SetDefaultWallet(ctx context.Context, walletID int) error {
+	if err := r.db.WithContext(ctx).Model(&entity.Wallet{}).Where("wallet_id = ?", walletID).Update("is_default", true).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 // GetTransaction retrieves a transaction by its ID from the repository
 func (r *transactionRepository) GetTransaction(ctx context.Context, id int32) (entity.Transaction, error) {
 	var transaction entity.Transaction
 
 	if err := r.db.WithContext(ctx).First(&transaction, "transaction_id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return entity.Transaction{}, errors.New("transaction not found")
+			return entity.Transaction{}, service.ErrTransactionNotFound
+		}
+		return entity.Transaction{}, err
+	}
+	return transaction, nil
+}
+
+// GetTransactionForUpdate retrieves a transaction and locks its row
+func (r *transactionRepository) GetTransactionForUpdate(ctx context.Context, id uint) (entity.Transaction, error) {
+	var transaction entity.Transaction
+
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&transaction, "transaction_id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Transaction{}, service.ErrTransactionNotFound
+		}
+		return entity.Transaction{}, err
+	}
+	return transaction, nil
+}
+
+// GetTransferCredit retrieves the credit leg sharing the debit's journal
+// entry, locking it so refunds of the transfer are serialized
+func (r *transactionRepository) GetTransferCredit(ctx context.Context, debit entity.Transaction) (entity.Transaction, error) {
+	var transaction entity.Transaction
+
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("journal_entry_id = ? AND wallet_id = ? AND transaction_type = ?", debit.JournalEntryID, debit.WalletIDSource, "in").
+		First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Transaction{}, service.ErrTransactionNotFound
 		}
 		return entity.Transaction{}, err
 	}
 	return transaction, nil
 }
 
-// UpdateWallet updates a wallet in the repository
+func (r *transactionRepository) AddRefundedAmount(ctx context.Context, id uint, amount money.Amount) error {
+	if err := r.db.WithContext(ctx).Model(&entity.Transaction{}).Where("transaction_id = ?", id).
+		Update("refunded_amount", gorm.Expr("refunded_amount + ?", amount)).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateWallet updates a wallet in the repository. Balance is always written,
+// even when it drops to zero.
 func (r *transactionRepository) UpdateWallet(ctx context.Context, wallet *entity.Wallet) error {
-	if err := r.db.WithContext(ctx).Model(&entity.Wallet{}).Where("Wallet_id = ?", wallet.Walletid).Updates(wallet).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&entity.Wallet{}).Where("Wallet_id = ?", wallet.Walletid).Select("Balance", "HeldBalance", "UpdatedAt").Updates(wallet).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
-// GetTransactionByUserID retrieves transactions by user ID
-func (r *transactionRepository) GetTransactionByUserID(ctx context.Context, userID int) ([]entity.Transaction, error) {
-	var transactions []entity.Transaction
+// UpdateWalletStatus updates the status and status reason of a wallet
+func (r *transactionRepository) UpdateWalletStatus(ctx context.Context, wallet *entity.Wallet) error {
+	if err := r.db.WithContext(ctx).Model(&entity.Wallet{}).Where("wallet_id = ?", wallet.Walletid).Updates(map[string]interface{}{
+		"status":        wallet.Status,
+		"status_reason": wallet.StatusReason,
+		"updated_at":    gorm.Expr("current_timestamp"),
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateWalletStatusChange inserts a wallet status change
+func (r *transactionRepository) CreateWalletStatusChange(ctx context.Context, change *entity.WalletStatusChange) error {
+	if err := r.db.WithContext(ctx).Create(change).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateJournalEntry stores a journal entry and its postings
+func (r *transactionRepository) CreateJournalEntry(ctx context.Context, entry *entity.JournalEntry) (entity.JournalEntry, error) {
+	if err := r.db.WithContext(ctx).Create(entry).Error; err != nil {
+		return entity.JournalEntry{}, err
+	}
+	return *entry, nil
+}
+
+// GetLedgerBalance sums the credits minus the debits posted to a wallet
+func (r *transactionRepository) GetLedgerBalance(ctx context.Context, walletID int) (money.Amount, error) {
+	var balance int64
+
+	if err := r.db.WithContext(ctx).
+		Model(&entity.Posting{}).
+		Select("COALESCE(SUM(CASE WHEN direction = ? THEN amount ELSE -amount END), 0)", entity.Credit).
+		Where("wallet_id = ?", walletID).
+		Scan(&balance).Error; err != nil {
+		return 0, err
+	}
+	return money.Amount(balance), nil
+}
+
+// CheckWalletBalances recomputes wallet balances from transactions rows and
+// postings and returns them next to the stored balance
+func (r *transactionRepository) CheckWalletBalances(ctx context.Context, walletID int) ([]service.WalletBalanceCheck, error) {
+	var checks []service.WalletBalanceCheck
+
+	query := r.db.WithContext(ctx).
+		Table("wallets AS w").
+		Select(`w.wallet_id,
+			w.balance AS stored_balance,
+			COALESCE((SELECT SUM(CASE WHEN t.transaction_type = 'in' THEN t.amount ELSE -t.amount END)
+				FROM transactions t WHERE t.wallet_id = w.wallet_id), 0) AS computed_balance,
+			COALESCE((SELECT SUM(CASE WHEN p.direction = ? THEN p.amount ELSE -p.amount END)
+				FROM postings p WHERE p.wallet_id = w.wallet_id), 0) AS ledger_balance`, entity.Credit).
+		Order("w.wallet_id")
+	if walletID != 0 {
+		query = query.Where("w.wallet_id = ?", walletID)
+	}
+	if err := query.Scan(&checks).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range checks {
+		checks[i].Delta = checks[i].StoredBalance - checks[i].ComputedBalance
+	}
+	return checks, nil
+}
+
+// CreateWalletEvent stores a wallet event
+func (r *transactionRepository) CreateWalletEvent(ctx context.Context, event *entity.WalletEvent) error {
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetWalletEvents retrieves the events of a wallet after afterEventID, oldest
+// first
+func (r *transactionRepository) GetWalletEvents(ctx context.Context, walletID int, afterEventID int64, limit int) ([]entity.WalletEvent, error) {
+	var events []entity.WalletEvent
 
 	if err := r.db.WithContext(ctx).
-		// Debug().
+		Where("wallet_id = ? AND event_id > ?", walletID, afterEventID).
+		Order("event_id").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetLatestWalletEventID returns the newest event ID of a wallet, or 0
+func (r *transactionRepository) GetLatestWalletEventID(ctx context.Context, walletID int) (int64, error) {
+	var eventID int64
+
+	if err := r.db.WithContext(ctx).
+		Model(&entity.WalletEvent{}).
+		Select("COALESCE(MAX(event_id), 0)").
+		Where("wallet_id = ?", walletID).
+		Scan(&eventID).Error; err != nil {
+		return 0, err
+	}
+	return eventID, nil
+}
+
+// CreateOutboxMessages stores outbox messages
+func (r *transactionRepository) CreateOutboxMessages(ctx context.Context, messages []entity.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(&messages).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClaimOutboxMessages leases up to limit due pending messages. Rows locked by
+// a concurrent claim are skipped rather than waited for.
+func (r *transactionRepository) ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxMessage, error) {
+	var messages []entity.OutboxMessage
+
+	if err := r.db.WithContext(ctx).Raw(`UPDATE outbox_messages
+		SET next_attempt_at = current_timestamp + make_interval(secs => ?)
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE status = ? AND next_attempt_at <= current_timestamp
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, lease.Seconds(), entity.OutboxPending, limit).
+		Scan(&messages).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+// MarkOutboxPublished records that a message was delivered
+func (r *transactionRepository) MarkOutboxPublished(ctx context.Context, id int64) error {
+	if err := r.db.WithContext(ctx).Model(&entity.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       entity.OutboxPublished,
+		"attempts":     gorm.Expr("attempts + 1"),
+		"published_at": gorm.Expr("current_timestamp"),
+		"last_error":   "",
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarkOutboxFailed records a failed delivery attempt
+func (r *transactionRepository) MarkOutboxFailed(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+	status := entity.OutboxPending
+	if dead {
+		status = entity.OutboxDead
+	}
+	if err := r.db.WithContext(ctx).Model(&entity.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          status,
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetDeadOutboxMessages retrieves the dead letters, oldest first
+func (r *transactionRepository) GetDeadOutboxMessages(ctx context.Context) ([]entity.OutboxMessage, error) {
+	var messages []entity.OutboxMessage
+
+	if err := r.db.WithContext(ctx).Where("status = ?", entity.OutboxDead).Order("id").Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// RequeueOutboxMessages makes one or every dead message pending again
+func (r *transactionRepository) RequeueOutboxMessages(ctx context.Context, id int64) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&entity.OutboxMessage{}).Where("status = ?", entity.OutboxDead)
+	if id != 0 {
+		query = query.Where("id = ?", id)
+	}
+	result := query.Updates(map[string]interface{}{
+		"status":          entity.OutboxPending,
+		"attempts":        0,
+		"next_attempt_at": gorm.Expr("current_timestamp"),
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *transactionRepository) CreateAuthorization(ctx context.Context, authorization *entity.Authorization) error {
+	if err := r.db.WithContext(ctx).Create(authorization).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return service.ErrIdempotencyKeyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *transactionRepository) UpdateAuthorization(ctx context.Context, authorization *entity.Authorization) error {
+	if err := r.db.WithContext(ctx).Model(&entity.Authorization{}).Where("authorization_id = ?", authorization.AuthorizationID).Updates(map[string]interface{}{
+		"status":          authorization.Status,
+		"captured_amount": authorization.CapturedAmount,
+		"transaction_id":  authorization.TransactionID,
+		"updated_at":      gorm.Expr("current_timestamp"),
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAuthorization retrieves an authorization by its ID
+func (r *transactionRepository) GetAuthorization(ctx context.Context, id uint) (entity.Authorization, error) {
+	var authorization entity.Authorization
+
+	if err := r.db.WithContext(ctx).First(&authorization, "authorization_id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Authorization{}, service.ErrAuthorizationNotFound
+		}
+		return entity.Authorization{}, err
+	}
+	return authorization, nil
+}
+
+// GetAuthorizationForUpdate retrieves an authorization and locks its row
+func (r *transactionRepository) GetAuthorizationForUpdate(ctx context.Context, id uint) (entity.Authorization, error) {
+	var authorization entity.Authorization
+
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&authorization, "authorization_id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Authorization{}, service.ErrAuthorizationNotFound
+		}
+		return entity.Authorization{}, err
+	}
+	return authorization, nil
+}
+
+func (r *transactionRepository) GetAuthorizationByIdempotencyKey(ctx context.Context, key string) (entity.Authorization, bool, error) {
+	var authorization entity.Authorization
+
+	if err := r.db.WithContext(ctx).First(&authorization, "idempotency_key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Authorization{}, false, nil
+		}
+		return entity.Authorization{}, false, err
+	}
+	return authorization, true, nil
+}
+
+// ClaimExpiredAuthorizations orders the claimed rows by wallet so that
+// concurrent sweeps lock wallets in the same order
+func (r *transactionRepository) ClaimExpiredAuthorizations(ctx context.Context, limit int) ([]entity.Authorization, error) {
+	var authorizations []entity.Authorization
+
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND expires_at <= current_timestamp", entity.AuthorizationAuthorized).
+		Order("wallet_id, authorization_id").Limit(limit).Find(&authorizations).Error; err != nil {
+		return nil, err
+	}
+	return authorizations, nil
+}
+
+func (r *transactionRepository) CreateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error {
+	if err := r.db.WithContext(ctx).Create(transfer).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *transactionRepository) UpdateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error {
+	if err := r.db.WithContext(ctx).Model(&entity.ScheduledTransfer{}).Where("id = ?", transfer.ID).Updates(map[string]interface{}{
+		"status":      transfer.Status,
+		"next_run_at": transfer.NextRunAt,
+		"last_run_at": transfer.LastRunAt,
+		"lease_until": transfer.LeaseUntil,
+		"run_count":   transfer.RunCount,
+		"updated_at":  gorm.Expr("current_timestamp"),
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetScheduledTransfer retrieves a scheduled transfer by its ID
+func (r *transactionRepository) GetScheduledTransfer(ctx context.Context, id int64) (entity.ScheduledTransfer, error) {
+	var transfer entity.ScheduledTransfer
+
+	if err := r.db.WithContext(ctx).First(&transfer, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ScheduledTransfer{}, service.ErrScheduleNotFound
+		}
+		return entity.ScheduledTransfer{}, err
+	}
+	return transfer, nil
+}
+
+// GetScheduledTransferForUpdate retrieves a scheduled transfer and locks its
+// row
+func (r *transactionRepository) GetScheduledTransferForUpdate(ctx context.Context, id int64) (entity.ScheduledTransfer, error) {
+	var transfer entity.ScheduledTransfer
+
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ScheduledTransfer{}, service.ErrScheduleNotFound
+		}
+		return entity.ScheduledTransfer{}, err
+	}
+	return transfer, nil
+}
+
+func (r *transactionRepository) GetScheduledTransfersByUserID(ctx context.Context, userID int) ([]entity.ScheduledTransfer, error) {
+	var transfers []entity.ScheduledTransfer
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// ClaimDueScheduledTransfers leases due schedules. SKIP LOCKED lets several
+// schedulers claim disjoint batches concurrently, and a schedule whose lease
+// ran out is claimed again.
+func (r *transactionRepository) ClaimDueScheduledTransfers(ctx context.Context, limit int, lease time.Duration) ([]entity.ScheduledTransfer, error) {
+	var transfers []entity.ScheduledTransfer
+
+	if err := r.db.WithContext(ctx).Raw(`UPDATE scheduled_transfers
+		SET lease_until = current_timestamp + make_interval(secs => ?)
+		WHERE id IN (
+			SELECT id FROM scheduled_transfers
+			WHERE status = ? AND next_run_at <= current_timestamp
+			AND (lease_until IS NULL OR lease_until <= current_timestamp)
+			ORDER BY next_run_at, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, lease.Seconds(), entity.ScheduleActive, limit).
+		Scan(&transfers).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if !transfers[i].NextRunAt.Equal(transfers[j].NextRunAt) {
+			return transfers[i].NextRunAt.Before(transfers[j].NextRunAt)
+		}
+		return transfers[i].ID < transfers[j].ID
+	})
+	return transfers, nil
+}
+
+// CancelScheduledTransfersByWallet cancels the schedules from or to a
+// wallet that are still active or paused
+func (r *transactionRepository) CancelScheduledTransfersByWallet(ctx context.Context, walletID int) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&entity.ScheduledTransfer{}).
+		Where("(from_wallet_id = ? OR to_wallet_id = ?) AND status IN ?", walletID, walletID, []string{entity.ScheduleActive, entity.SchedulePaused}).
+		Updates(map[string]interface{}{
+			"status":      entity.ScheduleCancelled,
+			"lease_until": nil,
+			"updated_at":  gorm.Expr("current_timestamp"),
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *transactionRepository) CreateScheduledTransferRun(ctx context.Context, run *entity.ScheduledTransferRun) error {
+	if err := r.db.WithContext(ctx).Create(run).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *transactionRepository) GetScheduledTransferRuns(ctx context.Context, scheduleID int64, limit int) ([]entity.ScheduledTransferRun, error) {
+	var runs []entity.ScheduledTransferRun
+
+	if err := r.db.WithContext(ctx).Where("schedule_id = ?", scheduleID).Order("id DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *transactionRepository) GetUserTier(ctx context.Context, userID uint) (string, error) {
+	var tier entity.UserTier
+
+	if err := r.db.WithContext(ctx).First(&tier, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.TierUnverified, nil
+		}
+		return "", err
+	}
+	return tier.Tier, nil
+}
+
+func (r *transactionRepository) SaveUserTier(ctx context.Context, tier *entity.UserTier) error {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"tier":       tier.Tier,
+			"updated_at": gorm.Expr("current_timestamp"),
+		}),
+	}).Create(tier).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *transactionRepository) GetWalletLimit(ctx context.Context, walletID int) (entity.WalletLimit, bool, error) {
+	var limit entity.WalletLimit
+
+	if err := r.db.WithContext(ctx).First(&limit, "wallet_id = ?", walletID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.WalletLimit{}, false, nil
+		}
+		return entity.WalletLimit{}, false, err
+	}
+	return limit, true, nil
+}
+
+// SaveWalletLimit replaces every override, so a nil field clears it
+func (r *transactionRepository) SaveWalletLimit(ctx context.Context, limit *entity.WalletLimit) error {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "wallet_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"daily_amount":   limit.DailyAmount,
+			"monthly_amount": limit.MonthlyAmount,
+			"daily_count":    limit.DailyCount,
+			"monthly_count":  limit.MonthlyCount,
+			"updated_at":     gorm.Expr("current_timestamp"),
+		}),
+	}).Create(limit).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetWalletUsage counts the wallet's debits other than refunds; day is
+// expected to fall within the month
+func (r *transactionRepository) GetWalletUsage(ctx context.Context, walletID int, day, month time.Time) (service.WalletUsage, error) {
+	var usage service.WalletUsage
+
+	if err := r.db.WithContext(ctx).Raw(`SELECT
+			COALESCE(SUM(amount) FILTER (WHERE created_at >= ?), 0) AS daily_amount,
+			COUNT(*) FILTER (WHERE created_at >= ?) AS daily_count,
+			COALESCE(SUM(amount), 0) AS monthly_amount,
+			COUNT(*) AS monthly_count
+		FROM transactions
+		WHERE wallet_id = ? AND transaction_type = 'out' AND refund_of IS NULL AND created_at >= ?`,
+		day, day, walletID, month).Scan(&usage).Error; err != nil {
+		return service.WalletUsage{}, err
+	}
+	return usage, nil
+}
+
+// GetRecipientsSince lists the distinct wallets and merchants paid by the
+// wallet's debits other than refunds
+func (r *transactionRepository) GetRecipientsSince(ctx context.Context, walletID int, since time.Time) ([]service.RiskRecipient, error) {
+	var recipients []service.RiskRecipient
+
+	if err := r.db.WithContext(ctx).Raw(`SELECT DISTINCT COALESCE(wallet_id_source, 0) AS wallet_id, merchant_id
+		FROM transactions
+		WHERE wallet_id = ? AND transaction_type = 'out' AND refund_of IS NULL AND created_at >= ?`,
+		walletID, since).Scan(&recipients).Error; err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+// GetSpendingStats counts and sums the wallet's debits other than refunds
+func (r *transactionRepository) GetSpendingStats(ctx context.Context, walletID int, since time.Time) (service.AmountStats, error) {
+	var stats service.AmountStats
+
+	if err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total
+		FROM transactions
+		WHERE wallet_id = ? AND transaction_type = 'out' AND refund_of IS NULL AND created_at >= ?`,
+		walletID, since).Scan(&stats).Error; err != nil {
+		return service.AmountStats{}, err
+	}
+	return stats, nil
+}
+
+// GetTopUpStats counts and sums the wallet's top-ups, the credits with no
+// source wallet that are not refunds
+func (r *transactionRepository) GetTopUpStats(ctx context.Context, walletID int, since time.Time) (service.AmountStats, error) {
+	var stats service.AmountStats
+
+	if err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total
+		FROM transactions
+		WHERE wallet_id = ? AND transaction_type = 'in' AND wallet_id_source = 0 AND refund_of IS NULL AND created_at >= ?`,
+		walletID, since).Scan(&stats).Error; err != nil {
+		return service.AmountStats{}, err
+	}
+	return stats, nil
+}
+
+// CreateRiskAudit inserts an audit row for a denied or reviewed attempt
+func (r *transactionRepository) CreateRiskAudit(ctx context.Context, audit *entity.RiskAudit) error {
+	if err := r.db.WithContext(ctx).Create(audit).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// SaveMerchantWebhook inserts a merchant's endpoint or replaces its URL and
+// secret
+func (r *transactionRepository) SaveMerchantWebhook(ctx context.Context, webhook *entity.MerchantWebhook) error {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "merchant_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"url":        webhook.URL,
+			"secret":     webhook.Secret,
+			"updated_at": gorm.Expr("current_timestamp"),
+		}),
+	}).Create(webhook).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetMerchantWebhook retrieves a merchant's endpoint
+func (r *transactionRepository) GetMerchantWebhook(ctx context.Context, merchantID string) (entity.MerchantWebhook, error) {
+	var webhook entity.MerchantWebhook
+
+	if err := r.db.WithContext(ctx).First(&webhook, "merchant_id = ?", merchantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.MerchantWebhook{}, service.ErrWebhookNotFound
+		}
+		return entity.MerchantWebhook{}, err
+	}
+	return webhook, nil
+}
+
+func (r *transactionRepository) CreateWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	if err := r.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries leases due deliveries. SKIP LOCKED lets several
+// dispatchers claim disjoint batches concurrently.
+func (r *transactionRepository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+
+	if err := r.db.WithContext(ctx).Raw(`UPDATE webhook_deliveries
+		SET next_attempt_at = current_timestamp + make_interval(secs => ?)
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= current_timestamp
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, lease.Seconds(), entity.WebhookPending, limit).
+		Scan(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID < deliveries[j].ID })
+	return deliveries, nil
+}
+
+func (r *transactionRepository) MarkWebhookDelivered(ctx context.Context, id int64, statusCode int) error {
+	if err := r.db.WithContext(ctx).Model(&entity.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":           entity.WebhookDelivered,
+		"attempts":         gorm.Expr("attempts + 1"),
+		"last_status_code": statusCode,
+		"last_error":       "",
+		"delivered_at":     gorm.Expr("current_timestamp"),
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *transactionRepository) MarkWebhookFailed(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, statusCode int, lastError string, failed bool) error {
+	status := entity.WebhookPending
+	if failed {
+		status = entity.WebhookFailed
+	}
+	if err := r.db.WithContext(ctx).Model(&entity.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":           status,
+		"attempts":         attempts,
+		"next_attempt_at":  nextAttemptAt,
+		"last_status_code": statusCode,
+		"last_error":       lastError,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *transactionRepository) GetWebhookDeliveries(ctx context.Context, merchantID string, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+
+	if err := r.db.WithContext(ctx).Where("merchant_id = ?", merchantID).Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *transactionRepository) ResetWebhookDelivery(ctx context.Context, id int64) (entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+
+	if err := r.db.WithContext(ctx).Raw(`UPDATE webhook_deliveries
+		SET status = ?, attempts = 0, next_attempt_at = current_timestamp, delivered_at = NULL
+		WHERE id = ?
+		RETURNING *`, entity.WebhookPending, id).
+		Scan(&deliveries).Error; err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return entity.WebhookDelivery{}, service.ErrWebhookDeliveryNotFound
+	}
+	return deliveries[0], nil
+}
+
+// GetIdempotencyKey retrieves a stored idempotency key, reporting false when
+// the key has not been used yet
+func (r *transactionRepository) GetIdempotencyKey(ctx context.Context, key string) (entity.IdempotencyKey, bool, error) {
+	var stored entity.IdempotencyKey
+
+	if err := r.db.WithContext(ctx).First(&stored, "key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.IdempotencyKey{}, false, nil
+		}
+		return entity.IdempotencyKey{}, false, err
+	}
+	return stored, true, nil
+}
+
+// CreateIdempotencyKey stores an idempotency key. A key that already exists
+// is reported as service.ErrIdempotencyKeyExists.
+func (r *transactionRepository) CreateIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return service.ErrIdempotencyKeyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetTransactionByUserID retrieves one page of a user's transactions,
+// newest first. Rows are ordered by (created_at, transaction_id) so that the
+// keyset condition on the cursor never skips or repeats a row.
+func (r *transactionRepository) GetTransactionByUserID(ctx context.Context, userID int, filter service.TransactionFilter) ([]entity.Transaction, error) {
+	var transactions []entity.Transaction
+
+	query := r.db.WithContext(ctx).
 		Joins("JOIN wallets ON transactions.wallet_id = wallets.wallet_id").
-		Where("wallets.user_id = ?", userID).
+		Where("wallets.user_id = ?", userID)
+
+	if filter.After != nil {
+		query = query.Where("(transactions.created_at, transactions.transaction_id) < (?, ?)", filter.After.CreatedAt, filter.After.TransactionID)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("transactions.created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("transactions.created_at < ?", filter.CreatedTo)
+	}
+	if filter.Type != "" {
+		query = query.Where("transactions.transaction_type = ?", filter.Type)
+	}
+	if filter.CounterpartyWalletID != 0 {
+		query = query.Where("transactions.wallet_id_source = ?", filter.CounterpartyWalletID)
+	}
+	if filter.CounterpartyUserID != 0 {
+		query = query.Where("transactions.wallet_id_source IN (SELECT wallet_id FROM wallets WHERE user_id = ?)", filter.CounterpartyUserID)
+	}
+	if filter.MinAmount != 0 {
+		query = query.Where("transactions.amount >= ?", filter.MinAmount)
+	}
+	if filter.MaxAmount != 0 {
+		query = query.Where("transactions.amount <= ?", filter.MaxAmount)
+	}
+
+	if err := query.
+		Order("transactions.created_at DESC, transactions.transaction_id DESC").
+		Limit(filter.Limit).
 		Find(&transactions).Error; err != nil {
 		return nil, err
 	}
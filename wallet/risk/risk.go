@@ -0,0 +1,272 @@
+// Package risk provides a rules engine that screens top-ups, payments and
+// transfers for the wallet service.
+//
+// LoadFile reads the rules from a YAML file. Every rule is optional and
+// names the decision, review or deny, taken when it fires:
+//
+//	# A wallet younger than max_age moving more than amount at once.
+//	new_account_large_amount:
+//	  max_age: 72h
+//	  amounts: {IDR: "2000000.00"}
+//	  decision: review
+//	# Paying a new recipient after max distinct ones within window.
+//	distinct_recipients:
+//	  window: 1h
+//	  max: 5
+//	  decision: deny
+//	# A movement over factor times the wallet's average over lookback, once
+//	# it has at least min_history movements of that kind.
+//	amount_spike:
+//	  lookback: 720h
+//	  min_history: 5
+//	  factor: "10"
+//	  decision: review
+//
+// When several rules fire, deny wins over review.
+package risk
+
+import (
+	"context"
+	"ewallet/pkg/money"
+	"ewallet/wallet/service"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the built-in rules, as recorded in the risk audit.
+const (
+	RuleNewAccountLargeAmount = "new_account_large_amount"
+	RuleDistinctRecipients    = "distinct_recipients"
+	RuleAmountSpike           = "amount_spike"
+)
+
+// Config is the content of a rules file.
+type Config struct {
+	NewAccountLargeAmount *NewAccountLargeAmount `yaml:"new_account_large_amount"`
+	DistinctRecipients    *DistinctRecipients    `yaml:"distinct_recipients"`
+	AmountSpike           *AmountSpike           `yaml:"amount_spike"`
+}
+
+// NewAccountLargeAmount fires on a payment or transfer larger than the
+// amount set for its currency out of a wallet created less than MaxAge ago.
+type NewAccountLargeAmount struct {
+	MaxAge   time.Duration     `yaml:"max_age"`
+	Amounts  map[string]string `yaml:"amounts"`
+	Decision string            `yaml:"decision"`
+
+	amounts map[string]money.Amount
+}
+
+// DistinctRecipients fires on a payment or transfer to a recipient the
+// wallet has not paid within Window once it has already paid Max distinct
+// recipients within it.
+type DistinctRecipients struct {
+	Window   time.Duration `yaml:"window"`
+	Max      int           `yaml:"max"`
+	Decision string        `yaml:"decision"`
+}
+
+// AmountSpike fires on a movement larger than Factor times the average of
+// the wallet's movements of the same kind, top-ups or spending, over
+// Lookback. Wallets with fewer than MinHistory such movements are skipped.
+type AmountSpike struct {
+	Lookback   time.Duration `yaml:"lookback"`
+	MinHistory int           `yaml:"min_history"`
+	Factor     string        `yaml:"factor"`
+	Decision   string        `yaml:"decision"`
+
+	factor money.Rate
+}
+
+// Engine evaluates the configured rules. It implements service.RiskChecker
+// and is safe for concurrent use.
+type Engine struct {
+	cfg Config
+}
+
+// New validates cfg and returns an engine evaluating its rules.
+func New(cfg Config) (*Engine, error) {
+	if r := cfg.NewAccountLargeAmount; r != nil {
+		if r.MaxAge <= 0 {
+			return nil, fmt.Errorf("risk: %s.max_age must be positive", RuleNewAccountLargeAmount)
+		}
+		r.amounts = make(map[string]money.Amount, len(r.Amounts))
+		for currency, text := range r.Amounts {
+			amount, err := money.Parse(text)
+			if err != nil || amount <= 0 || !money.IsSupportedCurrency(currency) {
+				return nil, fmt.Errorf("risk: %s.amounts.%s must be a positive amount in a supported currency", RuleNewAccountLargeAmount, currency)
+			}
+			r.amounts[currency] = amount
+		}
+		if err := checkDecision(RuleNewAccountLargeAmount, r.Decision); err != nil {
+			return nil, err
+		}
+	}
+	if r := cfg.DistinctRecipients; r != nil {
+		if r.Window <= 0 || r.Max <= 0 {
+			return nil, fmt.Errorf("risk: %s.window and max must be positive", RuleDistinctRecipients)
+		}
+		if err := checkDecision(RuleDistinctRecipients, r.Decision); err != nil {
+			return nil, err
+		}
+	}
+	if r := cfg.AmountSpike; r != nil {
+		if r.Lookback <= 0 || r.MinHistory <= 0 {
+			return nil, fmt.Errorf("risk: %s.lookback and min_history must be positive", RuleAmountSpike)
+		}
+		factor, err := money.ParseRate(r.Factor)
+		if err != nil || factor <= money.One {
+			return nil, fmt.Errorf("risk: %s.factor must be a decimal greater than 1", RuleAmountSpike)
+		}
+		r.factor = factor
+		if err := checkDecision(RuleAmountSpike, r.Decision); err != nil {
+			return nil, err
+		}
+	}
+	return &Engine{cfg: cfg}, nil
+}
+
+// LoadFile reads rules from a YAML file; see the package documentation for
+// the format.
+func LoadFile(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("risk: reading %s: %w", path, err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("risk: parsing %s: %w", path, err)
+	}
+	return New(cfg)
+}
+
+// checkDecision validates the decision of a rule.
+func checkDecision(rule, decision string) error {
+	if decision != service.RiskReview && decision != service.RiskDeny {
+		return fmt.Errorf("risk: %s.decision must be %q or %q", rule, service.RiskReview, service.RiskDeny)
+	}
+	return nil
+}
+
+// finding is a rule that fired.
+type finding struct {
+	rule, decision, reason string
+}
+
+// Check implements service.RiskChecker.
+func (e *Engine) Check(ctx context.Context, history service.RiskHistory, attempt service.RiskAttempt) (service.RiskAssessment, error) {
+	var findings []finding
+	for _, rule := range []func(context.Context, service.RiskHistory, service.RiskAttempt) (*finding, error){
+		e.newAccountLargeAmount,
+		e.distinctRecipients,
+		e.amountSpike,
+	} {
+		f, err := rule(ctx, history, attempt)
+		if err != nil {
+			return service.RiskAssessment{}, err
+		}
+		if f != nil {
+			findings = append(findings, *f)
+		}
+	}
+
+	assessment := service.RiskAssessment{Decision: service.RiskAllow}
+	var reasons []string
+	for _, f := range findings {
+		if assessment.Decision != service.RiskDeny {
+			assessment.Decision = f.decision
+		}
+		assessment.Rules = append(assessment.Rules, f.rule)
+		reasons = append(reasons, f.reason)
+	}
+	assessment.Reason = strings.Join(reasons, "; ")
+	return assessment, nil
+}
+
+// outgoing reports whether an attempt takes money out of its wallet.
+func outgoing(attempt service.RiskAttempt) bool {
+	return attempt.Operation == service.OperationTransfer || attempt.Operation == service.OperationPayment
+}
+
+func (e *Engine) newAccountLargeAmount(ctx context.Context, history service.RiskHistory, attempt service.RiskAttempt) (*finding, error) {
+	r := e.cfg.NewAccountLargeAmount
+	if r == nil || !outgoing(attempt) {
+		return nil, nil
+	}
+	age := attempt.At.Sub(attempt.Wallet.CreatedAt)
+	limit, ok := r.amounts[attempt.Wallet.Currency]
+	if age >= r.MaxAge || !ok || attempt.Amount <= limit {
+		return nil, nil
+	}
+	return &finding{
+		rule:     RuleNewAccountLargeAmount,
+		decision: r.Decision,
+		reason: fmt.Sprintf("%s %s %s from a wallet created %s ago", attempt.Operation, attempt.Amount,
+			attempt.Wallet.Currency, age.Round(time.Minute)),
+	}, nil
+}
+
+func (e *Engine) distinctRecipients(ctx context.Context, history service.RiskHistory, attempt service.RiskAttempt) (*finding, error) {
+	r := e.cfg.DistinctRecipients
+	if r == nil || !outgoing(attempt) {
+		return nil, nil
+	}
+	recipients, err := history.GetRecipientsSince(ctx, int(attempt.Wallet.Walletid), attempt.At.Add(-r.Window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent recipients: %w", err)
+	}
+	if len(recipients) < r.Max {
+		return nil, nil
+	}
+	for _, recipient := range recipients {
+		if recipient == attempt.Recipient {
+			return nil, nil
+		}
+	}
+	return &finding{
+		rule:     RuleDistinctRecipients,
+		decision: r.Decision,
+		reason:   fmt.Sprintf("new recipient after %d distinct recipients within %s", len(recipients), r.Window),
+	}, nil
+}
+
+func (e *Engine) amountSpike(ctx context.Context, history service.RiskHistory, attempt service.RiskAttempt) (*finding, error) {
+	r := e.cfg.AmountSpike
+	if r == nil {
+		return nil, nil
+	}
+	since := attempt.At.Add(-r.Lookback)
+	walletID := int(attempt.Wallet.Walletid)
+	var stats service.AmountStats
+	var err error
+	if outgoing(attempt) {
+		stats, err = history.GetSpendingStats(ctx, walletID, since)
+	} else {
+		stats, err = history.GetTopUpStats(ctx, walletID, since)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get amount history: %w", err)
+	}
+	if stats.Count < r.MinHistory {
+		return nil, nil
+	}
+
+	average := stats.Total / money.Amount(stats.Count)
+	threshold, err := r.factor.Convert(average)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute spike threshold: %w", err)
+	}
+	if attempt.Amount <= threshold {
+		return nil, nil
+	}
+	return &finding{
+		rule:     RuleAmountSpike,
+		decision: r.Decision,
+		reason: fmt.Sprintf("%s %s %s is over %s times the average of %s over %s", attempt.Operation, attempt.Amount,
+			attempt.Wallet.Currency, r.factor, average, r.Lookback),
+	}, nil
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Errors returned by the two-phase payment methods.
+var (
+	ErrAuthorizationNotFound = errors.New("authorization not found")
+	// ErrAuthorizationClosed is returned when capturing or voiding an
+	// authorization that was already captured, voided or expired.
+	ErrAuthorizationClosed = errors.New("authorization is no longer open")
+	// ErrAuthorizationExpired is returned when capturing an authorization
+	// past its expiry time.
+	ErrAuthorizationExpired = errors.New("authorization has expired")
+	// ErrCaptureExceedsAuthorization is returned when a capture is larger
+	// than the authorized amount.
+	ErrCaptureExceedsAuthorization = errors.New("capture exceeds the authorized amount")
+)
+
+// Lifetimes of an authorization hold.
+const (
+	DefaultAuthorizationTTL = 7 * 24 * time.Hour
+	MaxAuthorizationTTL     = 30 * 24 * time.Hour
+)
+
+// expiryBatchSize is the number of stale authorizations released at once by
+// ExpireAuthorizations.
+const expiryBatchSize = 100
+
+// AuthorizePayment holds amount on a wallet for merchantID until it is
+// captured, voided or ttl elapses; a zero ttl means DefaultAuthorizationTTL.
+// The hold reduces the available balance but posts nothing to the ledger.
+// Replaying idempotencyKey returns the authorization it created.
+func (s *transactionService) AuthorizePayment(ctx context.Context, walletID int, amount money.Amount, merchantID string, ttl time.Duration, idempotencyKey string) (entity.Authorization, error) {
+	if ttl == 0 {
+		ttl = DefaultAuthorizationTTL
+	}
+	var v validator
+	v.check(walletID > 0, "wallet_id", "must be a positive wallet ID")
+	v.check(amount > 0, "amount", "must be greater than zero")
+	v.check(len(merchantID) <= maxMerchantIDLength, "merchant_id", fmt.Sprintf("must be at most %d characters", maxMerchantIDLength))
+	v.check(ttl > 0 && ttl <= MaxAuthorizationTTL, "ttl", fmt.Sprintf("must be between 1s and %s", MaxAuthorizationTTL))
+	if err := v.err(); err != nil {
+		return entity.Authorization{}, err
+	}
+
+	var result entity.Authorization
+	err := s.transactionRepo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		if idempotencyKey != "" {
+			stored, found, err := repo.GetAuthorizationByIdempotencyKey(ctx, idempotencyKey)
+			if err != nil {
+				return fmt.Errorf("failed to look up idempotency key: %w", err)
+			}
+			if found {
+				if stored.WalletID != walletID || stored.Amount != amount || stored.MerchantID != merchantID {
+					return ErrIdempotencyKeyReused
+				}
+				result = stored
+				return nil
+			}
+		}
+
+		wallet, err := repo.GetWalletByIDForUpdate(ctx, walletID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve wallet: %w", err)
+		}
+		if err := requireActive(wallet); err != nil {
+			return err
+		}
+		if wallet.Available() < amount {
+			return ErrInsufficientFunds
+		}
+
+		wallet.HeldBalance += amount
+		if err := repo.UpdateWallet(ctx, &wallet); err != nil {
+			return fmt.Errorf("failed to update wallet: %w", err)
+		}
+
+		authorization := &entity.Authorization{
+			WalletID:   walletID,
+			MerchantID: merchantID,
+			Amount:     amount,
+			Status:     entity.AuthorizationAuthorized,
+			ExpiresAt:  time.Now().Add(ttl),
+		}
+		if idempotencyKey != "" {
+			authorization.IdempotencyKey = &idempotencyKey
+		}
+		if err := repo.CreateAuthorization(ctx, authorization); err != nil {
+			return fmt.Errorf("failed to create authorization: %w", err)
+		}
+		result = *authorization
+		return nil
+	})
+	if errors.Is(err, ErrIdempotencyKeyExists) {
+		// A concurrent request with the same key committed first.
+		return s.AuthorizePayment(ctx, walletID, amount, merchantID, ttl, idempotencyKey)
+	}
+	if err != nil {
+		return entity.Authorization{}, err
+	}
+	return result, nil
+}
+
+// CapturePayment completes an authorization: amount, or the whole
+// authorized amount when it is 0, is paid to the merchant exactly as by
+// Payment, and the rest of the hold is released. Spending limits and risk
+// checks apply at capture, not when the amount is held. An authorization is
+// captured once; capturing it again with the same amount returns the
+// original payment, so a retried capture is safe.
+func (s *transactionService) CapturePayment(ctx context.Context, authorizationID uint, amount money.Amount) (entity.Transaction, error) {
+	var v validator
+	v.check(authorizationID > 0, "authorization_id", "must be a positive authorization ID")
+	v.check(amount >= 0, "amount", "must not be negative")
+	if err := v.err(); err != nil {
+		return entity.Transaction{}, err
+	}
+
+	var result entity.Transaction
+	err := s.transactionRepo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		authorization, err := repo.GetAuthorizationForUpdate(ctx, authorizationID)
+		if err != nil {
+			return fmt.Errorf("failed to lock authorization: %w", err)
+		}
+		capture := amount
+		if capture == 0 {
+			capture = authorization.Amount
+		}
+
+		switch {
+		case authorization.Status == entity.AuthorizationCaptured && capture == authorization.CapturedAmount:
+			result, err = repo.GetTransaction(ctx, int32(*authorization.TransactionID))
+			if err != nil {
+				return fmt.Errorf("failed to load capture: %w", err)
+			}
+			return nil
+		case authorization.Status != entity.AuthorizationAuthorized:
+			return fmt.Errorf("authorization %d is %s: %w", authorizationID, authorization.Status, ErrAuthorizationClosed)
+		case !time.Now().Before(authorization.ExpiresAt):
+			return fmt.Errorf("authorization %d expired at %s: %w", authorizationID, authorization.ExpiresAt.Format(time.RFC3339), ErrAuthorizationExpired)
+		case capture > authorization.Amount:
+			return fmt.Errorf("%s requested, %s authorized: %w", capture, authorization.Amount, ErrCaptureExceedsAuthorization)
+		}
+
+		wallet, err := repo.GetWalletByIDForUpdate(ctx, authorization.WalletID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve wallet: %w", err)
+		}
+		if err := requireActive(wallet); err != nil {
+			return err
+		}
+		if err := s.checkLimits(ctx, repo, wallet, capture); err != nil {
+			return err
+		}
+		err = s.screen(ctx, repo, RiskAttempt{
+			Operation: OperationPayment,
+			Wallet:    wallet,
+			Amount:    capture,
+			Recipient: RiskRecipient{MerchantID: authorization.MerchantID},
+		})
+		if err != nil {
+			return err
+		}
+		wallet.HeldBalance -= authorization.Amount
+		wallet.Balance -= capture
+		if err := repo.UpdateWallet(ctx, &wallet); err != nil {
+			return fmt.Errorf("failed to update wallet: %w", err)
+		}
+
+		created, err := recordPayment(ctx, repo, wallet, capture, authorization.MerchantID)
+		if err != nil {
+			return err
+		}
+
+		authorization.Status = entity.AuthorizationCaptured
+		authorization.CapturedAmount = capture
+		authorization.TransactionID = &created.TransactionID
+		if err := repo.UpdateAuthorization(ctx, &authorization); err != nil {
+			return fmt.Errorf("failed to update authorization: %w", err)
+		}
+		result = created
+		return nil
+	})
+	if err != nil {
+		return entity.Transaction{}, s.recordDenial(ctx, err)
+	}
+	s.events.notify(result.WalletID)
+	return result, nil
+}
+
+// VoidAuthorization cancels an open authorization and releases its hold.
+// Voiding an authorization that is already voided returns it unchanged.
+func (s *transactionService) VoidAuthorization(ctx context.Context, authorizationID uint) (entity.Authorization, error) {
+	var v validator
+	v.check(authorizationID > 0, "authorization_id", "must be a positive authorization ID")
+	if err := v.err(); err != nil {
+		return entity.Authorization{}, err
+	}
+
+	var result entity.Authorization
+	err := s.transactionRepo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		authorization, err := repo.GetAuthorizationForUpdate(ctx, authorizationID)
+		if err != nil {
+			return fmt.Errorf("failed to lock authorization: %w", err)
+		}
+		switch authorization.Status {
+		case entity.AuthorizationVoided:
+			result = authorization
+			return nil
+		case entity.AuthorizationAuthorized:
+		default:
+			return fmt.Errorf("authorization %d is %s: %w", authorizationID, authorization.Status, ErrAuthorizationClosed)
+		}
+
+		if err := releaseHold(ctx, repo, &authorization, entity.AuthorizationVoided); err != nil {
+			return err
+		}
+		result = authorization
+		return nil
+	})
+	if err != nil {
+		return entity.Authorization{}, err
+	}
+	return result, nil
+}
+
+// GetAuthorization retrieves an authorization by its ID.
+func (s *transactionService) GetAuthorization(ctx context.Context, authorizationID uint) (entity.Authorization, error) {
+	authorization, err := s.transactionRepo.GetAuthorization(ctx, authorizationID)
+	if err != nil {
+		return entity.Authorization{}, fmt.Errorf("failed to get authorization: %w", err)
+	}
+	return authorization, nil
+}
+
+// ExpireAuthorizations releases the hold of every open authorization past
+// its expiry time and returns how many expired. Several instances may run it
+// at once; each authorization is expired by exactly one of them.
+func (s *transactionService) ExpireAuthorizations(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		n := 0
+		err := s.transactionRepo.WithinTx(ctx, func(repo ITransactionRepository) error {
+			stale, err := repo.ClaimExpiredAuthorizations(ctx, expiryBatchSize)
+			if err != nil {
+				return fmt.Errorf("failed to claim expired authorizations: %w", err)
+			}
+			for i := range stale {
+				if err := releaseHold(ctx, repo, &stale[i], entity.AuthorizationExpired); err != nil {
+					return fmt.Errorf("failed to expire authorization %d: %w", stale[i].AuthorizationID, err)
+				}
+			}
+			n = len(stale)
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+		expired += n
+		if n < expiryBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunAuthorizationExpiry calls svc.ExpireAuthorizations every interval
+// until ctx is done.
+func RunAuthorizationExpiry(ctx context.Context, svc ITransactionService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := svc.ExpireAuthorizations(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("authorization expiry: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// releaseHold gives an open authorization's amount back to the wallet's
+// available balance and closes it with status. The authorization row must
+// already be locked.
+func releaseHold(ctx context.Context, repo ITransactionRepository, authorization *entity.Authorization, status string) error {
+	wallet, err := repo.GetWalletByIDForUpdate(ctx, authorization.WalletID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve wallet: %w", err)
+	}
+	wallet.HeldBalance -= authorization.Amount
+	if err := repo.UpdateWallet(ctx, &wallet); err != nil {
+		return fmt.Errorf("failed to update wallet: %w", err)
+	}
+
+	authorization.Status = status
+	if err := repo.UpdateAuthorization(ctx, authorization); err != nil {
+		return fmt.Errorf("failed to update authorization: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/service"
+	"testing"
+	"time"
+)
+
+func TestAuthorizationHold(t *testing.T) {
+	ctx := context.Background()
+	capture := func(amount money.Amount) func(service.ITransactionService, *ledgerRepo) error {
+		return func(svc service.ITransactionService, repo *ledgerRepo) error {
+			_, err := svc.CapturePayment(ctx, 1, amount)
+			return err
+		}
+	}
+	void := func(svc service.ITransactionService, repo *ledgerRepo) error {
+		_, err := svc.VoidAuthorization(ctx, 1)
+		return err
+	}
+	expire := func(svc service.ITransactionService, repo *ledgerRepo) error {
+		repo.authorizations[0].ExpiresAt = time.Now().Add(-time.Second)
+		return nil
+	}
+	runExpiry := func(svc service.ITransactionService, repo *ledgerRepo) error {
+		_, err := svc.ExpireAuthorizations(ctx)
+		return err
+	}
+
+	// Every case starts from 30.00 authorized on a wallet holding 100.00.
+	tests := []struct {
+		name        string
+		steps       []func(service.ITransactionService, *ledgerRepo) error
+		wantErr     error
+		wantBalance money.Amount
+		wantHeld    money.Amount
+		wantStatus  string
+	}{
+		{"open", nil, nil, 100_00, 30_00, entity.AuthorizationAuthorized},
+		{"capture all", steps(capture(0)), nil, 70_00, 0, entity.AuthorizationCaptured},
+		{"capture part", steps(capture(20_00)), nil, 80_00, 0, entity.AuthorizationCaptured},
+		{"capture twice", steps(capture(20_00), capture(20_00)), nil, 80_00, 0, entity.AuthorizationCaptured},
+		{"capture again for more", steps(capture(20_00), capture(30_00)), service.ErrAuthorizationClosed, 80_00, 0, entity.AuthorizationCaptured},
+		{"capture more than held", steps(capture(30_01)), service.ErrCaptureExceedsAuthorization, 100_00, 30_00, entity.AuthorizationAuthorized},
+		{"void", steps(void), nil, 100_00, 0, entity.AuthorizationVoided},
+		{"void twice", steps(void, void), nil, 100_00, 0, entity.AuthorizationVoided},
+		{"capture after void", steps(void, capture(0)), service.ErrAuthorizationClosed, 100_00, 0, entity.AuthorizationVoided},
+		{"void after capture", steps(capture(0), void), service.ErrAuthorizationClosed, 70_00, 0, entity.AuthorizationCaptured},
+		{"capture past expiry", steps(expire, capture(0)), service.ErrAuthorizationExpired, 100_00, 30_00, entity.AuthorizationAuthorized},
+		{"expiry", steps(expire, runExpiry), nil, 100_00, 0, entity.AuthorizationExpired},
+		{"expiry before it is due", steps(runExpiry), nil, 100_00, 30_00, entity.AuthorizationAuthorized},
+		{"capture after expiry", steps(expire, runExpiry, capture(0)), service.ErrAuthorizationClosed, 100_00, 0, entity.AuthorizationExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+				1: {Walletid: 1, UserID: 1, Balance: 100_00, Currency: "IDR"},
+			}}
+			svc := service.NewTransactionService(repo, nil, nil, nil)
+			if _, err := svc.AuthorizePayment(ctx, 1, 30_00, "acme", 0, ""); err != nil {
+				t.Fatalf("AuthorizePayment() error = %v", err)
+			}
+
+			var err error
+			for _, step := range tt.steps {
+				if err = step(svc, repo); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			wallet := repo.wallets[1]
+			if wallet.Balance != tt.wantBalance || wallet.HeldBalance != tt.wantHeld {
+				t.Fatalf("wallet holds %s with %s held, want %s with %s held", wallet.Balance, wallet.HeldBalance, tt.wantBalance, tt.wantHeld)
+			}
+			if got := repo.authorizations[0].Status; got != tt.wantStatus {
+				t.Fatalf("authorization is %s, want %s", got, tt.wantStatus)
+			}
+			// Only a capture posts to the ledger.
+			if captured := tt.wantBalance != 100_00; captured != (len(repo.transactions) == 1) {
+				t.Fatalf("%d transactions recorded", len(repo.transactions))
+			}
+		})
+	}
+}
+
+func TestAuthorizationReducesAvailableBalance(t *testing.T) {
+	ctx := context.Background()
+	repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+		1: {Walletid: 1, UserID: 1, Balance: 100_00, Currency: "IDR"},
+	}}
+	svc := service.NewTransactionService(repo, nil, nil, nil)
+	if _, err := svc.AuthorizePayment(ctx, 1, 30_00, "acme", 0, "auth-1"); err != nil {
+		t.Fatalf("AuthorizePayment() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		attempt func() error
+		wantErr error
+	}{
+		{"hold the rest and more", func() error {
+			_, err := svc.AuthorizePayment(ctx, 1, 70_01, "acme", 0, "")
+			return err
+		}, service.ErrInsufficientFunds},
+		{"pay the held amount", func() error {
+			_, err := svc.Payment(ctx, 1, 70_01, "", "")
+			return err
+		}, service.ErrInsufficientFunds},
+		{"replay the authorization", func() error {
+			_, err := svc.AuthorizePayment(ctx, 1, 30_00, "acme", 0, "auth-1")
+			return err
+		}, nil},
+		{"reuse the key for more", func() error {
+			_, err := svc.AuthorizePayment(ctx, 1, 40_00, "acme", 0, "auth-1")
+			return err
+		}, service.ErrIdempotencyKeyReused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.attempt(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if wallet := repo.wallets[1]; wallet.Balance != 100_00 || wallet.HeldBalance != 30_00 || len(repo.authorizations) != 1 {
+				t.Fatalf("wallet holds %s with %s held in %d authorizations", wallet.Balance, wallet.HeldBalance, len(repo.authorizations))
+			}
+		})
+	}
+}
+
+// steps lists the actions of a test case.
+func steps(s ...func(service.ITransactionService, *ledgerRepo) error) []func(service.ITransactionService, *ledgerRepo) error {
+	return s
+}
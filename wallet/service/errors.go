@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Domain errors returned by the wallet service. Callers should test for them
+// with errors.Is, since they are usually wrapped with more context.
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	// ErrNotRefundable is returned for a transaction that does not take
+	// money out of a wallet, or is itself a refund.
+	ErrNotRefundable = errors.New("transaction cannot be refunded")
+	// ErrRefundExceedsRemaining is returned when a refund is larger than the
+	// part of the transaction not refunded yet.
+	ErrRefundExceedsRemaining = errors.New("refund exceeds the amount left to refund")
+)
+
+// FieldViolation describes why a single request field was rejected.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError is returned when a request is rejected before any data is
+// read or written. It lists every invalid field, not just the first one.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = fmt.Sprintf("%s: %s", v.Field, v.Description)
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+// validator collects field violations for one request.
+type validator struct {
+	violations []FieldViolation
+}
+
+// check records a violation for field when ok is false.
+func (v *validator) check(ok bool, field, description string) {
+	if !ok {
+		v.violations = append(v.violations, FieldViolation{Field: field, Description: description})
+	}
+}
+
+// err returns a *ValidationError if any check failed, nil otherwise.
+func (v *validator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"ewallet/wallet/entity"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// eventPollInterval bounds how long a subscriber waits for events committed
+// by another instance of the service, which cannot wake it directly.
+const eventPollInterval = 5 * time.Second
+
+// eventBatchSize is the number of events read from the database at once
+// while a subscriber catches up.
+const eventBatchSize = 100
+
+// eventBroker wakes the subscribers of a wallet when an event for it is
+// committed. It carries no event data: subscribers read events from the
+// database, so a slow subscriber can never miss one.
+type eventBroker struct {
+	mu   sync.Mutex
+	subs map[int]map[chan struct{}]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subs: map[int]map[chan struct{}]struct{}{}}
+}
+
+// subscribe registers interest in walletID. The returned channel receives a
+// value whenever new events may be available; cancel must be called once
+// the subscriber is done.
+func (b *eventBroker) subscribe(walletID int) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	if b.subs[walletID] == nil {
+		b.subs[walletID] = map[chan struct{}]struct{}{}
+	}
+	b.subs[walletID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[walletID], ch)
+		if len(b.subs[walletID]) == 0 {
+			delete(b.subs, walletID)
+		}
+	}
+	return ch, cancel
+}
+
+// notify wakes every subscriber of the given wallets without blocking.
+func (b *eventBroker) notify(walletIDs ...int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, walletID := range walletIDs {
+		for ch := range b.subs[walletID] {
+			select {
+			case ch <- struct{}{}:
+			default:
+				// A wake-up is already pending.
+			}
+		}
+	}
+}
+
+// walletEvent builds the event recorded for a ledger row, given the wallet
+// balance after the change.
+func walletEvent(eventType string, wallet entity.Wallet, transaction entity.Transaction) *entity.WalletEvent {
+	return &entity.WalletEvent{
+		WalletID:      int(wallet.Walletid),
+		EventType:     eventType,
+		TransactionID: transaction.TransactionID,
+		Amount:        transaction.Amount,
+		Balance:       wallet.Balance,
+		Currency:      wallet.Currency,
+	}
+}
+
+// SubscribeWalletEvents calls send for every event of walletID after
+// afterEventID, in order, and then for each new event as it is committed.
+// With afterEventID 0 only events committed after the call are sent. It
+// returns when ctx is done or send fails.
+func (s *transactionService) SubscribeWalletEvents(ctx context.Context, walletID int, afterEventID int64, send func(entity.WalletEvent) error) error {
+	var v validator
+	v.check(walletID > 0, "wallet_id", "must be a positive wallet ID")
+	v.check(afterEventID >= 0, "after_event_id", "must not be negative")
+	if err := v.err(); err != nil {
+		return err
+	}
+	if _, err := s.transactionRepo.GetWalletByID(ctx, walletID); err != nil {
+		return fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	// Subscribe before reading, so an event committed in between still
+	// wakes this subscriber.
+	wake, cancel := s.events.subscribe(walletID)
+	defer cancel()
+
+	lastID := afterEventID
+	if lastID == 0 {
+		latest, err := s.transactionRepo.GetLatestWalletEventID(ctx, walletID)
+		if err != nil {
+			return fmt.Errorf("failed to get latest wallet event: %w", err)
+		}
+		lastID = latest
+	}
+
+	poll := time.NewTicker(eventPollInterval)
+	defer poll.Stop()
+	for {
+		for {
+			events, err := s.transactionRepo.GetWalletEvents(ctx, walletID, lastID, eventBatchSize)
+			if err != nil {
+				return fmt.Errorf("failed to get wallet events: %w", err)
+			}
+			for _, event := range events {
+				if err := send(event); err != nil {
+					return err
+				}
+				lastID = event.EventID
+			}
+			if len(events) < eventBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		case <-poll.C:
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+)
+
+// ErrRateUnavailable is returned when no exchange rate is known for a
+// currency pair.
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+// RateProvider supplies the exchange rates applied to cross-currency
+// transfers. Rate returns the rate that converts an amount in from into to;
+// it returns an error wrapping ErrRateUnavailable for an unknown pair.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (money.Rate, error)
+}
+
+// conversion is the result of converting an amount between the currencies
+// of two wallets.
+type conversion struct {
+	Original          money.Amount
+	OriginalCurrency  string
+	Converted         money.Amount
+	ConvertedCurrency string
+	Rate              money.Rate
+}
+
+// noConversion describes a movement that stays in one currency.
+func noConversion(amount money.Amount, currency string) conversion {
+	return conversion{
+		Original:          amount,
+		OriginalCurrency:  currency,
+		Converted:         amount,
+		ConvertedCurrency: currency,
+		Rate:              money.One,
+	}
+}
+
+// record copies the conversion onto a ledger row.
+func (c conversion) record(t *entity.Transaction) {
+	t.OriginalAmount = c.Original
+	t.OriginalCurrency = c.OriginalCurrency
+	t.ConvertedAmount = c.Converted
+	t.ConvertedCurrency = c.ConvertedCurrency
+	t.FxRate = c.Rate
+}
+
+// convert converts amount from one currency into another, rounding half to
+// even. Same-currency conversions use a rate of 1 without asking rates.
+func convert(ctx context.Context, rates RateProvider, amount money.Amount, from, to string) (conversion, error) {
+	if from == to {
+		return noConversion(amount, from), nil
+	}
+	if rates == nil {
+		return conversion{}, fmt.Errorf("%s to %s: %w", from, to, ErrRateUnavailable)
+	}
+
+	rate, err := rates.Rate(ctx, from, to)
+	if err != nil {
+		return conversion{}, fmt.Errorf("failed to get %s to %s rate: %w", from, to, err)
+	}
+	converted, err := rate.Convert(amount)
+	if err != nil {
+		return conversion{}, fmt.Errorf("failed to convert %s %s to %s: %w", amount, from, to, err)
+	}
+	if converted <= 0 {
+		var v validator
+		v.check(false, "amount", fmt.Sprintf("converts to zero %s at rate %s", to, rate))
+		return conversion{}, v.err()
+	}
+	return conversion{
+		Original:          amount,
+		OriginalCurrency:  from,
+		Converted:         converted,
+		ConvertedCurrency: to,
+		Rate:              rate,
+	}, nil
+}
@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/fx"
+	"ewallet/wallet/service"
+	"testing"
+)
+
+// newFXService returns a service converting at USD/IDR 16250.125 between
+// wallet 1, holding 100.00 USD, and wallet 2, an empty IDR wallet. The
+// IDR/USD rate is derived by the provider.
+func newFXService(t *testing.T) (service.ITransactionService, *ledgerRepo) {
+	t.Helper()
+	rate, err := money.ParseRate("16250.125")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates, err := fx.NewStatic(map[string]money.Rate{"USD/IDR": rate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+		1: {Walletid: 1, UserID: 1, Balance: 100_00, Currency: "USD"},
+		2: {Walletid: 2, UserID: 2, Currency: "IDR"},
+	}}
+	return service.NewTransactionService(repo, rates, nil, nil), repo
+}
+
+func TestTransferConvertsAtTheCurrentRate(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newFXService(t)
+
+	// 10.01 USD is 162663.75125 IDR, credited as 162663.75.
+	out, err := svc.TransferWallet(ctx, 1, 2, 10_01, "")
+	if err != nil {
+		t.Fatalf("TransferWallet() error = %v", err)
+	}
+	if out.Amount != 10_01 || out.ConvertedAmount != 162_663_75 || out.ConvertedCurrency != "IDR" || out.FxRate.String() != "16250.125" {
+		t.Fatalf("transfer recorded %s to %s %s at %s", out.Amount, out.ConvertedAmount, out.ConvertedCurrency, out.FxRate)
+	}
+	if got := repo.balance(t, 1); got != 89_99 {
+		t.Fatalf("USD wallet holds %s, want 89.99", got)
+	}
+	if got := repo.balance(t, 2); got != 162_663_75 {
+		t.Fatalf("IDR wallet holds %s, want 162663.75", got)
+	}
+
+	// Each currency balances on its own, through the FX conversion account.
+	entry := repo.entries[len(repo.entries)-1]
+	sums := map[string]money.Amount{}
+	for _, p := range entry.Postings {
+		if p.Direction == entity.Debit {
+			sums[p.Currency] += p.Amount
+		} else {
+			sums[p.Currency] -= p.Amount
+		}
+	}
+	if len(entry.Postings) != 4 || sums["USD"] != 0 || sums["IDR"] != 0 {
+		t.Fatalf("transfer posted %+v", entry.Postings)
+	}
+}
+
+func TestTransferRefundsAtTheOriginalRate(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newFXService(t)
+
+	out, err := svc.TransferWallet(ctx, 1, 2, 10_01, "")
+	if err != nil {
+		t.Fatalf("TransferWallet() error = %v", err)
+	}
+
+	// 3.00 USD is 48750.375 IDR: a tie, rounded to the even 48750.38.
+	refund, err := svc.Refund(ctx, out.TransactionID, 3_00, "")
+	if err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if refund.Amount != 3_00 || refund.OriginalAmount != 48_750_38 || refund.OriginalCurrency != "IDR" || refund.ConvertedCurrency != "USD" {
+		t.Fatalf("refund recorded %s %s to %s %s", refund.OriginalAmount, refund.OriginalCurrency, refund.ConvertedAmount, refund.ConvertedCurrency)
+	}
+	if inverse, _ := out.FxRate.Inverse(); refund.FxRate != inverse {
+		t.Fatalf("refund rate %s, want the inverse of %s", refund.FxRate, out.FxRate)
+	}
+	if got := repo.balance(t, 2); got != 113_913_37 {
+		t.Fatalf("IDR wallet holds %s after the partial refund, want 113913.37", got)
+	}
+
+	// The last refund takes whatever is left of the credit, so rounding
+	// leaves nothing behind.
+	if _, err := svc.Refund(ctx, out.TransactionID, 0, ""); err != nil {
+		t.Fatalf("Refund() of the remainder error = %v", err)
+	}
+	if got := repo.balance(t, 1); got != 100_00 {
+		t.Fatalf("USD wallet holds %s after the full refund, want 100.00", got)
+	}
+	if got := repo.balance(t, 2); got != 0 {
+		t.Fatalf("IDR wallet holds %s after the full refund, want 0", got)
+	}
+}
+
+func TestTransferUsesTheDerivedReverseRate(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newFXService(t)
+	repo.wallets[2].Balance = 1_000_000_00
+
+	// IDR/USD is 1/16250.125 = 0.0000615380 to ten places, so 16250.13 IDR
+	// converts to 1.00 USD.
+	out, err := svc.TransferWallet(ctx, 2, 1, 16_250_13, "")
+	if err != nil {
+		t.Fatalf("TransferWallet() error = %v", err)
+	}
+	if out.ConvertedAmount != 1_00 || out.FxRate.String() != "0.000061538" {
+		t.Fatalf("transfer converted to %s USD at %s", out.ConvertedAmount, out.FxRate)
+	}
+	if got := repo.balance(t, 1); got != 101_00 {
+		t.Fatalf("USD wallet holds %s, want 101.00", got)
+	}
+
+	// 0.01 IDR is worth less than a cent and is rejected before any row
+	// is locked.
+	var validationErr *service.ValidationError
+	if _, err := svc.TransferWallet(ctx, 2, 1, 1, ""); !errors.As(err, &validationErr) {
+		t.Fatalf("transferring dust: error = %v, want a validation error", err)
+	}
+}
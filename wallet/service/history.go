@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes for transaction history.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// TransactionQuery selects one page of a user's transaction history. Zero
+// values leave a filter unset.
+type TransactionQuery struct {
+	PageSize int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+
+	// CreatedFrom is inclusive and CreatedTo exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Type is "in" or "out".
+	Type                 string
+	CounterpartyWalletID int
+	CounterpartyUserID   int
+	// MinAmount and MaxAmount are inclusive.
+	MinAmount money.Amount
+	MaxAmount money.Amount
+}
+
+// TransactionPage is one page of transaction history, newest first.
+type TransactionPage struct {
+	Transactions []entity.Transaction
+	// NextCursor fetches the following page; it is empty on the last page.
+	NextCursor string
+}
+
+// TransactionCursor is the position after which the next page starts.
+type TransactionCursor struct {
+	CreatedAt     time.Time
+	TransactionID uint
+}
+
+// TransactionFilter is a TransactionQuery as passed to the repository: the
+// cursor is decoded and Limit already includes the look-ahead row.
+type TransactionFilter struct {
+	TransactionQuery
+	After *TransactionCursor
+	Limit int
+}
+
+// encodeCursor returns the opaque cursor pointing after t.
+func encodeCursor(t entity.Transaction) string {
+	raw := fmt.Sprintf("%d:%d", t.CreatedAt.UnixMicro(), t.TransactionID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor produced by encodeCursor.
+func decodeCursor(cursor string) (*TransactionCursor, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, false
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, false
+	}
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	transactionID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &TransactionCursor{CreatedAt: time.UnixMicro(createdAt), TransactionID: uint(transactionID)}, true
+}
+
+// GetTransactionByUserID returns one page of a user's transactions, newest
+// first and ordered by created_at then transaction ID, so pages never skip
+// or repeat a row.
+func (s *transactionService) GetTransactionByUserID(ctx context.Context, userID int, query TransactionQuery) (TransactionPage, error) {
+	if query.PageSize == 0 {
+		query.PageSize = DefaultPageSize
+	}
+
+	filter := TransactionFilter{TransactionQuery: query, Limit: query.PageSize + 1}
+
+	var v validator
+	v.check(userID > 0, "user_id", "must be a positive user ID")
+	v.check(query.PageSize > 0 && query.PageSize <= MaxPageSize, "page_size", fmt.Sprintf("must be between 1 and %d", MaxPageSize))
+	if query.Cursor != "" {
+		after, ok := decodeCursor(query.Cursor)
+		v.check(ok, "cursor", "is not a valid cursor")
+		filter.After = after
+	}
+	v.check(query.Type == "" || query.Type == "in" || query.Type == "out", "transaction_type", `must be "in" or "out"`)
+	v.check(query.CreatedFrom.IsZero() || query.CreatedTo.IsZero() || query.CreatedFrom.Before(query.CreatedTo), "created_to", "must be after created_from")
+	v.check(query.CounterpartyWalletID >= 0, "counterparty_wallet_id", "must not be negative")
+	v.check(query.CounterpartyUserID >= 0, "counterparty_user_id", "must not be negative")
+	v.check(query.MinAmount >= 0, "min_amount", "must not be negative")
+	v.check(query.MaxAmount >= 0, "max_amount", "must not be negative")
+	v.check(query.MaxAmount == 0 || query.MinAmount <= query.MaxAmount, "max_amount", "must not be less than min_amount")
+	if err := v.err(); err != nil {
+		return TransactionPage{}, err
+	}
+
+	transactions, err := s.transactionRepo.GetTransactionByUserID(ctx, userID, filter)
+	if err != nil {
+		return TransactionPage{}, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	page := TransactionPage{Transactions: transactions}
+	if len(transactions) > query.PageSize {
+		page.Transactions = transactions[:query.PageSize]
+		page.NextCursor = encodeCursor(page.Transactions[query.PageSize-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/wallet/entity"
+	"fmt"
+)
+
+// Operation names stored with an idempotency key.
+const (
+	OperationTopUp    = "topup"
+	OperationPayment  = "payment"
+	OperationTransfer = "transfer"
+	OperationRefund   = "refund"
+)
+
+var (
+	// ErrIdempotencyKeyExists is returned by the repository when the key has
+	// already been stored by another request.
+	ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+	// ErrIdempotencyKeyReused is returned when a key is replayed with a
+	// different operation, wallet, merchant or amount than it was first used with.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+)
+
+// idempotent runs op in a single database transaction and remembers its
+// result under key. If key was already used, op is not run and the
+// transaction recorded the first time is returned instead. An empty key
+// disables the check. A risk denial returned by op is audited once the
+// transaction has rolled back.
+func (s *transactionService) idempotent(ctx context.Context, key string, request entity.IdempotencyKey, op func(repo ITransactionRepository) (entity.Transaction, error)) (entity.Transaction, error) {
+	var result entity.Transaction
+	err := s.transactionRepo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		if key != "" {
+			stored, found, err := repo.GetIdempotencyKey(ctx, key)
+			if err != nil {
+				return fmt.Errorf("failed to look up idempotency key: %w", err)
+			}
+			if found {
+				result, err = replay(ctx, repo, stored, request)
+				return err
+			}
+		}
+
+		transaction, err := op(repo)
+		if err != nil {
+			return err
+		}
+
+		if key != "" {
+			request.Key = key
+			request.TransactionID = transaction.TransactionID
+			if err := repo.CreateIdempotencyKey(ctx, &request); err != nil {
+				return fmt.Errorf("failed to store idempotency key: %w", err)
+			}
+		}
+		result = transaction
+		return nil
+	})
+	if errors.Is(err, ErrIdempotencyKeyExists) {
+		// A concurrent request with the same key committed first and this
+		// one was rolled back; answer with the committed result.
+		return s.idempotent(ctx, key, request, op)
+	}
+	return result, s.recordDenial(ctx, err)
+}
+
+// replay returns the transaction recorded for a previously used key after
+// checking that the retried request matches the original one.
+func replay(ctx context.Context, repo ITransactionRepository, stored, request entity.IdempotencyKey) (entity.Transaction, error) {
+	if stored.Operation != request.Operation ||
+		stored.WalletID != request.WalletID ||
+		stored.CounterpartyWalletID != request.CounterpartyWalletID ||
+		stored.MerchantID != request.MerchantID ||
+		stored.RefundOf != request.RefundOf ||
+		stored.Amount != request.Amount {
+		return entity.Transaction{}, ErrIdempotencyKeyReused
+	}
+
+	transaction, err := repo.GetTransaction(ctx, int32(stored.TransactionID))
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to load original transaction: %w", err)
+	}
+	return transaction, nil
+}
@@ -0,0 +1,65 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/service"
+	"testing"
+)
+
+func TestIdempotencyKeyReplay(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		retry func(svc service.ITransactionService) (entity.Transaction, error)
+		// replayed is set when the retry must return the first top-up.
+		replayed    bool
+		wantErr     error
+		wantBalance money.Amount
+	}{
+		{"same request", func(svc service.ITransactionService) (entity.Transaction, error) {
+			return svc.TopUp(ctx, 1, 10_00, "key-1")
+		}, true, nil, 10_00},
+		{"different amount", func(svc service.ITransactionService) (entity.Transaction, error) {
+			return svc.TopUp(ctx, 1, 20_00, "key-1")
+		}, false, service.ErrIdempotencyKeyReused, 10_00},
+		{"different wallet", func(svc service.ITransactionService) (entity.Transaction, error) {
+			return svc.TopUp(ctx, 2, 10_00, "key-1")
+		}, false, service.ErrIdempotencyKeyReused, 10_00},
+		{"different operation", func(svc service.ITransactionService) (entity.Transaction, error) {
+			return svc.Payment(ctx, 1, 10_00, "", "key-1")
+		}, false, service.ErrIdempotencyKeyReused, 10_00},
+		{"different key", func(svc service.ITransactionService) (entity.Transaction, error) {
+			return svc.TopUp(ctx, 1, 10_00, "key-2")
+		}, false, nil, 20_00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+				1: {Walletid: 1, UserID: 1, Currency: "IDR"},
+				2: {Walletid: 2, UserID: 1, Currency: "IDR"},
+			}}
+			svc := service.NewTransactionService(repo, nil, nil, nil)
+
+			first, err := svc.TopUp(ctx, 1, 10_00, "key-1")
+			if err != nil {
+				t.Fatalf("TopUp() error = %v", err)
+			}
+			got, err := tt.retry(svc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("retry error = %v, want %v", err, tt.wantErr)
+			}
+			if replayed := err == nil && got.TransactionID == first.TransactionID; replayed != tt.replayed {
+				t.Fatalf("retry returned transaction %d, first was %d", got.TransactionID, first.TransactionID)
+			}
+			if b := repo.balance(t, 1); b != tt.wantBalance {
+				t.Fatalf("wallet 1 holds %s, want %s", b, tt.wantBalance)
+			}
+			if b := repo.balance(t, 2); b != 0 {
+				t.Fatalf("wallet 2 holds %s, want 0", b)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+)
+
+// ErrUnbalancedEntry is returned when the debits and credits of a journal
+// entry do not sum to the same amount.
+var ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+
+// debit returns a posting that takes amount out of account.
+func debit(account string, walletID int, amount money.Amount, currency string) entity.Posting {
+	return entity.Posting{Account: account, WalletID: walletID, Direction: entity.Debit, Amount: amount, Currency: currency}
+}
+
+// credit returns a posting that puts amount into account.
+func credit(account string, walletID int, amount money.Amount, currency string) entity.Posting {
+	return entity.Posting{Account: account, WalletID: walletID, Direction: entity.Credit, Amount: amount, Currency: currency}
+}
+
+// walletDebit and walletCredit are debit and credit against a wallet account,
+// in the wallet's currency.
+func walletDebit(wallet entity.Wallet, amount money.Amount) entity.Posting {
+	return debit(entity.WalletAccount(int(wallet.Walletid)), int(wallet.Walletid), amount, wallet.Currency)
+}
+
+func walletCredit(wallet entity.Wallet, amount money.Amount) entity.Posting {
+	return credit(entity.WalletAccount(int(wallet.Walletid)), int(wallet.Walletid), amount, wallet.Currency)
+}
+
+// postJournal records a journal entry after checking that its postings
+// balance in every currency they use. It must run inside the same database
+// transaction as the wallet balance updates it describes.
+func postJournal(ctx context.Context, repo ITransactionRepository, entryType string, postings ...entity.Posting) (entity.JournalEntry, error) {
+	debits := map[string]money.Amount{}
+	credits := map[string]money.Amount{}
+	for _, p := range postings {
+		if p.Amount <= 0 {
+			return entity.JournalEntry{}, fmt.Errorf("%w: posting to %s has non-positive amount %s", ErrUnbalancedEntry, p.Account, p.Amount)
+		}
+		if p.Currency == "" {
+			return entity.JournalEntry{}, fmt.Errorf("%w: posting to %s has no currency", ErrUnbalancedEntry, p.Account)
+		}
+		switch p.Direction {
+		case entity.Debit:
+			debits[p.Currency] += p.Amount
+		case entity.Credit:
+			credits[p.Currency] += p.Amount
+		default:
+			return entity.JournalEntry{}, fmt.Errorf("%w: unknown direction %q", ErrUnbalancedEntry, p.Direction)
+		}
+	}
+	if len(postings) < 2 {
+		return entity.JournalEntry{}, fmt.Errorf("%w: fewer than two postings", ErrUnbalancedEntry)
+	}
+	for currency := range mergeKeys(debits, credits) {
+		if debits[currency] != credits[currency] {
+			return entity.JournalEntry{}, fmt.Errorf("%w: %s debits %s, credits %s", ErrUnbalancedEntry, currency, debits[currency], credits[currency])
+		}
+	}
+
+	entry := &entity.JournalEntry{EntryType: entryType, Postings: postings}
+	created, err := repo.CreateJournalEntry(ctx, entry)
+	if err != nil {
+		return entity.JournalEntry{}, fmt.Errorf("failed to create journal entry: %w", err)
+	}
+	return created, nil
+}
+
+// mergeKeys returns the union of the keys of a and b.
+func mergeKeys(a, b map[string]money.Amount) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
+
+// GetLedgerBalance returns a wallet's balance as derived from its postings
+// (credits minus debits), for reconciling against the stored balance.
+func (s *transactionService) GetLedgerBalance(ctx context.Context, walletID int) (money.Amount, error) {
+	balance, err := s.transactionRepo.GetLedgerBalance(ctx, walletID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+	return balance, nil
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+	"time"
+)
+
+// ErrLimitExceeded is wrapped by every *LimitError.
+var ErrLimitExceeded = errors.New("spending limit exceeded")
+
+// Names of the spending limits.
+const (
+	LimitDailyAmount   = "daily_amount"
+	LimitMonthlyAmount = "monthly_amount"
+	LimitDailyCount    = "daily_count"
+	LimitMonthlyCount  = "monthly_count"
+)
+
+// LimitError is returned when a payment or transfer would take a wallet over
+// one of its spending limits. Amount limits are in minor units of Currency;
+// count limits leave Currency empty.
+type LimitError struct {
+	WalletID int
+	Limit    string
+	Currency string
+	Max      int64
+	Used     int64
+	// Requested is the amount of the rejected movement, or 1 for a count.
+	Requested int64
+	// ResetsAt is when the day or month of the limit ends.
+	ResetsAt time.Time
+}
+
+func (e *LimitError) Error() string {
+	if e.Currency == "" {
+		return fmt.Sprintf("wallet %d: %s limit of %d reached", e.WalletID, e.Limit, e.Max)
+	}
+	return fmt.Sprintf("wallet %d: %s limit of %s %s exceeded: %s used, %s requested", e.WalletID, e.Limit,
+		money.Amount(e.Max), e.Currency, money.Amount(e.Used), money.Amount(e.Requested))
+}
+
+func (e *LimitError) Unwrap() error { return ErrLimitExceeded }
+
+// TierLimits are the default spending limits of a tier. Amounts are keyed by
+// currency; a currency without an amount, and a zero count, are not
+// limited.
+type TierLimits struct {
+	DailyAmount   map[string]money.Amount
+	MonthlyAmount map[string]money.Amount
+	DailyCount    int
+	MonthlyCount  int
+}
+
+// LimitPolicy maps tier names to their limits. It must define
+// entity.TierUnverified; a nil policy enforces no limits.
+type LimitPolicy map[string]TierLimits
+
+// Limits are the spending limits in force on one wallet, in its currency.
+// Zero means unlimited.
+type Limits struct {
+	DailyAmount   money.Amount
+	MonthlyAmount money.Amount
+	DailyCount    int
+	MonthlyCount  int
+}
+
+// WalletUsage is what a wallet spent in the current UTC day and month:
+// payments and outgoing transfers, refunds not deducted.
+type WalletUsage struct {
+	DailyAmount   money.Amount
+	DailyCount    int
+	MonthlyAmount money.Amount
+	MonthlyCount  int
+}
+
+// WalletLimits describes the limits of a wallet and how much of them is
+// used.
+type WalletLimits struct {
+	WalletID int
+	Currency string
+	Tier     string
+	Limits   Limits
+	Usage    WalletUsage
+}
+
+// limitPeriods returns the start of the UTC day and month containing now.
+func limitPeriods(now time.Time) (day, month time.Time) {
+	now = now.UTC()
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
+
+// walletLimits resolves the tier, limits and current usage of a wallet.
+func (s *transactionService) walletLimits(ctx context.Context, repo ITransactionRepository, wallet entity.Wallet, now time.Time) (WalletLimits, error) {
+	tier, err := repo.GetUserTier(ctx, wallet.UserID)
+	if err != nil {
+		return WalletLimits{}, fmt.Errorf("failed to get user tier: %w", err)
+	}
+	defaults, ok := s.limits[tier]
+	if !ok {
+		// A tier dropped from the configuration falls back to the default.
+		defaults = s.limits[entity.TierUnverified]
+	}
+	limits := Limits{
+		DailyAmount:   defaults.DailyAmount[wallet.Currency],
+		MonthlyAmount: defaults.MonthlyAmount[wallet.Currency],
+		DailyCount:    defaults.DailyCount,
+		MonthlyCount:  defaults.MonthlyCount,
+	}
+
+	override, found, err := repo.GetWalletLimit(ctx, int(wallet.Walletid))
+	if err != nil {
+		return WalletLimits{}, fmt.Errorf("failed to get wallet limits: %w", err)
+	}
+	if found {
+		if override.DailyAmount != nil {
+			limits.DailyAmount = *override.DailyAmount
+		}
+		if override.MonthlyAmount != nil {
+			limits.MonthlyAmount = *override.MonthlyAmount
+		}
+		if override.DailyCount != nil {
+			limits.DailyCount = *override.DailyCount
+		}
+		if override.MonthlyCount != nil {
+			limits.MonthlyCount = *override.MonthlyCount
+		}
+	}
+
+	day, month := limitPeriods(now)
+	usage, err := repo.GetWalletUsage(ctx, int(wallet.Walletid), day, month)
+	if err != nil {
+		return WalletLimits{}, fmt.Errorf("failed to get wallet usage: %w", err)
+	}
+	return WalletLimits{
+		WalletID: int(wallet.Walletid),
+		Currency: wallet.Currency,
+		Tier:     tier,
+		Limits:   limits,
+		Usage:    usage,
+	}, nil
+}
+
+// checkLimits returns a *LimitError if spending amount from wallet would
+// exceed one of its limits. The wallet row must already be locked, so that
+// concurrent debits of the wallet are checked one after the other.
+func (s *transactionService) checkLimits(ctx context.Context, repo ITransactionRepository, wallet entity.Wallet, amount money.Amount) error {
+	if s.limits == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	current, err := s.walletLimits(ctx, repo, wallet, now)
+	if err != nil {
+		return err
+	}
+
+	day, month := limitPeriods(now)
+	nextDay, nextMonth := day.AddDate(0, 0, 1), month.AddDate(0, 1, 0)
+	limits, usage := current.Limits, current.Usage
+	checks := []struct {
+		limit     string
+		max, used int64
+		requested int64
+		currency  string
+		resetsAt  time.Time
+	}{
+		{LimitDailyCount, int64(limits.DailyCount), int64(usage.DailyCount), 1, "", nextDay},
+		{LimitMonthlyCount, int64(limits.MonthlyCount), int64(usage.MonthlyCount), 1, "", nextMonth},
+		{LimitDailyAmount, int64(limits.DailyAmount), int64(usage.DailyAmount), int64(amount), wallet.Currency, nextDay},
+		{LimitMonthlyAmount, int64(limits.MonthlyAmount), int64(usage.MonthlyAmount), int64(amount), wallet.Currency, nextMonth},
+	}
+	for _, c := range checks {
+		if c.max > 0 && c.used+c.requested > c.max {
+			return &LimitError{
+				WalletID:  int(wallet.Walletid),
+				Limit:     c.limit,
+				Currency:  c.currency,
+				Max:       c.max,
+				Used:      c.used,
+				Requested: c.requested,
+				ResetsAt:  c.resetsAt,
+			}
+		}
+	}
+	return nil
+}
+
+// GetWalletLimits returns the limits in force on a wallet and what it spent
+// so far in the current day and month.
+func (s *transactionService) GetWalletLimits(ctx context.Context, walletID int) (WalletLimits, error) {
+	var v validator
+	v.check(walletID > 0, "wallet_id", "must be a positive wallet ID")
+	if err := v.err(); err != nil {
+		return WalletLimits{}, err
+	}
+
+	wallet, err := s.transactionRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return WalletLimits{}, fmt.Errorf("failed to retrieve wallet: %w", err)
+	}
+	return s.walletLimits(ctx, s.transactionRepo, wallet, time.Now())
+}
+
+// SetUserTier moves a user to another limit tier, which applies to all of
+// the user's wallets from their next payment or transfer.
+func (s *transactionService) SetUserTier(ctx context.Context, userID uint, tier string) error {
+	var v validator
+	v.check(userID > 0, "user_id", "must be a positive user ID")
+	// Without configured tiers any name is accepted; the default tier always
+	// is.
+	_, known := s.limits[tier]
+	v.check(known || tier == entity.TierUnverified || (len(s.limits) == 0 && tier != ""), "tier", "is not a configured tier")
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	if err := s.transactionRepo.SaveUserTier(ctx, &entity.UserTier{UserID: userID, Tier: tier}); err != nil {
+		return fmt.Errorf("failed to save user tier: %w", err)
+	}
+	return nil
+}
+
+// SetWalletLimits replaces the overrides of a wallet's tier limits. A nil
+// field of limit restores the tier's limit; zero removes the limit.
+func (s *transactionService) SetWalletLimits(ctx context.Context, limit entity.WalletLimit) (WalletLimits, error) {
+	var v validator
+	v.check(limit.WalletID > 0, "wallet_id", "must be a positive wallet ID")
+	v.check(limit.DailyAmount == nil || *limit.DailyAmount >= 0, "daily_amount", "must not be negative")
+	v.check(limit.MonthlyAmount == nil || *limit.MonthlyAmount >= 0, "monthly_amount", "must not be negative")
+	v.check(limit.DailyCount == nil || *limit.DailyCount >= 0, "daily_count", "must not be negative")
+	v.check(limit.MonthlyCount == nil || *limit.MonthlyCount >= 0, "monthly_count", "must not be negative")
+	if err := v.err(); err != nil {
+		return WalletLimits{}, err
+	}
+
+	wallet, err := s.transactionRepo.GetWalletByID(ctx, limit.WalletID)
+	if err != nil {
+		return WalletLimits{}, fmt.Errorf("failed to retrieve wallet: %w", err)
+	}
+	if err := s.transactionRepo.SaveWalletLimit(ctx, &limit); err != nil {
+		return WalletLimits{}, fmt.Errorf("failed to save wallet limits: %w", err)
+	}
+	return s.walletLimits(ctx, s.transactionRepo, wallet, time.Now())
+}
@@ -0,0 +1,113 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/service"
+	"testing"
+)
+
+// testPolicy limits unverified IDR wallets to 50.00 and three payments a
+// day and 80.00 a month, and verified ones to 500.00 a day.
+var testPolicy = service.LimitPolicy{
+	entity.TierUnverified: {
+		DailyAmount:   map[string]money.Amount{"IDR": 50_00},
+		MonthlyAmount: map[string]money.Amount{"IDR": 80_00},
+		DailyCount:    3,
+	},
+	"verified": {
+		DailyAmount: map[string]money.Amount{"IDR": 500_00},
+	},
+}
+
+func TestPaymentLimits(t *testing.T) {
+	ctx := context.Background()
+	amount := func(a money.Amount) *money.Amount { return &a }
+	tests := []struct {
+		name     string
+		currency string
+		tier     string
+		override *entity.WalletLimit
+		payments []money.Amount
+		// wantLimit names the limit the last payment exceeds, if any.
+		wantLimit string
+	}{
+		{"within the default tier", "IDR", "", nil, []money.Amount{20_00, 30_00}, ""},
+		{"daily amount", "IDR", "", nil, []money.Amount{30_00, 20_01}, service.LimitDailyAmount},
+		{"daily count", "IDR", "", nil, []money.Amount{1_00, 1_00, 1_00, 1_00}, service.LimitDailyCount},
+		{"monthly amount", "IDR", "", &entity.WalletLimit{WalletID: 1, DailyAmount: amount(0)}, []money.Amount{80_00, 1}, service.LimitMonthlyAmount},
+		{"verified tier", "IDR", "verified", nil, []money.Amount{60_00, 100_00, 300_00}, ""},
+		{"verified daily amount", "IDR", "verified", nil, []money.Amount{500_01}, service.LimitDailyAmount},
+		{"tier dropped from the config", "IDR", "gold", nil, []money.Amount{50_01}, service.LimitDailyAmount},
+		{"override above the tier", "IDR", "", &entity.WalletLimit{WalletID: 1, DailyAmount: amount(70_00)}, []money.Amount{70_00}, ""},
+		{"override below the tier", "IDR", "verified", &entity.WalletLimit{WalletID: 1, DailyAmount: amount(10_00)}, []money.Amount{10_01}, service.LimitDailyAmount},
+		{"currency without an amount limit", "USD", "", nil, []money.Amount{900_00}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+				1: {Walletid: 1, UserID: 1, Balance: 1000_00, Currency: tt.currency},
+			}}
+			if tt.tier != "" {
+				repo.tiers = map[uint]string{1: tt.tier}
+			}
+			if tt.override != nil {
+				repo.limits = map[int]entity.WalletLimit{1: *tt.override}
+			}
+			svc := service.NewTransactionService(repo, nil, testPolicy, nil)
+
+			var err error
+			spent := money.Amount(0)
+			for _, p := range tt.payments {
+				if _, err = svc.Payment(ctx, 1, p, "", ""); err != nil {
+					break
+				}
+				spent += p
+			}
+			var limitErr *service.LimitError
+			switch {
+			case tt.wantLimit == "" && err != nil:
+				t.Fatalf("Payment() error = %v", err)
+			case tt.wantLimit != "" && (!errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit || !errors.Is(err, service.ErrLimitExceeded)):
+				t.Fatalf("Payment() error = %v, want the %s limit exceeded", err, tt.wantLimit)
+			}
+			if got := repo.balance(t, 1); got != 1000_00-spent {
+				t.Fatalf("wallet holds %s, want %s", got, 1000_00-spent)
+			}
+		})
+	}
+}
+
+func TestSetUserTier(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		policy  service.LimitPolicy
+		tier    string
+		wantErr bool
+	}{
+		{"configured tier", testPolicy, "verified", false},
+		{"default tier", testPolicy, entity.TierUnverified, false},
+		{"unknown tier", testPolicy, "gold", true},
+		{"default tier without a policy", nil, entity.TierUnverified, false},
+		{"any tier without a policy", nil, "gold", false},
+		{"no tier", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepo{}
+			svc := service.NewTransactionService(repo, nil, tt.policy, nil)
+
+			err := svc.SetUserTier(ctx, 1, tt.tier)
+			var validationErr *service.ValidationError
+			if tt.wantErr != errors.As(err, &validationErr) {
+				t.Fatalf("SetUserTier(%q) error = %v, want a validation error: %t", tt.tier, err, tt.wantErr)
+			}
+			if got, _ := repo.GetUserTier(ctx, 1); !tt.wantErr && got != tt.tier {
+				t.Fatalf("user is in tier %q, want %q", got, tt.tier)
+			}
+		})
+	}
+}
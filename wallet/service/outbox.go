@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// WalletBalanceEvent is the payload of WalletCredited events, published when
+// money enters a wallet, and WalletDebited events, published when it leaves.
+// Reason is "topup", "payment" or "transfer".
+type WalletBalanceEvent struct {
+	WalletID      int          `json:"wallet_id"`
+	UserID        uint         `json:"user_id"`
+	TransactionID uint         `json:"transaction_id"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency"`
+	Balance       money.Amount `json:"balance"`
+	Reason        string       `json:"reason"`
+}
+
+// TransferCompletedEvent is the payload of TransferCompleted events,
+// published once per transfer next to the WalletDebited and WalletCredited
+// events of its two legs. TransactionID is the source leg.
+type TransferCompletedEvent struct {
+	FromWalletID      int          `json:"from_wallet_id"`
+	ToWalletID        int          `json:"to_wallet_id"`
+	FromUserID        uint         `json:"from_user_id"`
+	ToUserID          uint         `json:"to_user_id"`
+	TransactionID     uint         `json:"transaction_id"`
+	Amount            money.Amount `json:"amount"`
+	Currency          string       `json:"currency"`
+	ConvertedAmount   money.Amount `json:"converted_amount"`
+	ConvertedCurrency string       `json:"converted_currency"`
+	FxRate            money.Rate   `json:"fx_rate"`
+}
+
+// WalletStatusChangedEvent is the payload of WalletStatusChanged events,
+// published when support freezes, unfreezes or closes a wallet.
+type WalletStatusChangedEvent struct {
+	WalletID   int    `json:"wallet_id"`
+	UserID     uint   `json:"user_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Reason     string `json:"reason"`
+}
+
+// Reasons carried by WalletCredited and WalletDebited events.
+const (
+	ReasonTopUp    = "topup"
+	ReasonPayment  = "payment"
+	ReasonTransfer = "transfer"
+)
+
+// outboxEvent is a domain event about to be stored in the outbox.
+type outboxEvent struct {
+	eventType   string
+	aggregateID int
+	payload     interface{}
+}
+
+// balanceEvent builds a WalletCredited or WalletDebited event for a ledger
+// row, given the wallet balance after the change.
+func balanceEvent(eventType string, wallet entity.Wallet, transaction entity.Transaction, reason string) outboxEvent {
+	return outboxEvent{eventType: eventType, aggregateID: int(wallet.Walletid), payload: WalletBalanceEvent{
+		WalletID:      int(wallet.Walletid),
+		UserID:        wallet.UserID,
+		TransactionID: transaction.TransactionID,
+		Amount:        transaction.Amount,
+		Currency:      wallet.Currency,
+		Balance:       wallet.Balance,
+		Reason:        reason,
+	}}
+}
+
+// enqueue stores domain events in the outbox. It must run in the transaction
+// that made the change the events describe.
+func enqueue(ctx context.Context, repo ITransactionRepository, events ...outboxEvent) error {
+	messages := make([]entity.OutboxMessage, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event.payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", event.eventType, err)
+		}
+		messages = append(messages, entity.OutboxMessage{
+			EventType:   event.eventType,
+			AggregateID: strconv.Itoa(event.aggregateID),
+			Payload:     payload,
+		})
+	}
+	if err := repo.CreateOutboxMessages(ctx, messages); err != nil {
+		return fmt.Errorf("failed to store outbox messages: %w", err)
+	}
+	return nil
+}
+
+// Publisher delivers outbox messages to downstream consumers. Publish must
+// return an error unless the message was accepted; the relay then retries
+// it. Consumers may see a message more than once and should deduplicate by
+// its ID.
+type Publisher interface {
+	Publish(ctx context.Context, message entity.OutboxMessage) error
+}
+
+// OutboxRelayConfig tunes an OutboxRelay.
+type OutboxRelayConfig struct {
+	// PollInterval is how often the relay looks for due messages.
+	PollInterval time.Duration
+	// MaxAttempts is the number of failed deliveries after which a message
+	// is moved to the dead letters.
+	MaxAttempts int
+	// BatchSize is the number of messages claimed at once.
+	BatchSize int
+	// Lease is how long a claimed message is hidden from other relays while
+	// it is being published.
+	Lease time.Duration
+}
+
+// Retry delays grow from minRetryDelay, doubling per attempt, up to
+// maxRetryDelay.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 10 * time.Minute
+)
+
+// retryDelay returns the wait before the next delivery after the given
+// number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// OutboxRelay publishes outbox messages. Several relays may run against the
+// same database: each claims messages with a lease, so a message is handled
+// by one relay at a time and picked up again if that relay dies.
+type OutboxRelay struct {
+	repo      ITransactionRepository
+	publisher Publisher
+	cfg       OutboxRelayConfig
+}
+
+// NewOutboxRelay creates a relay. Zero BatchSize and Lease get defaults.
+func NewOutboxRelay(repo ITransactionRepository, publisher Publisher, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	return &OutboxRelay{repo: repo, publisher: publisher, cfg: cfg}
+}
+
+// Run publishes due messages every PollInterval until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("outbox relay: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce publishes every message that is currently due and returns how many
+// were published.
+func (r *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		messages, err := r.repo.ClaimOutboxMessages(ctx, r.cfg.BatchSize, r.cfg.Lease)
+		if err != nil {
+			return published, fmt.Errorf("failed to claim outbox messages: %w", err)
+		}
+		for _, message := range messages {
+			ok, err := r.deliver(ctx, message)
+			if err != nil {
+				return published, err
+			}
+			if ok {
+				published++
+			}
+		}
+		if len(messages) < r.cfg.BatchSize {
+			return published, nil
+		}
+	}
+}
+
+// deliver publishes one claimed message and records the outcome. It reports
+// whether the message was published; the error is only set when the outcome
+// could not be stored.
+func (r *OutboxRelay) deliver(ctx context.Context, message entity.OutboxMessage) (bool, error) {
+	publishErr := r.publisher.Publish(ctx, message)
+	if publishErr == nil {
+		if err := r.repo.MarkOutboxPublished(ctx, message.ID); err != nil {
+			return false, fmt.Errorf("failed to mark outbox message %d published: %w", message.ID, err)
+		}
+		return true, nil
+	}
+
+	attempts := message.Attempts + 1
+	dead := attempts >= r.cfg.MaxAttempts
+	if dead {
+		log.Printf("outbox relay: message %d (%s) moved to dead letters after %d attempts: %v", message.ID, message.EventType, attempts, publishErr)
+	}
+	if err := r.repo.MarkOutboxFailed(ctx, message.ID, attempts, time.Now().Add(retryDelay(attempts)), publishErr.Error(), dead); err != nil {
+		return false, fmt.Errorf("failed to record outbox message %d failure: %w", message.ID, err)
+	}
+	return false, nil
+}
+
+// DeadLetters returns the messages that exhausted their delivery attempts,
+// oldest first.
+func (r *OutboxRelay) DeadLetters(ctx context.Context) ([]entity.OutboxMessage, error) {
+	messages, err := r.repo.GetDeadOutboxMessages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+// Requeue makes dead messages pending again with a fresh attempt count. A
+// zero id requeues every dead message. It returns the number requeued.
+func (r *OutboxRelay) Requeue(ctx context.Context, id int64) (int64, error) {
+	n, err := r.repo.RequeueOutboxMessages(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue outbox messages: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+)
+
+// WalletBalanceCheck compares a wallet's stored balance with the balance
+// recomputed from its transactions rows ("in" minus "out") and from its
+// journal postings.
+type WalletBalanceCheck struct {
+	WalletID        int          `json:"wallet_id"`
+	StoredBalance   money.Amount `json:"stored_balance"`
+	ComputedBalance money.Amount `json:"computed_balance"`
+	LedgerBalance   money.Amount `json:"ledger_balance"`
+	// Delta is StoredBalance minus ComputedBalance.
+	Delta    money.Amount `json:"delta"`
+	Repaired bool         `json:"repaired"`
+}
+
+// Mismatch reports whether the stored balance disagrees with the
+// transactions rows.
+func (c WalletBalanceCheck) Mismatch() bool {
+	return c.Delta != 0
+}
+
+// ReconciliationService recomputes wallet balances and optionally repairs
+// the stored balance of wallets that drifted
+type ReconciliationService struct {
+	repo ITransactionRepository
+}
+
+// NewReconciliationService creates a new instance of ReconciliationService
+func NewReconciliationService(repo ITransactionRepository) *ReconciliationService {
+	return &ReconciliationService{repo: repo}
+}
+
+// Reconcile checks every wallet. With repair set, each mismatched wallet's
+// stored balance is set to the computed balance, and the correction is
+// posted to the journal against system:reconciliation so the ledger moves
+// with it.
+func (s *ReconciliationService) Reconcile(ctx context.Context, repair bool) ([]WalletBalanceCheck, error) {
+	checks, err := s.repo.CheckWalletBalances(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check wallet balances: %w", err)
+	}
+	if !repair {
+		return checks, nil
+	}
+
+	for i, check := range checks {
+		if !check.Mismatch() {
+			continue
+		}
+		repaired, err := s.repairWallet(ctx, check.WalletID)
+		if err != nil {
+			return checks, fmt.Errorf("failed to repair wallet %d: %w", check.WalletID, err)
+		}
+		checks[i] = repaired
+	}
+	return checks, nil
+}
+
+// repairWallet re-checks a single wallet under a row lock, so concurrent
+// payments cannot change it between the check and the fix.
+func (s *ReconciliationService) repairWallet(ctx context.Context, walletID int) (WalletBalanceCheck, error) {
+	var result WalletBalanceCheck
+	err := s.repo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		wallet, err := repo.GetWalletByIDForUpdate(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		checks, err := repo.CheckWalletBalances(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		if len(checks) != 1 {
+			return fmt.Errorf("wallet %d: %w", walletID, ErrWalletNotFound)
+		}
+		check := checks[0]
+		if !check.Mismatch() {
+			result = check
+			return nil
+		}
+
+		correction := walletCredit(wallet, -check.Delta)
+		system := debit(entity.AccountReconciliation, 0, -check.Delta, wallet.Currency)
+		if check.Delta > 0 {
+			correction = walletDebit(wallet, check.Delta)
+			system = credit(entity.AccountReconciliation, 0, check.Delta, wallet.Currency)
+		}
+		if _, err := postJournal(ctx, repo, entity.EntryTypeAdjustment, correction, system); err != nil {
+			return err
+		}
+
+		wallet.Balance = check.ComputedBalance
+		if err := repo.UpdateWallet(ctx, &wallet); err != nil {
+			return err
+		}
+
+		check.LedgerBalance -= check.Delta
+		check.StoredBalance = check.ComputedBalance
+		check.Delta = 0
+		check.Repaired = true
+		result = check
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+)
+
+// ReasonRefund is carried by the WalletCredited and WalletDebited events of
+// a refund.
+const ReasonRefund = "refund"
+
+// Refund reverses all or part of a payment or a transfer. transactionID is
+// the "out" row of the wallet that paid; amount is in that wallet's currency
+// and 0 refunds whatever is left. A payment is refunded from the merchant
+// settlement account. A transfer is refunded from the destination wallet,
+// converted at the rate of the original transfer, and fails with
+// ErrInsufficientFunds if that wallet no longer holds the money. The
+// returned transaction is the credit to the paying wallet.
+func (s *transactionService) Refund(ctx context.Context, transactionID uint, amount money.Amount, idempotencyKey string) (entity.Transaction, error) {
+	var v validator
+	v.check(transactionID > 0, "transaction_id", "must be a positive transaction ID")
+	v.check(amount >= 0, "amount", "must not be negative")
+	if err := v.err(); err != nil {
+		return entity.Transaction{}, err
+	}
+
+	original, err := s.transactionRepo.GetTransaction(ctx, int32(transactionID))
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if original.TransactionType != "out" || original.RefundOf != nil {
+		return entity.Transaction{}, fmt.Errorf("transaction %d is not a payment or the source of a transfer: %w", transactionID, ErrNotRefundable)
+	}
+
+	request := entity.IdempotencyKey{
+		Operation:            OperationRefund,
+		WalletID:             original.WalletID,
+		CounterpartyWalletID: original.WalletIDSource,
+		RefundOf:             transactionID,
+		Amount:               amount,
+	}
+	transaction, err := s.idempotent(ctx, idempotencyKey, request, func(repo ITransactionRepository) (entity.Transaction, error) {
+		// Lock the original first so concurrent refunds of it see each
+		// other's refunded amount.
+		original, err := repo.GetTransactionForUpdate(ctx, transactionID)
+		if err != nil {
+			return entity.Transaction{}, fmt.Errorf("failed to lock transaction: %w", err)
+		}
+
+		remaining := original.Amount - original.RefundedAmount
+		refund := amount
+		if refund == 0 {
+			refund = remaining
+		}
+		if refund == 0 || refund > remaining {
+			return entity.Transaction{}, fmt.Errorf("%s requested, %s left of transaction %d: %w", refund, remaining, transactionID, ErrRefundExceedsRemaining)
+		}
+
+		if original.WalletIDSource == 0 {
+			return refundPayment(ctx, repo, original, refund)
+		}
+		return refundTransfer(ctx, repo, original, refund, refund == remaining)
+	})
+	if err == nil {
+		if transaction.WalletIDSource != 0 {
+			s.events.notify(transaction.WalletID, transaction.WalletIDSource)
+		} else {
+			s.events.notify(transaction.WalletID)
+		}
+	}
+	return transaction, err
+}
+
+// refundPayment moves refund from the merchant settlement account back to
+// the wallet that made the payment.
+func refundPayment(ctx context.Context, repo ITransactionRepository, original entity.Transaction, refund money.Amount) (entity.Transaction, error) {
+	wallet, err := repo.GetWalletByIDForUpdate(ctx, original.WalletID)
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to retrieve wallet: %w", err)
+	}
+	if err := requireActive(wallet); err != nil {
+		return entity.Transaction{}, err
+	}
+
+	wallet.Balance += refund
+	if err := repo.UpdateWallet(ctx, &wallet); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to update wallet: %w", err)
+	}
+
+	entry, err := postJournal(ctx, repo, entity.EntryTypeRefund,
+		debit(entity.AccountMerchantSettlement, 0, refund, wallet.Currency),
+		walletCredit(wallet, refund),
+	)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+
+	transaction := &entity.Transaction{
+		WalletID:        original.WalletID,
+		WalletIDSource:  0,
+		Amount:          refund,
+		TransactionType: "in",
+		JournalEntryID:  &entry.EntryID,
+		MerchantID:      original.MerchantID,
+		RefundOf:        &original.TransactionID,
+	}
+	noConversion(refund, wallet.Currency).record(transaction)
+	created, err := repo.CreateTransaction(ctx, transaction)
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to create transaction record for refund: %w", err)
+	}
+	if err := repo.AddRefundedAmount(ctx, original.TransactionID, refund); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to update refunded amount: %w", err)
+	}
+
+	if err := repo.CreateWalletEvent(ctx, walletEvent(entity.EventRefundIn, wallet, created)); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to record wallet event: %w", err)
+	}
+	if err := enqueue(ctx, repo, balanceEvent(entity.EventWalletCredited, wallet, created, ReasonRefund)); err != nil {
+		return entity.Transaction{}, err
+	}
+	if err := enqueueWebhook(ctx, repo, entity.WebhookPaymentRefunded, created, wallet.Currency); err != nil {
+		return entity.Transaction{}, err
+	}
+	return created, nil
+}
+
+// refundTransfer moves refund back from the destination of a transfer to its
+// source. The destination gives up the converted amount at the original
+// rate; the last refund of a transfer takes exactly what is left of the
+// credit, so rounding never leaves a remainder behind.
+func refundTransfer(ctx context.Context, repo ITransactionRepository, original entity.Transaction, refund money.Amount, last bool) (entity.Transaction, error) {
+	credited, err := repo.GetTransferCredit(ctx, original)
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to find transfer credit: %w", err)
+	}
+
+	conv := noConversion(refund, original.OriginalCurrency)
+	if original.OriginalCurrency != original.ConvertedCurrency {
+		reverse := credited.Amount - credited.RefundedAmount
+		if !last {
+			if reverse, err = original.FxRate.Convert(refund); err != nil {
+				return entity.Transaction{}, fmt.Errorf("failed to convert refund: %w", err)
+			}
+		}
+		if reverse <= 0 || reverse > credited.Amount-credited.RefundedAmount {
+			var v validator
+			v.check(false, "amount", fmt.Sprintf("converts to %s %s, which cannot be refunded", reverse, original.ConvertedCurrency))
+			return entity.Transaction{}, v.err()
+		}
+		rate, err := original.FxRate.Inverse()
+		if err != nil {
+			return entity.Transaction{}, fmt.Errorf("failed to invert rate: %w", err)
+		}
+		conv = conversion{
+			Original:          reverse,
+			OriginalCurrency:  original.ConvertedCurrency,
+			Converted:         refund,
+			ConvertedCurrency: original.OriginalCurrency,
+			Rate:              rate,
+		}
+	}
+
+	// The refund runs from the destination back to the source.
+	toWallet, fromWallet, err := lockWalletPair(ctx, repo, credited.WalletID, original.WalletID)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	if err := requireActive(toWallet); err != nil {
+		return entity.Transaction{}, err
+	}
+	if err := requireActive(fromWallet); err != nil {
+		return entity.Transaction{}, err
+	}
+	if toWallet.Available() < conv.Original {
+		return entity.Transaction{}, fmt.Errorf("destination wallet: %w", ErrInsufficientFunds)
+	}
+
+	toWallet.Balance -= conv.Original
+	fromWallet.Balance += refund
+	if err := repo.UpdateWallet(ctx, &toWallet); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to update destination wallet: %w", err)
+	}
+	if err := repo.UpdateWallet(ctx, &fromWallet); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to update source wallet: %w", err)
+	}
+
+	postings := []entity.Posting{
+		walletDebit(toWallet, conv.Original),
+		walletCredit(fromWallet, refund),
+	}
+	if toWallet.Currency != fromWallet.Currency {
+		postings = append(postings,
+			credit(entity.AccountFXConversion, 0, conv.Original, toWallet.Currency),
+			debit(entity.AccountFXConversion, 0, refund, fromWallet.Currency),
+		)
+	}
+	entry, err := postJournal(ctx, repo, entity.EntryTypeRefund, postings...)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+
+	transactionOut := &entity.Transaction{
+		WalletID:        credited.WalletID,
+		WalletIDSource:  original.WalletID,
+		Amount:          conv.Original,
+		TransactionType: "out",
+		JournalEntryID:  &entry.EntryID,
+		RefundOf:        &credited.TransactionID,
+	}
+	conv.record(transactionOut)
+	createdOut, err := repo.CreateTransaction(ctx, transactionOut)
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to create transaction record for destination wallet: %w", err)
+	}
+
+	transactionIn := &entity.Transaction{
+		WalletID:        original.WalletID,
+		WalletIDSource:  credited.WalletID,
+		Amount:          refund,
+		TransactionType: "in",
+		JournalEntryID:  &entry.EntryID,
+		RefundOf:        &original.TransactionID,
+	}
+	conv.record(transactionIn)
+	createdIn, err := repo.CreateTransaction(ctx, transactionIn)
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to create transaction record for source wallet: %w", err)
+	}
+
+	if err := repo.AddRefundedAmount(ctx, original.TransactionID, refund); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to update refunded amount: %w", err)
+	}
+	if err := repo.AddRefundedAmount(ctx, credited.TransactionID, conv.Original); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to update refunded amount: %w", err)
+	}
+
+	if err := repo.CreateWalletEvent(ctx, walletEvent(entity.EventRefundOut, toWallet, createdOut)); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to record destination wallet event: %w", err)
+	}
+	if err := repo.CreateWalletEvent(ctx, walletEvent(entity.EventRefundIn, fromWallet, createdIn)); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to record source wallet event: %w", err)
+	}
+	err = enqueue(ctx, repo,
+		balanceEvent(entity.EventWalletDebited, toWallet, createdOut, ReasonRefund),
+		balanceEvent(entity.EventWalletCredited, fromWallet, createdIn, ReasonRefund),
+	)
+	if err != nil {
+		return entity.Transaction{}, err
+	}
+	return createdIn, nil
+}
@@ -0,0 +1,75 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/service"
+	"testing"
+)
+
+func TestRefundPayment(t *testing.T) {
+	ctx := context.Background()
+	// Every case tops wallet 1 up with 100.00 as transaction 1 and pays
+	// 10.00 from it as transaction 2; earlier refunds of the payment follow
+	// as transactions 3 and up.
+	const topUp, payment = 1, 2
+	tests := []struct {
+		name          string
+		earlier       []money.Amount
+		transactionID uint
+		amount        money.Amount
+		wantErr       error
+		wantBalance   money.Amount
+	}{
+		{"whole payment", nil, payment, 0, nil, 100_00},
+		{"part of the payment", nil, payment, 4_00, nil, 94_00},
+		{"rest after a partial refund", []money.Amount{4_00}, payment, 0, nil, 100_00},
+		{"exactly the rest", []money.Amount{4_00}, payment, 6_00, nil, 100_00},
+		{"more than was paid", nil, payment, 10_01, service.ErrRefundExceedsRemaining, 90_00},
+		{"more than is left", []money.Amount{4_00}, payment, 6_01, service.ErrRefundExceedsRemaining, 94_00},
+		{"already refunded", []money.Amount{0}, payment, 0, service.ErrRefundExceedsRemaining, 100_00},
+		{"a top-up", nil, topUp, 0, service.ErrNotRefundable, 90_00},
+		{"a refund", []money.Amount{4_00}, 3, 0, service.ErrNotRefundable, 94_00},
+		{"unknown transaction", nil, 9, 0, service.ErrTransactionNotFound, 90_00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+				1: {Walletid: 1, UserID: 1, Currency: "IDR"},
+			}}
+			svc := service.NewTransactionService(repo, nil, nil, nil)
+			if _, err := svc.TopUp(ctx, 1, 100_00, ""); err != nil {
+				t.Fatalf("TopUp() error = %v", err)
+			}
+			if _, err := svc.Payment(ctx, 1, 10_00, "acme", ""); err != nil {
+				t.Fatalf("Payment() error = %v", err)
+			}
+			for _, amount := range tt.earlier {
+				if _, err := svc.Refund(ctx, payment, amount, ""); err != nil {
+					t.Fatalf("earlier Refund(%s) error = %v", amount, err)
+				}
+			}
+
+			refund, err := svc.Refund(ctx, tt.transactionID, tt.amount, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Refund() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := repo.balance(t, 1); got != tt.wantBalance {
+				t.Fatalf("wallet holds %s, want %s", got, tt.wantBalance)
+			}
+			if err != nil {
+				return
+			}
+			if refund.TransactionType != "in" || refund.RefundOf == nil || *refund.RefundOf != payment || refund.MerchantID != "acme" {
+				t.Fatalf("refund recorded as %+v", refund)
+			}
+			// The wallet held 90.00 after the payment; all it got back since
+			// is counted as refunded.
+			if refunded := repo.transactions[payment-1].RefundedAmount; refunded != tt.wantBalance-90_00 {
+				t.Fatalf("payment has %s refunded, want %s", refunded, tt.wantBalance-90_00)
+			}
+		})
+	}
+}
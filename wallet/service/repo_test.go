@@ -0,0 +1,354 @@
+package service_test
+
+import (
+	"context"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/service"
+	"maps"
+	"sort"
+	"testing"
+	"time"
+)
+
+// ledgerRepo keeps wallets, transactions and journal entries in memory.
+// WithinTx rolls back a failed unit of work by restoring what the
+// repository held before it, so anything written inside a transaction that
+// failed is gone afterwards. Methods a test does not reach are not
+// implemented.
+type ledgerRepo struct {
+	service.ITransactionRepository
+	wallets      map[int]*entity.Wallet
+	transactions []*entity.Transaction
+	entries      []entity.JournalEntry
+	outbox       []entity.OutboxMessage
+	keys         map[string]entity.IdempotencyKey
+	// authorizations[i] has AuthorizationID i+1.
+	authorizations []*entity.Authorization
+	// schedules[i] has ID i+1.
+	schedules []*entity.ScheduledTransfer
+	runs      []entity.ScheduledTransferRun
+	tiers     map[uint]string
+	limits    map[int]entity.WalletLimit
+	audits    []entity.RiskAudit
+	changes   []entity.WalletStatusChange
+}
+
+func (r *ledgerRepo) WithinTx(ctx context.Context, fn func(repo service.ITransactionRepository) error) error {
+	saved := r.snapshot()
+	err := fn(r)
+	if err != nil {
+		*r = saved
+	}
+	return err
+}
+
+// snapshot copies the repository deeply enough that restoring the copy
+// undoes any later write. Appends need no copy: restoring the shorter
+// slice drops them.
+func (r *ledgerRepo) snapshot() ledgerRepo {
+	saved := *r
+	saved.wallets = make(map[int]*entity.Wallet, len(r.wallets))
+	for id, wallet := range r.wallets {
+		stored := *wallet
+		saved.wallets[id] = &stored
+	}
+	saved.transactions = copyAll(r.transactions)
+	saved.authorizations = copyAll(r.authorizations)
+	saved.schedules = copyAll(r.schedules)
+	saved.keys = maps.Clone(r.keys)
+	saved.tiers = maps.Clone(r.tiers)
+	saved.limits = maps.Clone(r.limits)
+	return saved
+}
+
+// copyAll returns a slice pointing to copies of the elements of s.
+func copyAll[T any](s []*T) []*T {
+	copied := make([]*T, len(s))
+	for i, v := range s {
+		stored := *v
+		copied[i] = &stored
+	}
+	return copied
+}
+
+func (r *ledgerRepo) GetWalletByID(ctx context.Context, walletID int) (entity.Wallet, error) {
+	wallet, ok := r.wallets[walletID]
+	if !ok {
+		return entity.Wallet{}, service.ErrWalletNotFound
+	}
+	return *wallet, nil
+}
+
+func (r *ledgerRepo) GetWalletByIDForUpdate(ctx context.Context, walletID int) (entity.Wallet, error) {
+	return r.GetWalletByID(ctx, walletID)
+}
+
+func (r *ledgerRepo) UpdateWallet(ctx context.Context, wallet *entity.Wallet) error {
+	stored := *wallet
+	r.wallets[int(wallet.Walletid)] = &stored
+	return nil
+}
+
+func (r *ledgerRepo) CreateJournalEntry(ctx context.Context, entry *entity.JournalEntry) (entity.JournalEntry, error) {
+	entry.EntryID = uint(len(r.entries) + 1)
+	r.entries = append(r.entries, *entry)
+	return *entry, nil
+}
+
+func (r *ledgerRepo) CreateTransaction(ctx context.Context, transaction *entity.Transaction) (entity.Transaction, error) {
+	stored := *transaction
+	stored.TransactionID = uint(len(r.transactions) + 1)
+	r.transactions = append(r.transactions, &stored)
+	return stored, nil
+}
+
+func (r *ledgerRepo) GetTransaction(ctx context.Context, id int32) (entity.Transaction, error) {
+	if id <= 0 || int(id) > len(r.transactions) {
+		return entity.Transaction{}, service.ErrTransactionNotFound
+	}
+	return *r.transactions[id-1], nil
+}
+
+func (r *ledgerRepo) GetTransactionForUpdate(ctx context.Context, id uint) (entity.Transaction, error) {
+	return r.GetTransaction(ctx, int32(id))
+}
+
+func (r *ledgerRepo) GetTransferCredit(ctx context.Context, debit entity.Transaction) (entity.Transaction, error) {
+	for _, t := range r.transactions {
+		if *t.JournalEntryID == *debit.JournalEntryID && t.WalletID == debit.WalletIDSource && t.TransactionType == "in" {
+			return *t, nil
+		}
+	}
+	return entity.Transaction{}, service.ErrTransactionNotFound
+}
+
+func (r *ledgerRepo) AddRefundedAmount(ctx context.Context, id uint, amount money.Amount) error {
+	r.transactions[id-1].RefundedAmount += amount
+	return nil
+}
+
+func (r *ledgerRepo) GetMerchantWebhook(ctx context.Context, merchantID string) (entity.MerchantWebhook, error) {
+	return entity.MerchantWebhook{}, service.ErrWebhookNotFound
+}
+
+func (r *ledgerRepo) CreateWalletEvent(ctx context.Context, event *entity.WalletEvent) error {
+	return nil
+}
+
+func (r *ledgerRepo) CreateOutboxMessages(ctx context.Context, messages []entity.OutboxMessage) error {
+	r.outbox = append(r.outbox, messages...)
+	return nil
+}
+
+func (r *ledgerRepo) GetIdempotencyKey(ctx context.Context, key string) (entity.IdempotencyKey, bool, error) {
+	stored, ok := r.keys[key]
+	return stored, ok, nil
+}
+
+func (r *ledgerRepo) CreateIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	if _, ok := r.keys[key.Key]; ok {
+		return service.ErrIdempotencyKeyExists
+	}
+	if r.keys == nil {
+		r.keys = map[string]entity.IdempotencyKey{}
+	}
+	r.keys[key.Key] = *key
+	return nil
+}
+
+func (r *ledgerRepo) CreateAuthorization(ctx context.Context, authorization *entity.Authorization) error {
+	authorization.AuthorizationID = uint(len(r.authorizations) + 1)
+	stored := *authorization
+	r.authorizations = append(r.authorizations, &stored)
+	return nil
+}
+
+func (r *ledgerRepo) UpdateAuthorization(ctx context.Context, authorization *entity.Authorization) error {
+	stored := *authorization
+	r.authorizations[authorization.AuthorizationID-1] = &stored
+	return nil
+}
+
+func (r *ledgerRepo) GetAuthorization(ctx context.Context, id uint) (entity.Authorization, error) {
+	if id == 0 || int(id) > len(r.authorizations) {
+		return entity.Authorization{}, service.ErrAuthorizationNotFound
+	}
+	return *r.authorizations[id-1], nil
+}
+
+func (r *ledgerRepo) GetAuthorizationForUpdate(ctx context.Context, id uint) (entity.Authorization, error) {
+	return r.GetAuthorization(ctx, id)
+}
+
+func (r *ledgerRepo) GetAuthorizationByIdempotencyKey(ctx context.Context, key string) (entity.Authorization, bool, error) {
+	for _, a := range r.authorizations {
+		if a.IdempotencyKey != nil && *a.IdempotencyKey == key {
+			return *a, true, nil
+		}
+	}
+	return entity.Authorization{}, false, nil
+}
+
+func (r *ledgerRepo) ClaimExpiredAuthorizations(ctx context.Context, limit int) ([]entity.Authorization, error) {
+	var stale []entity.Authorization
+	for _, a := range r.authorizations {
+		if a.Status == entity.AuthorizationAuthorized && a.ExpiresAt.Before(time.Now()) && len(stale) < limit {
+			stale = append(stale, *a)
+		}
+	}
+	return stale, nil
+}
+
+func (r *ledgerRepo) CreateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error {
+	transfer.ID = int64(len(r.schedules) + 1)
+	stored := *transfer
+	r.schedules = append(r.schedules, &stored)
+	return nil
+}
+
+func (r *ledgerRepo) UpdateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error {
+	stored := *transfer
+	r.schedules[transfer.ID-1] = &stored
+	return nil
+}
+
+func (r *ledgerRepo) GetScheduledTransfer(ctx context.Context, id int64) (entity.ScheduledTransfer, error) {
+	if id <= 0 || int(id) > len(r.schedules) {
+		return entity.ScheduledTransfer{}, service.ErrScheduleNotFound
+	}
+	return *r.schedules[id-1], nil
+}
+
+func (r *ledgerRepo) GetScheduledTransferForUpdate(ctx context.Context, id int64) (entity.ScheduledTransfer, error) {
+	return r.GetScheduledTransfer(ctx, id)
+}
+
+func (r *ledgerRepo) ClaimDueScheduledTransfers(ctx context.Context, limit int, lease time.Duration) ([]entity.ScheduledTransfer, error) {
+	now := time.Now()
+	var due []entity.ScheduledTransfer
+	for _, transfer := range r.schedules {
+		leased := transfer.LeaseUntil != nil && transfer.LeaseUntil.After(now)
+		if transfer.Status == entity.ScheduleActive && !transfer.NextRunAt.After(now) && !leased && len(due) < limit {
+			until := now.Add(lease)
+			transfer.LeaseUntil = &until
+			due = append(due, *transfer)
+		}
+	}
+	return due, nil
+}
+
+func (r *ledgerRepo) CreateScheduledTransferRun(ctx context.Context, run *entity.ScheduledTransferRun) error {
+	run.ID = int64(len(r.runs) + 1)
+	r.runs = append(r.runs, *run)
+	return nil
+}
+
+func (r *ledgerRepo) GetUserTier(ctx context.Context, userID uint) (string, error) {
+	if tier, ok := r.tiers[userID]; ok {
+		return tier, nil
+	}
+	return entity.TierUnverified, nil
+}
+
+func (r *ledgerRepo) SaveUserTier(ctx context.Context, tier *entity.UserTier) error {
+	if r.tiers == nil {
+		r.tiers = map[uint]string{}
+	}
+	r.tiers[tier.UserID] = tier.Tier
+	return nil
+}
+
+func (r *ledgerRepo) GetWalletLimit(ctx context.Context, walletID int) (entity.WalletLimit, bool, error) {
+	limit, ok := r.limits[walletID]
+	return limit, ok, nil
+}
+
+func (r *ledgerRepo) SaveWalletLimit(ctx context.Context, limit *entity.WalletLimit) error {
+	if r.limits == nil {
+		r.limits = map[int]entity.WalletLimit{}
+	}
+	r.limits[limit.WalletID] = *limit
+	return nil
+}
+
+// GetWalletUsage counts every payment and outgoing transfer as made today.
+func (r *ledgerRepo) GetWalletUsage(ctx context.Context, walletID int, day, month time.Time) (service.WalletUsage, error) {
+	var usage service.WalletUsage
+	for _, t := range r.transactions {
+		if t.WalletID == walletID && t.TransactionType == "out" {
+			usage.DailyAmount += t.Amount
+			usage.DailyCount++
+		}
+	}
+	usage.MonthlyAmount, usage.MonthlyCount = usage.DailyAmount, usage.DailyCount
+	return usage, nil
+}
+
+func (r *ledgerRepo) CreateRiskAudit(ctx context.Context, audit *entity.RiskAudit) error {
+	audit.ID = int64(len(r.audits) + 1)
+	r.audits = append(r.audits, *audit)
+	return nil
+}
+
+func (r *ledgerRepo) UpdateWalletStatus(ctx context.Context, wallet *entity.Wallet) error {
+	stored := r.wallets[int(wallet.Walletid)]
+	stored.Status = wallet.Status
+	stored.StatusReason = wallet.StatusReason
+	return nil
+}
+
+func (r *ledgerRepo) CreateWalletStatusChange(ctx context.Context, change *entity.WalletStatusChange) error {
+	change.ID = int64(len(r.changes) + 1)
+	r.changes = append(r.changes, *change)
+	return nil
+}
+
+// GetWalletsByUserID orders the wallets default first, then by ID.
+func (r *ledgerRepo) GetWalletsByUserID(ctx context.Context, userID int) ([]entity.Wallet, error) {
+	var wallets []entity.Wallet
+	for _, wallet := range r.wallets {
+		if int(wallet.UserID) == userID {
+			wallets = append(wallets, *wallet)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		if wallets[i].IsDefault != wallets[j].IsDefault {
+			return wallets[i].IsDefault
+		}
+		return wallets[i].Walletid < wallets[j].Walletid
+	})
+	return wallets, nil
+}
+
+func (r *ledgerRepo) ClearDefaultWallet(ctx context.Context, userID int) error {
+	for _, wallet := range r.wallets {
+		if int(wallet.UserID) == userID {
+			wallet.IsDefault = false
+		}
+	}
+	return nil
+}
+
+func (r *ledgerRepo) SetDefaultWallet(ctx context.Context, walletID int) error {
+	r.wallets[walletID].IsDefault = true
+	return nil
+}
+
+func (r *ledgerRepo) CancelScheduledTransfersByWallet(ctx context.Context, walletID int) (int64, error) {
+	var cancelled int64
+	for _, transfer := range r.schedules {
+		open := transfer.Status == entity.ScheduleActive || transfer.Status == entity.SchedulePaused
+		if open && (transfer.FromWalletID == walletID || transfer.ToWalletID == walletID) {
+			transfer.Status = entity.ScheduleCancelled
+			cancelled++
+		}
+	}
+	return cancelled, nil
+}
+
+// balance returns the stored balance of a wallet.
+func (r *ledgerRepo) balance(t *testing.T, walletID int) money.Amount {
+	t.Helper()
+	return r.wallets[walletID].Balance
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Risk decisions returned by a RiskChecker.
+const (
+	RiskAllow  = "allow"
+	RiskReview = "review"
+	RiskDeny   = "deny"
+)
+
+// ErrRiskDenied is returned when the risk checks deny a top-up, payment or
+// transfer. It deliberately does not say which rule fired.
+var ErrRiskDenied = errors.New("declined by risk checks")
+
+// RiskDeniedError is returned when the risk checks deny an attempt. It
+// wraps ErrRiskDenied and carries the audit of the attempt, which
+// recordDenial stores once the transaction that screened it has ended.
+type RiskDeniedError struct {
+	Audit *entity.RiskAudit
+}
+
+func (e *RiskDeniedError) Error() string {
+	return fmt.Sprintf("%s from wallet %d: %v", e.Audit.Operation, e.Audit.WalletID, ErrRiskDenied)
+}
+
+func (e *RiskDeniedError) Unwrap() error { return ErrRiskDenied }
+
+// RiskAttempt describes a money movement about to be made. Wallet is the
+// wallet whose balance changes first: the source of a transfer, the payer
+// of a payment, the funded wallet of a top-up. It is locked while the
+// checker runs.
+type RiskAttempt struct {
+	// Operation is OperationTransfer, OperationPayment or OperationTopUp.
+	Operation string
+	Wallet    entity.Wallet
+	Amount    money.Amount
+	// Recipient is the wallet or merchant paid; it is zero for a top-up.
+	Recipient RiskRecipient
+	At        time.Time
+}
+
+// RiskRecipient identifies who received an outgoing movement: a wallet for
+// a transfer, a merchant for a payment. An anonymous payment has neither.
+type RiskRecipient struct {
+	WalletID   int
+	MerchantID string
+}
+
+// AmountStats summarizes a set of past movements of a wallet.
+type AmountStats struct {
+	Count int
+	Total money.Amount
+}
+
+// RiskHistory answers questions about a wallet's past activity. A checker
+// receives one reading within the transaction of the movement it screens.
+type RiskHistory interface {
+	// GetRecipientsSince returns the distinct recipients of the payments
+	// and outgoing transfers of a wallet since a time.
+	GetRecipientsSince(ctx context.Context, walletID int, since time.Time) ([]RiskRecipient, error)
+	// GetSpendingStats summarizes the payments and outgoing transfers of a
+	// wallet since a time.
+	GetSpendingStats(ctx context.Context, walletID int, since time.Time) (AmountStats, error)
+	// GetTopUpStats summarizes the top-ups of a wallet since a time.
+	GetTopUpStats(ctx context.Context, walletID int, since time.Time) (AmountStats, error)
+}
+
+// RiskAssessment is the outcome of screening an attempt. Rules names the
+// rules that fired and Reason explains them; both are empty on allow.
+type RiskAssessment struct {
+	Decision string
+	Rules    []string
+	Reason   string
+}
+
+// RiskChecker screens top-ups, payments, including the captures of
+// authorized payments, and transfers before any balance changes. Review
+// lets the movement through but records it for a person to look at; deny
+// rejects it with ErrRiskDenied.
+type RiskChecker interface {
+	Check(ctx context.Context, history RiskHistory, attempt RiskAttempt) (RiskAssessment, error)
+}
+
+// screen runs the risk checker on an attempt, if one is configured. A
+// reviewed attempt is audited with the movement. A denied one is returned
+// as a *RiskDeniedError for the caller to pass to recordDenial after its
+// transaction rolls back; an unknown decision counts as a denial.
+func (s *transactionService) screen(ctx context.Context, repo ITransactionRepository, attempt RiskAttempt) error {
+	if s.risk == nil {
+		return nil
+	}
+	attempt.At = time.Now().UTC()
+	assessment, err := s.risk.Check(ctx, repo, attempt)
+	if err != nil {
+		return fmt.Errorf("failed to run risk checks: %w", err)
+	}
+
+	audit := &entity.RiskAudit{
+		WalletID:             int(attempt.Wallet.Walletid),
+		Operation:            attempt.Operation,
+		Amount:               attempt.Amount,
+		Currency:             attempt.Wallet.Currency,
+		CounterpartyWalletID: attempt.Recipient.WalletID,
+		MerchantID:           attempt.Recipient.MerchantID,
+		Decision:             assessment.Decision,
+		Rules:                strings.Join(assessment.Rules, ","),
+		Reason:               assessment.Reason,
+		CreatedAt:            attempt.At,
+	}
+	switch assessment.Decision {
+	case RiskAllow:
+		return nil
+	case RiskReview:
+		if err := repo.CreateRiskAudit(ctx, audit); err != nil {
+			return fmt.Errorf("failed to record risk review: %w", err)
+		}
+		return nil
+	default:
+		audit.Decision = RiskDeny
+		return &RiskDeniedError{Audit: audit}
+	}
+}
+
+// recordDenial stores the audit carried by a *RiskDeniedError in err and
+// returns err. It must run after the transaction that screened the attempt
+// has ended: the audit references the wallet row that transaction locked,
+// so inserting it from another connection earlier would wait forever.
+func (s *transactionService) recordDenial(ctx context.Context, err error) error {
+	var denied *RiskDeniedError
+	if !errors.As(err, &denied) {
+		return err
+	}
+	if auditErr := s.transactionRepo.CreateRiskAudit(ctx, denied.Audit); auditErr != nil {
+		return fmt.Errorf("%w (failed to record risk denial: %v)", err, auditErr)
+	}
+	return err
+}
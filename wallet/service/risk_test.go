@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/service"
+	"testing"
+)
+
+// fixedRisk returns the same decision for every attempt.
+type fixedRisk struct {
+	decision string
+}
+
+func (f fixedRisk) Check(ctx context.Context, history service.RiskHistory, attempt service.RiskAttempt) (service.RiskAssessment, error) {
+	if f.decision == service.RiskAllow {
+		return service.RiskAssessment{Decision: f.decision}, nil
+	}
+	return service.RiskAssessment{Decision: f.decision, Rules: []string{"velocity"}, Reason: "too many attempts"}, nil
+}
+
+func TestRiskDecisionsAreAudited(t *testing.T) {
+	ctx := context.Background()
+	topUp := func(svc service.ITransactionService) error {
+		_, err := svc.TopUp(ctx, 1, 10_00, "key-1")
+		return err
+	}
+	payment := func(svc service.ITransactionService) error {
+		_, err := svc.Payment(ctx, 1, 10_00, "acme", "key-1")
+		return err
+	}
+	transfer := func(svc service.ITransactionService) error {
+		_, err := svc.TransferWallet(ctx, 1, 2, 10_00, "key-1")
+		return err
+	}
+	capture := func(svc service.ITransactionService) error {
+		if _, err := svc.AuthorizePayment(ctx, 1, 10_00, "acme", 0, ""); err != nil {
+			return err
+		}
+		_, err := svc.CapturePayment(ctx, 1, 0)
+		return err
+	}
+
+	tests := []struct {
+		name      string
+		decision  string
+		operation string
+		attempt   func(service.ITransactionService) error
+		// wantMoved is how much wallet 1 gained, negative when it paid.
+		wantMoved money.Amount
+		wantAudit *entity.RiskAudit
+	}{
+		{"allowed payment", service.RiskAllow, service.OperationPayment, payment, -10_00, nil},
+		{"reviewed payment", service.RiskReview, service.OperationPayment, payment, -10_00,
+			&entity.RiskAudit{MerchantID: "acme", Decision: service.RiskReview}},
+		{"denied payment", service.RiskDeny, service.OperationPayment, payment, 0,
+			&entity.RiskAudit{MerchantID: "acme", Decision: service.RiskDeny}},
+		{"denied top-up", service.RiskDeny, service.OperationTopUp, topUp, 0,
+			&entity.RiskAudit{Decision: service.RiskDeny}},
+		{"denied transfer", service.RiskDeny, service.OperationTransfer, transfer, 0,
+			&entity.RiskAudit{CounterpartyWalletID: 2, Decision: service.RiskDeny}},
+		{"denied capture", service.RiskDeny, service.OperationPayment, capture, 0,
+			&entity.RiskAudit{MerchantID: "acme", Decision: service.RiskDeny}},
+		{"unknown decision", "maybe", service.OperationPayment, payment, 0,
+			&entity.RiskAudit{MerchantID: "acme", Decision: service.RiskDeny}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+				1: {Walletid: 1, UserID: 1, Balance: 100_00, Currency: "IDR"},
+				2: {Walletid: 2, UserID: 2, Currency: "IDR"},
+			}}
+			svc := service.NewTransactionService(repo, nil, nil, fixedRisk{tt.decision})
+
+			err := tt.attempt(svc)
+			denied := tt.wantAudit != nil && tt.wantAudit.Decision == service.RiskDeny
+			if denied != errors.Is(err, service.ErrRiskDenied) || (!denied && err != nil) {
+				t.Fatalf("error = %v, want denied: %t", err, denied)
+			}
+
+			if got := repo.balance(t, 1); got != 100_00+tt.wantMoved {
+				t.Fatalf("wallet holds %s, want %s", got, 100_00+tt.wantMoved)
+			}
+			if denied && (len(repo.transactions) > 0 || len(repo.entries) > 0 || len(repo.outbox) > 0 || len(repo.keys) > 0) {
+				t.Fatalf("denied attempt left %d transactions, %d entries, %d messages and %d keys",
+					len(repo.transactions), len(repo.entries), len(repo.outbox), len(repo.keys))
+			}
+
+			// A denial is audited after its transaction rolled back; had it
+			// been written inside, the rollback would have dropped it.
+			if tt.wantAudit == nil {
+				if len(repo.audits) != 0 {
+					t.Fatalf("allowed attempt audited: %+v", repo.audits)
+				}
+				return
+			}
+			if len(repo.audits) != 1 {
+				t.Fatalf("%d audits recorded, want 1", len(repo.audits))
+			}
+			got := repo.audits[0]
+			if got.WalletID != 1 || got.Operation != tt.operation || got.Amount != 10_00 || got.Currency != "IDR" ||
+				got.CounterpartyWalletID != tt.wantAudit.CounterpartyWalletID || got.MerchantID != tt.wantAudit.MerchantID ||
+				got.Decision != tt.wantAudit.Decision || got.Rules != "velocity" || got.Reason != "too many attempts" {
+				t.Fatalf("audit %+v", got)
+			}
+		})
+	}
+}
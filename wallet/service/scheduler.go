@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/schedule"
+	"ewallet/wallet/entity"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Errors returned by the scheduled transfer methods.
+var (
+	ErrScheduleNotFound = errors.New("scheduled transfer not found")
+	// ErrScheduleClosed is returned when changing a scheduled transfer that
+	// was cancelled or has completed.
+	ErrScheduleClosed = errors.New("scheduled transfer is no longer active")
+)
+
+// Limits on scheduled transfers and their run history.
+const (
+	maxScheduleDescriptionLength = 255
+	maxScheduleSpecLength        = 100
+	DefaultScheduledTransferRuns = 50
+	MaxScheduledTransferRuns     = 500
+)
+
+// SchedulerConfig tunes a SchedulerService.
+type SchedulerConfig struct {
+	// PollInterval is how often the scheduler looks for due transfers.
+	PollInterval time.Duration
+	// BatchSize is the number of schedules claimed at once.
+	BatchSize int
+	// Lease is how long a claimed schedule is hidden from other schedulers
+	// while it runs. A run that fails for a transient reason is retried
+	// once the lease runs out.
+	Lease time.Duration
+}
+
+// SchedulerService manages standing orders and executes them through
+// TransferWallet when they fall due. Several schedulers may run against the
+// same database: each occurrence is transferred at most once, because its
+// transfer carries an idempotency key derived from the schedule and the
+// occurrence, and is recorded exactly once in the run history.
+type SchedulerService struct {
+	repo      ITransactionRepository
+	transfers ITransactionService
+	cfg       SchedulerConfig
+	now       func() time.Time
+}
+
+// NewSchedulerService creates a scheduler. Zero BatchSize and Lease get
+// defaults.
+func NewSchedulerService(repo ITransactionRepository, transfers ITransactionService, cfg SchedulerConfig) *SchedulerService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	return &SchedulerService{repo: repo, transfers: transfers, cfg: cfg, now: time.Now}
+}
+
+// CreateScheduledTransfer stores a standing order from transfer's
+// FromWalletID, ToWalletID, Amount, Schedule, Description and MaxRuns. It
+// first runs at the first occurrence of the schedule after now and belongs
+// to the owner of the source wallet.
+func (s *SchedulerService) CreateScheduledTransfer(ctx context.Context, transfer entity.ScheduledTransfer) (entity.ScheduledTransfer, error) {
+	var v validator
+	v.check(transfer.FromWalletID > 0, "from_wallet_id", "must be a positive wallet ID")
+	v.check(transfer.ToWalletID > 0, "to_wallet_id", "must be a positive wallet ID")
+	v.check(transfer.FromWalletID != transfer.ToWalletID, "to_wallet_id", "must differ from from_wallet_id")
+	v.check(transfer.Amount > 0, "amount", "must be greater than zero")
+	v.check(len(transfer.Description) <= maxScheduleDescriptionLength, "description", fmt.Sprintf("must be at most %d characters", maxScheduleDescriptionLength))
+	v.check(transfer.MaxRuns >= 0, "max_runs", "must not be negative")
+	sched, err := schedule.Parse(transfer.Schedule)
+	if len(transfer.Schedule) > maxScheduleSpecLength {
+		v.check(false, "schedule", fmt.Sprintf("must be at most %d characters", maxScheduleSpecLength))
+	} else if err != nil {
+		v.check(false, "schedule", err.Error())
+	}
+	if err := v.err(); err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+
+	next := sched.Next(s.now())
+	if next.IsZero() {
+		v.check(false, "schedule", "never falls due")
+		return entity.ScheduledTransfer{}, v.err()
+	}
+
+	from, err := s.repo.GetWalletByID(ctx, transfer.FromWalletID)
+	if err != nil {
+		return entity.ScheduledTransfer{}, fmt.Errorf("failed to retrieve source wallet: %w", err)
+	}
+	to, err := s.repo.GetWalletByID(ctx, transfer.ToWalletID)
+	if err != nil {
+		return entity.ScheduledTransfer{}, fmt.Errorf("failed to retrieve destination wallet: %w", err)
+	}
+	if err := requireActive(from); err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+	if err := requireActive(to); err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+
+	created := &entity.ScheduledTransfer{
+		UserID:       from.UserID,
+		FromWalletID: transfer.FromWalletID,
+		ToWalletID:   transfer.ToWalletID,
+		Amount:       transfer.Amount,
+		Schedule:     transfer.Schedule,
+		Description:  transfer.Description,
+		Status:       entity.ScheduleActive,
+		NextRunAt:    next,
+		MaxRuns:      transfer.MaxRuns,
+	}
+	if err := s.repo.CreateScheduledTransfer(ctx, created); err != nil {
+		return entity.ScheduledTransfer{}, fmt.Errorf("failed to create scheduled transfer: %w", err)
+	}
+	return *created, nil
+}
+
+// GetScheduledTransfer retrieves a scheduled transfer by its ID.
+func (s *SchedulerService) GetScheduledTransfer(ctx context.Context, id int64) (entity.ScheduledTransfer, error) {
+	transfer, err := s.repo.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		return entity.ScheduledTransfer{}, fmt.Errorf("failed to get scheduled transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// GetScheduledTransfersByUserID returns every scheduled transfer of the
+// user, including cancelled and completed ones, newest first.
+func (s *SchedulerService) GetScheduledTransfersByUserID(ctx context.Context, userID int) ([]entity.ScheduledTransfer, error) {
+	var v validator
+	v.check(userID > 0, "user_id", "must be a positive user ID")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	transfers, err := s.repo.GetScheduledTransfersByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+// PauseScheduledTransfer stops an active schedule from running until it is
+// resumed. A run that has already started still completes. Pausing a paused
+// schedule returns it unchanged.
+func (s *SchedulerService) PauseScheduledTransfer(ctx context.Context, id int64) (entity.ScheduledTransfer, error) {
+	return s.changeStatus(ctx, id, entity.SchedulePaused)
+}
+
+// ResumeScheduledTransfer reactivates a paused schedule. Occurrences that
+// fell due while it was paused are skipped: it next runs at its first
+// occurrence after now. Resuming an active schedule returns it unchanged.
+func (s *SchedulerService) ResumeScheduledTransfer(ctx context.Context, id int64) (entity.ScheduledTransfer, error) {
+	return s.changeStatus(ctx, id, entity.ScheduleActive)
+}
+
+// CancelScheduledTransfer ends a schedule for good; its run history is
+// kept. Cancelling a cancelled schedule returns it unchanged.
+func (s *SchedulerService) CancelScheduledTransfer(ctx context.Context, id int64) (entity.ScheduledTransfer, error) {
+	return s.changeStatus(ctx, id, entity.ScheduleCancelled)
+}
+
+// changeStatus locks a schedule and moves it to status. Cancelled and
+// completed schedules cannot be changed.
+func (s *SchedulerService) changeStatus(ctx context.Context, id int64, status string) (entity.ScheduledTransfer, error) {
+	var v validator
+	v.check(id > 0, "schedule_id", "must be a positive schedule ID")
+	if err := v.err(); err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+
+	var result entity.ScheduledTransfer
+	err := s.repo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		transfer, err := repo.GetScheduledTransferForUpdate(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to lock scheduled transfer: %w", err)
+		}
+		switch transfer.Status {
+		case status:
+			result = transfer
+			return nil
+		case entity.ScheduleCancelled, entity.ScheduleCompleted:
+			return fmt.Errorf("scheduled transfer %d is %s: %w", id, transfer.Status, ErrScheduleClosed)
+		}
+
+		if status == entity.ScheduleActive {
+			now := s.now()
+			if transfer.NextRunAt.Before(now) {
+				sched, err := schedule.Parse(transfer.Schedule)
+				if err != nil {
+					return fmt.Errorf("scheduled transfer %d: %w", id, err)
+				}
+				transfer.NextRunAt = sched.Next(now)
+			}
+		}
+		transfer.Status = status
+		if err := repo.UpdateScheduledTransfer(ctx, &transfer); err != nil {
+			return fmt.Errorf("failed to update scheduled transfer: %w", err)
+		}
+		result = transfer
+		return nil
+	})
+	if err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+	return result, nil
+}
+
+// GetScheduledTransferRuns returns the most recent runs of a schedule,
+// newest first. A zero limit means DefaultScheduledTransferRuns.
+func (s *SchedulerService) GetScheduledTransferRuns(ctx context.Context, id int64, limit int) ([]entity.ScheduledTransferRun, error) {
+	if limit == 0 {
+		limit = DefaultScheduledTransferRuns
+	}
+	var v validator
+	v.check(id > 0, "schedule_id", "must be a positive schedule ID")
+	v.check(limit > 0 && limit <= MaxScheduledTransferRuns, "limit", fmt.Sprintf("must be between 1 and %d", MaxScheduledTransferRuns))
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetScheduledTransfer(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfer: %w", err)
+	}
+	runs, err := s.repo.GetScheduledTransferRuns(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfer runs: %w", err)
+	}
+	return runs, nil
+}
+
+// Run executes due transfers every PollInterval until ctx is done.
+func (s *SchedulerService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce executes every transfer that is currently due and returns how many
+// runs were recorded, successful or not.
+func (s *SchedulerService) RunOnce(ctx context.Context) (int, error) {
+	recorded := 0
+	for {
+		due, err := s.repo.ClaimDueScheduledTransfers(ctx, s.cfg.BatchSize, s.cfg.Lease)
+		if err != nil {
+			return recorded, fmt.Errorf("failed to claim scheduled transfers: %w", err)
+		}
+		for _, transfer := range due {
+			ok, err := s.execute(ctx, transfer)
+			if err != nil {
+				return recorded, err
+			}
+			if ok {
+				recorded++
+			}
+		}
+		if len(due) < s.cfg.BatchSize {
+			return recorded, nil
+		}
+	}
+}
+
+// execute transfers one claimed occurrence and records the run. A transfer
+// refused for a business reason, such as insufficient funds, is recorded as
+// a failed run and the schedule moves on; any other error leaves the
+// occurrence due, to be retried when the lease runs out. It reports whether
+// a run was recorded; the error is only set when it could not be stored.
+func (s *SchedulerService) execute(ctx context.Context, transfer entity.ScheduledTransfer) (bool, error) {
+	occurrence := transfer.NextRunAt
+	key := fmt.Sprintf("schedule:%d:%d", transfer.ID, occurrence.Unix())
+
+	run := &entity.ScheduledTransferRun{
+		ScheduleID:   transfer.ID,
+		ScheduledFor: occurrence,
+		Status:       entity.ScheduleRunSucceeded,
+	}
+	created, err := s.transfers.TransferWallet(ctx, transfer.FromWalletID, transfer.ToWalletID, transfer.Amount, key)
+	switch {
+	case err == nil:
+		run.TransactionID = &created.TransactionID
+	case isRunFailure(err):
+		run.Status = entity.ScheduleRunFailed
+		run.Error = err.Error()
+	default:
+		log.Printf("scheduler: scheduled transfer %d due at %s will be retried: %v", transfer.ID, occurrence.Format(time.RFC3339), err)
+		return false, nil
+	}
+
+	recorded := false
+	err = s.repo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		locked, err := repo.GetScheduledTransferForUpdate(ctx, transfer.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock scheduled transfer: %w", err)
+		}
+		if !locked.NextRunAt.Equal(occurrence) {
+			// Another scheduler recorded this occurrence after our lease
+			// ran out.
+			return nil
+		}
+
+		if err := repo.CreateScheduledTransferRun(ctx, run); err != nil {
+			return fmt.Errorf("failed to record run: %w", err)
+		}
+		s.advance(&locked, occurrence)
+		if err := repo.UpdateScheduledTransfer(ctx, &locked); err != nil {
+			return fmt.Errorf("failed to update scheduled transfer: %w", err)
+		}
+		recorded = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("scheduled transfer %d: %w", transfer.ID, err)
+	}
+	return recorded, nil
+}
+
+// advance moves a schedule past the occurrence that just ran. A schedule
+// that fell behind, for instance because the scheduler was down, runs once
+// to catch up and then skips to its next occurrence after now. It completes
+// after MaxRuns runs or when no occurrence is left.
+func (s *SchedulerService) advance(transfer *entity.ScheduledTransfer, occurrence time.Time) {
+	now := s.now()
+	transfer.RunCount++
+	transfer.LastRunAt = &now
+	transfer.LeaseUntil = nil
+
+	var next time.Time
+	if sched, err := schedule.Parse(transfer.Schedule); err == nil {
+		next = sched.Next(occurrence)
+		if !next.After(now) {
+			next = sched.Next(now)
+		}
+	}
+	if next.IsZero() || (transfer.MaxRuns > 0 && transfer.RunCount >= transfer.MaxRuns) {
+		if transfer.Status != entity.ScheduleCancelled {
+			transfer.Status = entity.ScheduleCompleted
+		}
+		return
+	}
+	transfer.NextRunAt = next
+}
+
+// isRunFailure reports whether a transfer error means this occurrence
+// cannot succeed, as opposed to a transient failure worth retrying.
+func isRunFailure(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr) ||
+		errors.Is(err, ErrWalletNotFound) ||
+		errors.Is(err, ErrInsufficientFunds) ||
+		errors.Is(err, ErrLimitExceeded) ||
+		errors.Is(err, ErrRiskDenied) ||
+		errors.Is(err, ErrWalletFrozen) ||
+		errors.Is(err, ErrWalletClosed) ||
+		errors.Is(err, ErrRateUnavailable) ||
+		errors.Is(err, ErrIdempotencyKeyReused)
+}
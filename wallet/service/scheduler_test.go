@@ -0,0 +1,123 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/service"
+	"testing"
+	"time"
+)
+
+// flakyTransfers fails the first transfers it is asked for with a
+// transient error. Up to lost of them still move the money first, as when
+// the reply of a committed transfer is lost.
+type flakyTransfers struct {
+	service.ITransactionService
+	failures int
+	lost     int
+}
+
+func (f *flakyTransfers) TransferWallet(ctx context.Context, fromWalletID, toWalletID int, amount money.Amount, idempotencyKey string) (entity.Transaction, error) {
+	if f.failures == 0 {
+		return f.ITransactionService.TransferWallet(ctx, fromWalletID, toWalletID, amount, idempotencyKey)
+	}
+	f.failures--
+	if f.lost > 0 {
+		f.lost--
+		if _, err := f.ITransactionService.TransferWallet(ctx, fromWalletID, toWalletID, amount, idempotencyKey); err != nil {
+			return entity.Transaction{}, err
+		}
+	}
+	return entity.Transaction{}, errors.New("connection reset")
+}
+
+func TestSchedulerRunOnce(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		balance  money.Amount
+		status   string
+		maxRuns  int
+		failures int
+		lost     int
+		// wantRecorded is what each call of RunOnce records.
+		wantRecorded []int
+		wantRuns     []string
+		wantBalance  money.Amount
+		wantStatus   string
+	}{
+		{"due", 100_00, entity.ScheduleActive, 0, 0, 0, []int{1, 0}, []string{entity.ScheduleRunSucceeded}, 90_00, entity.ScheduleActive},
+		{"last run", 100_00, entity.ScheduleActive, 1, 0, 0, []int{1}, []string{entity.ScheduleRunSucceeded}, 90_00, entity.ScheduleCompleted},
+		{"insufficient funds", 5_00, entity.ScheduleActive, 0, 0, 0, []int{1, 0}, []string{entity.ScheduleRunFailed}, 5_00, entity.ScheduleActive},
+		{"paused", 100_00, entity.SchedulePaused, 0, 0, 0, []int{0}, nil, 100_00, entity.SchedulePaused},
+		{"transient failure", 100_00, entity.ScheduleActive, 0, 1, 0, []int{0, 1, 0}, []string{entity.ScheduleRunSucceeded}, 90_00, entity.ScheduleActive},
+		{"lost reply", 100_00, entity.ScheduleActive, 0, 1, 1, []int{0, 1, 0}, []string{entity.ScheduleRunSucceeded}, 90_00, entity.ScheduleActive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+				1: {Walletid: 1, UserID: 1, Balance: tt.balance, Currency: "IDR"},
+				2: {Walletid: 2, UserID: 2, Currency: "IDR"},
+			}}
+			transfers := &flakyTransfers{
+				ITransactionService: service.NewTransactionService(repo, nil, nil, nil),
+				failures:            tt.failures,
+				lost:                tt.lost,
+			}
+			// A lease of a nanosecond has run out by the next call, so a
+			// transient failure is retried at once.
+			scheduler := service.NewSchedulerService(repo, transfers, service.SchedulerConfig{Lease: time.Nanosecond})
+
+			due := time.Now().Add(-time.Minute).Truncate(time.Second)
+			repo.schedules = []*entity.ScheduledTransfer{{
+				ID:           1,
+				UserID:       1,
+				FromWalletID: 1,
+				ToWalletID:   2,
+				Amount:       10_00,
+				Schedule:     "@every 24h",
+				Status:       tt.status,
+				NextRunAt:    due,
+				MaxRuns:      tt.maxRuns,
+			}}
+
+			for i, want := range tt.wantRecorded {
+				if got, err := scheduler.RunOnce(ctx); err != nil || got != want {
+					t.Fatalf("RunOnce() call %d = %d, %v; want %d", i+1, got, err, want)
+				}
+			}
+
+			if len(repo.runs) != len(tt.wantRuns) {
+				t.Fatalf("%d runs recorded, want %d", len(repo.runs), len(tt.wantRuns))
+			}
+			for i, run := range repo.runs {
+				if run.Status != tt.wantRuns[i] || !run.ScheduledFor.Equal(due) {
+					t.Fatalf("run %d %s for %s, want %s for %s", i+1, run.Status, run.ScheduledFor, tt.wantRuns[i], due)
+				}
+				if succeeded := run.Status == entity.ScheduleRunSucceeded; succeeded != (run.TransactionID != nil) || succeeded == (run.Error != "") {
+					t.Fatalf("run %d %s with transaction %v and error %q", i+1, run.Status, run.TransactionID, run.Error)
+				}
+			}
+			if got := repo.balance(t, 1); got != tt.wantBalance {
+				t.Fatalf("source wallet holds %s, want %s", got, tt.wantBalance)
+			}
+			if got := repo.balance(t, 2); got != tt.balance-tt.wantBalance {
+				t.Fatalf("destination wallet holds %s, want %s", got, tt.balance-tt.wantBalance)
+			}
+
+			schedule := repo.schedules[0]
+			if schedule.Status != tt.wantStatus || schedule.RunCount != len(tt.wantRuns) {
+				t.Fatalf("schedule %s after %d runs, want %s after %d", schedule.Status, schedule.RunCount, tt.wantStatus, len(tt.wantRuns))
+			}
+			wantNext := due
+			if len(tt.wantRuns) > 0 && tt.wantStatus == entity.ScheduleActive {
+				wantNext = due.Add(24 * time.Hour)
+			}
+			if !schedule.NextRunAt.Equal(wantNext) {
+				t.Fatalf("schedule next runs at %s, want %s", schedule.NextRunAt, wantNext)
+			}
+		})
+	}
+}
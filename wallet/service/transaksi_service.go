@@ -2,58 +2,280 @@ package service
 
 import (
 	"context"
+	"ewallet/pkg/money"
 	"ewallet/wallet/entity"
 	"fmt"
+	"time"
 )
 
 // ITransactionService defines the interface for transaction services
 type ITransactionService interface {
-	CreateTransaction(ctx context.Context, transaction *entity.Transaction) (entity.Transaction, error)
 	GetTransaction(ctx context.Context, id int32) (entity.Transaction, error)
 	CreateWallet(ctx context.Context, wallet *entity.Wallet) (entity.Wallet, error)
-	TransferWallet(ctx context.Context, fromWalletID, toWalletID int, amount float64) error
-	TopUp(ctx context.Context, walletID int, amount float64) error
-	Payment(ctx context.Context, walletID int, amount float64) error
+	// TransferWallet, TopUp and Payment accept an optional idempotency key;
+	// replaying a key returns the originally recorded transaction.
+	TransferWallet(ctx context.Context, fromWalletID, toWalletID int, amount money.Amount, idempotencyKey string) (entity.Transaction, error)
+	TopUp(ctx context.Context, walletID int, amount money.Amount, idempotencyKey string) (entity.Transaction, error)
+	// Payment pays merchantID, which may be empty for an anonymous merchant.
+	Payment(ctx context.Context, walletID int, amount money.Amount, merchantID, idempotencyKey string) (entity.Transaction, error)
+	// AuthorizePayment holds amount on a wallet for a later capture.
+	AuthorizePayment(ctx context.Context, walletID int, amount money.Amount, merchantID string, ttl time.Duration, idempotencyKey string) (entity.Authorization, error)
+	// CapturePayment pays all or part of an authorization and releases the
+	// rest of its hold.
+	CapturePayment(ctx context.Context, authorizationID uint, amount money.Amount) (entity.Transaction, error)
+	// VoidAuthorization releases an authorization's hold without paying.
+	VoidAuthorization(ctx context.Context, authorizationID uint) (entity.Authorization, error)
+	GetAuthorization(ctx context.Context, authorizationID uint) (entity.Authorization, error)
+	// ExpireAuthorizations releases the holds of authorizations past their
+	// expiry time and returns how many expired.
+	ExpireAuthorizations(ctx context.Context) (int, error)
+	// Refund reverses all or, with a non-zero amount, part of a payment or a
+	// transfer identified by its debit row.
+	Refund(ctx context.Context, transactionID uint, amount money.Amount, idempotencyKey string) (entity.Transaction, error)
+	// GetWalletLimits returns the spending limits in force on a wallet and
+	// its usage in the current UTC day and month.
+	GetWalletLimits(ctx context.Context, walletID int) (WalletLimits, error)
+	// SetUserTier assigns the limit tier of all of a user's wallets.
+	SetUserTier(ctx context.Context, userID uint, tier string) error
+	// SetWalletLimits replaces a wallet's overrides of its tier limits.
+	SetWalletLimits(ctx context.Context, limit entity.WalletLimit) (WalletLimits, error)
+	// SetWalletStatus freezes or unfreezes a wallet, recording who did it
+	// and why.
+	SetWalletStatus(ctx context.Context, walletID int, status, reason string, changedBy uint) (entity.Wallet, error)
+	// CloseWallet closes a wallet for good, first sweeping any balance to
+	// sweepToWalletID when it is non-zero.
+	CloseWallet(ctx context.Context, walletID, sweepToWalletID int, reason string, changedBy uint) (entity.Wallet, error)
 	GetWalletByID(ctx context.Context, walletID int) (entity.Wallet, error)
+	// GetWalletsByIDs returns the wallets among walletIDs that exist, in no
+	// particular order.
+	GetWalletsByIDs(ctx context.Context, walletIDs []int) ([]entity.Wallet, error)
+	// GetWalletByUserID returns the user's default wallet.
 	GetWalletByUserID(ctx context.Context, userID int) (entity.Wallet, error)
-	GetTransactionByUserID(ctx context.Context, userID int) ([]entity.Transaction, error)
+	// GetWalletsByUserID returns every wallet of the user, default first.
+	GetWalletsByUserID(ctx context.Context, userID int) ([]entity.Wallet, error)
+	// GetTransactionByUserID returns one page of the user's transaction
+	// history matching query.
+	GetTransactionByUserID(ctx context.Context, userID int, query TransactionQuery) (TransactionPage, error)
+	// GetLedgerBalance derives a wallet's balance from its journal postings.
+	GetLedgerBalance(ctx context.Context, walletID int) (money.Amount, error)
+	// SubscribeWalletEvents sends the wallet's events after afterEventID,
+	// then every new event, until ctx is done or send fails.
+	SubscribeWalletEvents(ctx context.Context, walletID int, afterEventID int64, send func(entity.WalletEvent) error) error
 }
 
 // ITransactionRepository defines the interface for transaction repositories
 type ITransactionRepository interface {
 	CreateTransaction(ctx context.Context, transaction *entity.Transaction) (entity.Transaction, error)
 	GetTransaction(ctx context.Context, id int32) (entity.Transaction, error)
+	// GetTransactionForUpdate is like GetTransaction but locks the row until
+	// the enclosing transaction commits or rolls back.
+	GetTransactionForUpdate(ctx context.Context, id uint) (entity.Transaction, error)
+	// GetTransferCredit returns the "in" row recorded with the "out" row of a
+	// transfer.
+	GetTransferCredit(ctx context.Context, debit entity.Transaction) (entity.Transaction, error)
+	// AddRefundedAmount adds amount to the refunded amount of a transaction.
+	AddRefundedAmount(ctx context.Context, id uint, amount money.Amount) error
 	CreateWallet(ctx context.Context, wallet *entity.Wallet) (entity.Wallet, error)
 	GetWalletByID(ctx context.Context, walletID int) (entity.Wallet, error)
+	GetWalletsByIDs(ctx context.Context, walletIDs []int) ([]entity.Wallet, error)
+	// GetWalletByIDForUpdate is like GetWalletByID but locks the row until the
+	// enclosing transaction commits or rolls back.
+	GetWalletByIDForUpdate(ctx context.Context, walletID int) (entity.Wallet, error)
 	UpdateWallet(ctx context.Context, wallet *entity.Wallet) error
+	// UpdateWalletStatus stores the status and status reason of a wallet.
+	UpdateWalletStatus(ctx context.Context, wallet *entity.Wallet) error
+	CreateWalletStatusChange(ctx context.Context, change *entity.WalletStatusChange) error
 	GetWalletByUserID(ctx context.Context, userID int) (entity.Wallet, error)
-	GetTransactionByUserID(ctx context.Context, userID int) ([]entity.Transaction, error)
+	GetWalletsByUserID(ctx context.Context, userID int) ([]entity.Wallet, error)
+	// ClearDefaultWallet unsets the default flag on every wallet of userID.
+	ClearDefaultWallet(ctx context.Context, userID int) error
+	// SetDefaultWallet sets the default flag on a wallet; the caller clears
+	// it on the owner's other wallets first.
+	SetDefaultWallet(ctx context.Context, walletID int) error
+	// GetTransactionByUserID returns up to filter.Limit transactions of the
+	// user's wallets matching filter, newest first by created_at and then
+	// transaction ID, starting after filter.After when it is set.
+	GetTransactionByUserID(ctx context.Context, userID int, filter TransactionFilter) ([]entity.Transaction, error)
+	// CreateJournalEntry stores a journal entry together with its postings.
+	CreateJournalEntry(ctx context.Context, entry *entity.JournalEntry) (entity.JournalEntry, error)
+	// GetLedgerBalance sums the credits minus the debits posted to a wallet.
+	GetLedgerBalance(ctx context.Context, walletID int) (money.Amount, error)
+	// CheckWalletBalances compares stored wallet balances with the balances
+	// recomputed from transactions rows and postings, ordered by wallet ID.
+	// When walletID is non-zero only that wallet is checked.
+	CheckWalletBalances(ctx context.Context, walletID int) ([]WalletBalanceCheck, error)
+	// CreateWalletEvent stores an event; it must run in the transaction that
+	// made the change it records.
+	CreateWalletEvent(ctx context.Context, event *entity.WalletEvent) error
+	// GetWalletEvents returns up to limit events of walletID after
+	// afterEventID, oldest first.
+	GetWalletEvents(ctx context.Context, walletID int, afterEventID int64, limit int) ([]entity.WalletEvent, error)
+	// GetLatestWalletEventID returns the ID of the wallet's newest event, or
+	// 0 if it has none.
+	GetLatestWalletEventID(ctx context.Context, walletID int) (int64, error)
+	// CreateOutboxMessages stores outbox messages; it must run in the
+	// transaction that made the change they describe.
+	CreateOutboxMessages(ctx context.Context, messages []entity.OutboxMessage) error
+	// ClaimOutboxMessages returns up to limit pending messages that are due,
+	// oldest first, and hides them from other callers for lease.
+	ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxMessage, error)
+	// MarkOutboxPublished records a successful delivery.
+	MarkOutboxPublished(ctx context.Context, id int64) error
+	// MarkOutboxFailed records a failed delivery: the message is retried at
+	// nextAttemptAt, or moved to the dead letters when dead is set.
+	MarkOutboxFailed(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error
+	// GetDeadOutboxMessages returns the dead letters, oldest first.
+	GetDeadOutboxMessages(ctx context.Context) ([]entity.OutboxMessage, error)
+	// RequeueOutboxMessages makes the dead message id, or every dead message
+	// when id is 0, pending again. It returns the number requeued.
+	RequeueOutboxMessages(ctx context.Context, id int64) (int64, error)
+	// SaveMerchantWebhook creates or replaces a merchant's endpoint.
+	SaveMerchantWebhook(ctx context.Context, webhook *entity.MerchantWebhook) error
+	// GetMerchantWebhook returns ErrWebhookNotFound when the merchant has no
+	// endpoint.
+	GetMerchantWebhook(ctx context.Context, merchantID string) (entity.MerchantWebhook, error)
+	// CreateWebhookDelivery stores a delivery; it must run in the
+	// transaction that made the change it reports.
+	CreateWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// ClaimWebhookDeliveries returns up to limit pending deliveries that are
+	// due, oldest first, and hides them from other callers for lease.
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error)
+	// MarkWebhookDelivered records an acknowledged delivery.
+	MarkWebhookDelivered(ctx context.Context, id int64, statusCode int) error
+	// MarkWebhookFailed records a failed attempt: the delivery is retried at
+	// nextAttemptAt, or marked failed when failed is set.
+	MarkWebhookFailed(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, statusCode int, lastError string, failed bool) error
+	// GetWebhookDeliveries returns up to limit deliveries of merchantID,
+	// newest first.
+	GetWebhookDeliveries(ctx context.Context, merchantID string, limit int) ([]entity.WebhookDelivery, error)
+	// ResetWebhookDelivery makes a delivery pending and due again with no
+	// attempts, returning ErrWebhookDeliveryNotFound if it does not exist.
+	ResetWebhookDelivery(ctx context.Context, id int64) (entity.WebhookDelivery, error)
+	CreateAuthorization(ctx context.Context, authorization *entity.Authorization) error
+	// UpdateAuthorization stores the status, captured amount and capture
+	// transaction of an authorization.
+	UpdateAuthorization(ctx context.Context, authorization *entity.Authorization) error
+	GetAuthorization(ctx context.Context, id uint) (entity.Authorization, error)
+	// GetAuthorizationForUpdate is like GetAuthorization but locks the row
+	// until the enclosing transaction commits or rolls back.
+	GetAuthorizationForUpdate(ctx context.Context, id uint) (entity.Authorization, error)
+	// GetAuthorizationByIdempotencyKey reports whether an authorization was
+	// created with key and returns it.
+	GetAuthorizationByIdempotencyKey(ctx context.Context, key string) (entity.Authorization, bool, error)
+	// ClaimExpiredAuthorizations locks and returns up to limit open
+	// authorizations past their expiry time, skipping rows locked by others.
+	ClaimExpiredAuthorizations(ctx context.Context, limit int) ([]entity.Authorization, error)
+	CreateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error
+	// UpdateScheduledTransfer stores the status, next and last run times,
+	// run count and lease of a scheduled transfer.
+	UpdateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error
+	// GetScheduledTransfer returns ErrScheduleNotFound when id does not
+	// exist.
+	GetScheduledTransfer(ctx context.Context, id int64) (entity.ScheduledTransfer, error)
+	// GetScheduledTransferForUpdate is like GetScheduledTransfer but locks
+	// the row until the enclosing transaction commits or rolls back.
+	GetScheduledTransferForUpdate(ctx context.Context, id int64) (entity.ScheduledTransfer, error)
+	// GetScheduledTransfersByUserID returns the user's scheduled transfers,
+	// newest first.
+	GetScheduledTransfersByUserID(ctx context.Context, userID int) ([]entity.ScheduledTransfer, error)
+	// ClaimDueScheduledTransfers returns up to limit active schedules that
+	// are due, earliest first, and hides them from other callers for lease.
+	ClaimDueScheduledTransfers(ctx context.Context, limit int, lease time.Duration) ([]entity.ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, run *entity.ScheduledTransferRun) error
+	// GetScheduledTransferRuns returns up to limit runs of a schedule,
+	// newest first.
+	GetScheduledTransferRuns(ctx context.Context, scheduleID int64, limit int) ([]entity.ScheduledTransferRun, error)
+	// CancelScheduledTransfersByWallet cancels the active and paused
+	// schedules from or to a wallet and returns how many it cancelled.
+	CancelScheduledTransfersByWallet(ctx context.Context, walletID int) (int64, error)
+	// GetUserTier returns the user's limit tier, entity.TierUnverified when
+	// none was assigned.
+	GetUserTier(ctx context.Context, userID uint) (string, error)
+	// SaveUserTier creates or replaces a user's tier.
+	SaveUserTier(ctx context.Context, tier *entity.UserTier) error
+	// GetWalletLimit reports whether a wallet has limit overrides and
+	// returns them.
+	GetWalletLimit(ctx context.Context, walletID int) (entity.WalletLimit, bool, error)
+	// SaveWalletLimit creates or replaces a wallet's limit overrides.
+	SaveWalletLimit(ctx context.Context, limit *entity.WalletLimit) error
+	// GetWalletUsage sums the payments and outgoing transfers of a wallet
+	// since day and since month.
+	GetWalletUsage(ctx context.Context, walletID int, day, month time.Time) (WalletUsage, error)
+	// GetRecipientsSince, GetSpendingStats and GetTopUpStats read the
+	// history the risk checks screen a movement against.
+	GetRecipientsSince(ctx context.Context, walletID int, since time.Time) ([]RiskRecipient, error)
+	GetSpendingStats(ctx context.Context, walletID int, since time.Time) (AmountStats, error)
+	GetTopUpStats(ctx context.Context, walletID int, since time.Time) (AmountStats, error)
+	// CreateRiskAudit records an attempt the risk checks denied or flagged.
+	CreateRiskAudit(ctx context.Context, audit *entity.RiskAudit) error
+	// GetIdempotencyKey reports whether key has been stored and returns it.
+	GetIdempotencyKey(ctx context.Context, key string) (entity.IdempotencyKey, bool, error)
+	// CreateIdempotencyKey stores key, returning ErrIdempotencyKeyExists if
+	// it is already present.
+	CreateIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error
+	// WithinTx runs fn in a single database transaction using a repository
+	// bound to it. Any error returned by fn rolls the whole unit of work back.
+	WithinTx(ctx context.Context, fn func(repo ITransactionRepository) error) error
 }
 
 // transactionService is the implementation of ITransactionService that uses ITransactionRepository
 type transactionService struct {
 	transactionRepo ITransactionRepository
+	rates           RateProvider
+	limits          LimitPolicy
+	risk            RiskChecker
+	events          *eventBroker
 }
 
-// NewTransactionService creates a new instance of transactionService
-func NewTransactionService(repo ITransactionRepository) ITransactionService {
-	return &transactionService{transactionRepo: repo}
+// NewTransactionService creates a new instance of transactionService. rates
+// converts cross-currency transfers; with a nil provider only transfers
+// between wallets of the same currency are accepted. limits caps payments
+// and outgoing transfers; a nil policy leaves them unlimited. risk screens
+// top-ups, payments and transfers; a nil checker allows all of them.
+func NewTransactionService(repo ITransactionRepository, rates RateProvider, limits LimitPolicy, risk RiskChecker) ITransactionService {
+	return &transactionService{transactionRepo: repo, rates: rates, limits: limits, risk: risk, events: newEventBroker()}
 }
 
-// CreateTransaction creates a new transaction
-func (s *transactionService) CreateTransaction(ctx context.Context, transaction *entity.Transaction) (entity.Transaction, error) {
-	createdTransaction, err := s.transactionRepo.CreateTransaction(ctx, transaction)
-	if err != nil {
-		return entity.Transaction{}, fmt.Errorf("failed to create transaction: %v", err)
+// CreateWallet creates a new wallet. Currency defaults to IDR and name to
+// "Main". A user's first wallet always becomes the default; a later wallet
+// created with IsDefault set takes the flag over from the previous default.
+func (s *transactionService) CreateWallet(ctx context.Context, wallet *entity.Wallet) (entity.Wallet, error) {
+	if wallet.Currency == "" {
+		wallet.Currency = money.DefaultCurrency
+	}
+	wallet.Status = entity.WalletActive
+	if wallet.Name == "" {
+		wallet.Name = "Main"
 	}
-	return createdTransaction, nil
-}
 
-// CreateWallet creates a new wallet
-func (s *transactionService) CreateWallet(ctx context.Context, wallet *entity.Wallet) (entity.Wallet, error) {
-	createdWallet, err := s.transactionRepo.CreateWallet(ctx, wallet)
+	var v validator
+	v.check(wallet.UserID > 0, "user_id", "must be a positive user ID")
+	v.check(money.IsSupportedCurrency(wallet.Currency), "currency", "is not a supported currency")
+	v.check(len(wallet.Name) <= 50, "name", "must be at most 50 characters")
+	v.check(wallet.Balance == 0, "balance", "must be zero; fund the wallet with a top-up")
+	if err := v.err(); err != nil {
+		return entity.Wallet{}, err
+	}
+
+	var createdWallet entity.Wallet
+	err := s.transactionRepo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		existing, err := repo.GetWalletsByUserID(ctx, int(wallet.UserID))
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			wallet.IsDefault = true
+		} else if wallet.IsDefault {
+			if err := repo.ClearDefaultWallet(ctx, int(wallet.UserID)); err != nil {
+				return err
+			}
+		}
+
+		createdWallet, err = repo.CreateWallet(ctx, wallet)
+		return err
+	})
 	if err != nil {
-		return entity.Wallet{}, fmt.Errorf("failed to create wallet: %v", err)
+		return entity.Wallet{}, fmt.Errorf("failed to create wallet: %w", err)
 	}
 	return createdWallet, nil
 }
@@ -62,38 +284,142 @@ func (s *transactionService) CreateWallet(ctx context.Context, wallet *entity.Wa
 func (s *transactionService) GetTransaction(ctx context.Context, id int32) (entity.Transaction, error) {
 	transaction, err := s.transactionRepo.GetTransaction(ctx, id)
 	if err != nil {
-		return entity.Transaction{}, fmt.Errorf("failed to get transaction: %v", err)
+		return entity.Transaction{}, fmt.Errorf("failed to get transaction: %w", err)
 	}
 	return transaction, nil
 }
 
-// TransferWallet transfers funds from one wallet to another
-func (s *transactionService) TransferWallet(ctx context.Context, fromWalletID, toWalletID int, amount float64) error {
-	fromWallet, err := s.transactionRepo.GetWalletByID(ctx, fromWalletID)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve source wallet: %v", err)
+// lockWalletPair locks both wallets of a transfer in ascending wallet ID
+// order, so two opposing transfers between the same wallets cannot deadlock.
+func lockWalletPair(ctx context.Context, repo ITransactionRepository, fromWalletID, toWalletID int) (entity.Wallet, entity.Wallet, error) {
+	order := []int{fromWalletID, toWalletID}
+	if toWalletID < fromWalletID {
+		order[0], order[1] = toWalletID, fromWalletID
+	}
+
+	locked := make(map[int]entity.Wallet, 2)
+	for _, walletID := range order {
+		wallet, err := repo.GetWalletByIDForUpdate(ctx, walletID)
+		if err != nil {
+			return entity.Wallet{}, entity.Wallet{}, fmt.Errorf("failed to lock wallet %d: %w", walletID, err)
+		}
+		locked[walletID] = wallet
+	}
+	return locked[fromWalletID], locked[toWalletID], nil
+}
+
+// validateWalletAmount checks the arguments of a single-wallet movement
+func validateWalletAmount(walletID int, amount money.Amount) error {
+	var v validator
+	v.check(walletID > 0, "wallet_id", "must be a positive wallet ID")
+	v.check(amount > 0, "amount", "must be greater than zero")
+	return v.err()
+}
+
+// TransferWallet transfers funds from one wallet to another. amount is in
+// the source wallet's currency; when the destination holds another currency
+// it is credited with the amount converted at the provider's current rate.
+// The debit, the credit and both ledger rows are committed atomically, and
+// both wallet rows stay locked until then. The returned transaction is the
+// debit recorded on the source wallet.
+func (s *transactionService) TransferWallet(ctx context.Context, fromWalletID, toWalletID int, amount money.Amount, idempotencyKey string) (entity.Transaction, error) {
+	var v validator
+	v.check(fromWalletID > 0, "from_wallet_id", "must be a positive wallet ID")
+	v.check(toWalletID > 0, "to_wallet_id", "must be a positive wallet ID")
+	v.check(fromWalletID != toWalletID, "to_wallet_id", "must differ from from_wallet_id")
+	v.check(amount > 0, "amount", "must be greater than zero")
+	if err := v.err(); err != nil {
+		return entity.Transaction{}, err
 	}
 
-	toWallet, err := s.transactionRepo.GetWalletByID(ctx, toWalletID)
+	// A wallet's currency never changes, so the rate can be fetched before
+	// any row is locked.
+	from, err := s.transactionRepo.GetWalletByID(ctx, fromWalletID)
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to retrieve source wallet: %w", err)
+	}
+	to, err := s.transactionRepo.GetWalletByID(ctx, toWalletID)
+	if err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to retrieve destination wallet: %w", err)
+	}
+	conv, err := convert(ctx, s.rates, amount, from.Currency, to.Currency)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve destination wallet: %v", err)
+		return entity.Transaction{}, err
 	}
 
-	if fromWallet.Balance < amount {
-		return fmt.Errorf("insufficient funds in source wallet")
+	request := entity.IdempotencyKey{
+		Operation:            OperationTransfer,
+		WalletID:             fromWalletID,
+		CounterpartyWalletID: toWalletID,
+		Amount:               amount,
+	}
+	transaction, err := s.idempotent(ctx, idempotencyKey, request, func(repo ITransactionRepository) (entity.Transaction, error) {
+		fromWallet, toWallet, err := lockWalletPair(ctx, repo, fromWalletID, toWalletID)
+		if err != nil {
+			return entity.Transaction{}, err
+		}
+		if err := requireActive(fromWallet); err != nil {
+			return entity.Transaction{}, err
+		}
+		if err := requireActive(toWallet); err != nil {
+			return entity.Transaction{}, err
+		}
+
+		if fromWallet.Available() < amount {
+			return entity.Transaction{}, fmt.Errorf("source wallet: %w", ErrInsufficientFunds)
+		}
+		if err := s.checkLimits(ctx, repo, fromWallet, amount); err != nil {
+			return entity.Transaction{}, err
+		}
+		err = s.screen(ctx, repo, RiskAttempt{
+			Operation: OperationTransfer,
+			Wallet:    fromWallet,
+			Amount:    amount,
+			Recipient: RiskRecipient{WalletID: toWalletID},
+		})
+		if err != nil {
+			return entity.Transaction{}, err
+		}
+
+		return moveFunds(ctx, repo, fromWallet, toWallet, amount, conv)
+	})
+	if err == nil {
+		s.events.notify(fromWalletID, toWalletID)
 	}
+	return transaction, err
+}
 
+// moveFunds debits amount from fromWallet and credits the converted amount
+// to toWallet, both locked by the caller, and records the ledger rows,
+// wallet events and domain events of the transfer. It returns the debit.
+func moveFunds(ctx context.Context, repo ITransactionRepository, fromWallet, toWallet entity.Wallet, amount money.Amount, conv conversion) (entity.Transaction, error) {
+	fromWalletID, toWalletID := int(fromWallet.Walletid), int(toWallet.Walletid)
 	fromWallet.Balance -= amount
-	toWallet.Balance += amount
+	toWallet.Balance += conv.Converted
 
-	err = s.transactionRepo.UpdateWallet(ctx, &fromWallet)
+	err := repo.UpdateWallet(ctx, &fromWallet)
 	if err != nil {
-		return fmt.Errorf("failed to update source wallet: %v", err)
+		return entity.Transaction{}, fmt.Errorf("failed to update source wallet: %w", err)
 	}
 
-	err = s.transactionRepo.UpdateWallet(ctx, &toWallet)
+	err = repo.UpdateWallet(ctx, &toWallet)
 	if err != nil {
-		return fmt.Errorf("failed to update destination wallet: %v", err)
+		return entity.Transaction{}, fmt.Errorf("failed to update destination wallet: %w", err)
+	}
+
+	postings := []entity.Posting{
+		walletDebit(fromWallet, amount),
+		walletCredit(toWallet, conv.Converted),
+	}
+	if fromWallet.Currency != toWallet.Currency {
+		postings = append(postings,
+			credit(entity.AccountFXConversion, 0, amount, fromWallet.Currency),
+			debit(entity.AccountFXConversion, 0, conv.Converted, toWallet.Currency),
+		)
+	}
+	entry, err := postJournal(ctx, repo, entity.EntryTypeTransfer, postings...)
+	if err != nil {
+		return entity.Transaction{}, err
 	}
 
 	transactionOut := &entity.Transaction{
@@ -101,111 +427,272 @@ func (s *transactionService) TransferWallet(ctx context.Context, fromWalletID, t
 		WalletIDSource:  toWalletID,
 		Amount:          amount,
 		TransactionType: "out",
+		JournalEntryID:  &entry.EntryID,
 	}
-	_, err = s.transactionRepo.CreateTransaction(ctx, transactionOut)
+	conv.record(transactionOut)
+	createdOut, err := repo.CreateTransaction(ctx, transactionOut)
 	if err != nil {
-		return fmt.Errorf("failed to create transaction record for source wallet: %v", err)
+		return entity.Transaction{}, fmt.Errorf("failed to create transaction record for source wallet: %w", err)
 	}
 
 	transactionIn := &entity.Transaction{
 		WalletID:        toWalletID,
 		WalletIDSource:  fromWalletID,
-		Amount:          amount,
+		Amount:          conv.Converted,
 		TransactionType: "in",
+		JournalEntryID:  &entry.EntryID,
 	}
-	_, err = s.transactionRepo.CreateTransaction(ctx, transactionIn)
+	conv.record(transactionIn)
+	createdIn, err := repo.CreateTransaction(ctx, transactionIn)
 	if err != nil {
-		return fmt.Errorf("failed to create transaction record for destination wallet: %v", err)
+		return entity.Transaction{}, fmt.Errorf("failed to create transaction record for destination wallet: %w", err)
 	}
 
-	return nil
-}
+	if err := repo.CreateWalletEvent(ctx, walletEvent(entity.EventTransferOut, fromWallet, createdOut)); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to record source wallet event: %w", err)
+	}
+	if err := repo.CreateWalletEvent(ctx, walletEvent(entity.EventTransferIn, toWallet, createdIn)); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to record destination wallet event: %w", err)
+	}
 
-// TopUp adds funds to a wallet and creates an "in" transaction
-func (s *transactionService) TopUp(ctx context.Context, walletID int, amount float64) error {
-	wallet, err := s.transactionRepo.GetWalletByID(ctx, walletID)
+	err = enqueue(ctx, repo,
+		balanceEvent(entity.EventWalletDebited, fromWallet, createdOut, ReasonTransfer),
+		balanceEvent(entity.EventWalletCredited, toWallet, createdIn, ReasonTransfer),
+		outboxEvent{eventType: entity.EventTransferCompleted, aggregateID: fromWalletID, payload: TransferCompletedEvent{
+			FromWalletID:      fromWalletID,
+			ToWalletID:        toWalletID,
+			FromUserID:        fromWallet.UserID,
+			ToUserID:          toWallet.UserID,
+			TransactionID:     createdOut.TransactionID,
+			Amount:            conv.Original,
+			Currency:          conv.OriginalCurrency,
+			ConvertedAmount:   conv.Converted,
+			ConvertedCurrency: conv.ConvertedCurrency,
+			FxRate:            conv.Rate,
+		}},
+	)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve wallet: %v", err)
+		return entity.Transaction{}, err
 	}
 
-	wallet.Balance += amount
+	return createdOut, nil
+}
 
-	err = s.transactionRepo.UpdateWallet(ctx, &wallet)
-	if err != nil {
-		return fmt.Errorf("failed to update wallet: %v", err)
+// TopUp adds funds to a wallet and creates an "in" transaction in a single
+// database transaction
+func (s *transactionService) TopUp(ctx context.Context, walletID int, amount money.Amount, idempotencyKey string) (entity.Transaction, error) {
+	if err := validateWalletAmount(walletID, amount); err != nil {
+		return entity.Transaction{}, err
 	}
 
-	transaction := &entity.Transaction{
-		WalletID:        walletID,
-		WalletIDSource:  0,
-		Amount:          amount,
-		TransactionType: "in",
+	request := entity.IdempotencyKey{
+		Operation: OperationTopUp,
+		WalletID:  walletID,
+		Amount:    amount,
 	}
-	_, err = s.transactionRepo.CreateTransaction(ctx, transaction)
-	if err != nil {
-		return fmt.Errorf("failed to create transaction record for top-up: %v", err)
+	transaction, err := s.idempotent(ctx, idempotencyKey, request, func(repo ITransactionRepository) (entity.Transaction, error) {
+		wallet, err := repo.GetWalletByIDForUpdate(ctx, walletID)
+		if err != nil {
+			return entity.Transaction{}, fmt.Errorf("failed to retrieve wallet: %w", err)
+		}
+		if err := requireActive(wallet); err != nil {
+			return entity.Transaction{}, err
+		}
+		if err := s.screen(ctx, repo, RiskAttempt{Operation: OperationTopUp, Wallet: wallet, Amount: amount}); err != nil {
+			return entity.Transaction{}, err
+		}
+
+		wallet.Balance += amount
+
+		err = repo.UpdateWallet(ctx, &wallet)
+		if err != nil {
+			return entity.Transaction{}, fmt.Errorf("failed to update wallet: %w", err)
+		}
+
+		entry, err := postJournal(ctx, repo, entity.EntryTypeTopUp,
+			debit(entity.AccountTopUpFunding, 0, amount, wallet.Currency),
+			walletCredit(wallet, amount),
+		)
+		if err != nil {
+			return entity.Transaction{}, err
+		}
+
+		transaction := &entity.Transaction{
+			WalletID:        walletID,
+			WalletIDSource:  0,
+			Amount:          amount,
+			TransactionType: "in",
+			JournalEntryID:  &entry.EntryID,
+		}
+		noConversion(amount, wallet.Currency).record(transaction)
+		created, err := repo.CreateTransaction(ctx, transaction)
+		if err != nil {
+			return entity.Transaction{}, fmt.Errorf("failed to create transaction record for top-up: %w", err)
+		}
+
+		if err := repo.CreateWalletEvent(ctx, walletEvent(entity.EventTopUp, wallet, created)); err != nil {
+			return entity.Transaction{}, fmt.Errorf("failed to record wallet event: %w", err)
+		}
+		if err := enqueue(ctx, repo, balanceEvent(entity.EventWalletCredited, wallet, created, ReasonTopUp)); err != nil {
+			return entity.Transaction{}, err
+		}
+
+		return created, nil
+	})
+	if err == nil {
+		s.events.notify(walletID)
 	}
-
-	return nil
+	return transaction, err
 }
 
-// Payment deducts funds from a wallet and creates an "out" transaction
-func (s *transactionService) Payment(ctx context.Context, walletID int, amount float64) error {
-	wallet, err := s.transactionRepo.GetWalletByID(ctx, walletID)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve wallet: %v", err)
+// Payment deducts funds from a wallet and creates an "out" transaction in a
+// single database transaction. When the merchant registered a webhook, a
+// payment.succeeded delivery is recorded in the same transaction.
+func (s *transactionService) Payment(ctx context.Context, walletID int, amount money.Amount, merchantID, idempotencyKey string) (entity.Transaction, error) {
+	if err := validateWalletAmount(walletID, amount); err != nil {
+		return entity.Transaction{}, err
 	}
-
-	if wallet.Balance < amount {
-		return fmt.Errorf("insufficient funds in wallet")
+	var v validator
+	v.check(len(merchantID) <= maxMerchantIDLength, "merchant_id", fmt.Sprintf("must be at most %d characters", maxMerchantIDLength))
+	if err := v.err(); err != nil {
+		return entity.Transaction{}, err
 	}
 
-	wallet.Balance -= amount
+	request := entity.IdempotencyKey{
+		Operation:  OperationPayment,
+		WalletID:   walletID,
+		MerchantID: merchantID,
+		Amount:     amount,
+	}
+	transaction, err := s.idempotent(ctx, idempotencyKey, request, func(repo ITransactionRepository) (entity.Transaction, error) {
+		wallet, err := repo.GetWalletByIDForUpdate(ctx, walletID)
+		if err != nil {
+			return entity.Transaction{}, fmt.Errorf("failed to retrieve wallet: %w", err)
+		}
+		if err := requireActive(wallet); err != nil {
+			return entity.Transaction{}, err
+		}
+
+		if wallet.Available() < amount {
+			return entity.Transaction{}, ErrInsufficientFunds
+		}
+		if err := s.checkLimits(ctx, repo, wallet, amount); err != nil {
+			return entity.Transaction{}, err
+		}
+		err = s.screen(ctx, repo, RiskAttempt{
+			Operation: OperationPayment,
+			Wallet:    wallet,
+			Amount:    amount,
+			Recipient: RiskRecipient{MerchantID: merchantID},
+		})
+		if err != nil {
+			return entity.Transaction{}, err
+		}
+
+		wallet.Balance -= amount
+
+		err = repo.UpdateWallet(ctx, &wallet)
+		if err != nil {
+			return entity.Transaction{}, fmt.Errorf("failed to update wallet: %w", err)
+		}
+
+		return recordPayment(ctx, repo, wallet, amount, merchantID)
+	})
+	if err == nil {
+		s.events.notify(walletID)
+	}
+	return transaction, err
+}
 
-	err = s.transactionRepo.UpdateWallet(ctx, &wallet)
+// recordPayment writes the journal entry, the ledger row, the wallet event,
+// the domain event and the merchant webhook of a payment whose amount was
+// already taken from wallet.
+func recordPayment(ctx context.Context, repo ITransactionRepository, wallet entity.Wallet, amount money.Amount, merchantID string) (entity.Transaction, error) {
+	entry, err := postJournal(ctx, repo, entity.EntryTypePayment,
+		walletDebit(wallet, amount),
+		credit(entity.AccountMerchantSettlement, 0, amount, wallet.Currency),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to update wallet: %v", err)
+		return entity.Transaction{}, err
 	}
 
 	transaction := &entity.Transaction{
-		WalletID:        walletID,
+		WalletID:        int(wallet.Walletid),
 		WalletIDSource:  0,
 		Amount:          amount,
 		TransactionType: "out",
+		JournalEntryID:  &entry.EntryID,
+		MerchantID:      merchantID,
 	}
-	_, err = s.transactionRepo.CreateTransaction(ctx, transaction)
+	noConversion(amount, wallet.Currency).record(transaction)
+	created, err := repo.CreateTransaction(ctx, transaction)
 	if err != nil {
-		return fmt.Errorf("failed to create transaction record for payment: %v", err)
+		return entity.Transaction{}, fmt.Errorf("failed to create transaction record for payment: %w", err)
 	}
 
-	return nil
+	if err := repo.CreateWalletEvent(ctx, walletEvent(entity.EventPayment, wallet, created)); err != nil {
+		return entity.Transaction{}, fmt.Errorf("failed to record wallet event: %w", err)
+	}
+	if err := enqueue(ctx, repo, balanceEvent(entity.EventWalletDebited, wallet, created, ReasonPayment)); err != nil {
+		return entity.Transaction{}, err
+	}
+	if err := enqueueWebhook(ctx, repo, entity.WebhookPaymentSucceeded, created, wallet.Currency); err != nil {
+		return entity.Transaction{}, err
+	}
+	return created, nil
 }
 
 // GetWalletByID retrieves a wallet by its ID
 func (s *transactionService) GetWalletByID(ctx context.Context, walletID int) (entity.Wallet, error) {
 	wallet, err := s.transactionRepo.GetWalletByID(ctx, walletID)
 	if err != nil {
-		return entity.Wallet{}, fmt.Errorf("failed to get wallet: %v", err)
+		return entity.Wallet{}, fmt.Errorf("failed to get wallet: %w", err)
 	}
 	return wallet, nil
 }
 
+// MaxBatchSize is the largest number of IDs GetWalletsByIDs accepts.
+const MaxBatchSize = 500
+
+// GetWalletsByIDs retrieves several wallets at once. IDs that do not exist
+// are skipped, so the result may be shorter than walletIDs.
+func (s *transactionService) GetWalletsByIDs(ctx context.Context, walletIDs []int) ([]entity.Wallet, error) {
+	var v validator
+	v.check(len(walletIDs) <= MaxBatchSize, "ids", fmt.Sprintf("must hold at most %d IDs", MaxBatchSize))
+	for _, id := range walletIDs {
+		if id <= 0 {
+			v.check(false, "ids", "must be positive wallet IDs")
+			break
+		}
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	if len(walletIDs) == 0 {
+		return nil, nil
+	}
+
+	wallets, err := s.transactionRepo.GetWalletsByIDs(ctx, walletIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallets: %w", err)
+	}
+	return wallets, nil
+}
+
 // GetWalletByUserID retrieves wallets by user ID
 func (s *transactionService) GetWalletByUserID(ctx context.Context, userID int) (entity.Wallet, error) {
 	wallets, err := s.transactionRepo.GetWalletByUserID(ctx, userID)
 	if err != nil {
-		return entity.Wallet{}, fmt.Errorf("failed to get wallets: %v", err)
+		return entity.Wallet{}, fmt.Errorf("failed to get wallets: %w", err)
 	}
 	return wallets, nil
 }
 
-// GetTransactionByUserID retrieves transactions by user ID
-func (s *transactionService) GetTransactionByUserID(ctx context.Context, userID int) ([]entity.Transaction, error) {
-	transactions, err := s.transactionRepo.GetTransactionByUserID(ctx, userID)
+// GetWalletsByUserID retrieves every wallet of a user
+func (s *transactionService) GetWalletsByUserID(ctx context.Context, userID int) ([]entity.Wallet, error) {
+	wallets, err := s.transactionRepo.GetWalletsByUserID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transactions: %v", err)
+		return nil, fmt.Errorf("failed to get wallets: %w", err)
 	}
-	// log.Printf("transactions: %+v", transactions)
-	return transactions, nil
-
+	return wallets, nil
 }
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ewallet/wallet/entity"
+	"fmt"
+)
+
+// Errors returned for wallets that are not active.
+var (
+	ErrWalletFrozen = errors.New("wallet is frozen")
+	ErrWalletClosed = errors.New("wallet is closed")
+	// ErrWalletNotEmpty is returned when closing a wallet that holds money
+	// without a wallet to sweep it to, or that has open authorizations.
+	ErrWalletNotEmpty = errors.New("wallet is not empty")
+)
+
+// maxStatusReasonLength bounds the reason given for a status change.
+const maxStatusReasonLength = 500
+
+// requireActive returns an error wrapping ErrWalletFrozen or
+// ErrWalletClosed unless money may move in or out of wallet.
+func requireActive(wallet entity.Wallet) error {
+	switch wallet.Status {
+	case entity.WalletFrozen:
+		return fmt.Errorf("wallet %d: %w", wallet.Walletid, ErrWalletFrozen)
+	case entity.WalletClosed:
+		return fmt.Errorf("wallet %d: %w", wallet.Walletid, ErrWalletClosed)
+	}
+	return nil
+}
+
+// validateStatusChange checks the arguments shared by SetWalletStatus and
+// CloseWallet.
+func validateStatusChange(v *validator, walletID int, reason string, changedBy uint) {
+	v.check(walletID > 0, "wallet_id", "must be a positive wallet ID")
+	v.check(reason != "", "reason", "is required")
+	v.check(len(reason) <= maxStatusReasonLength, "reason", fmt.Sprintf("must be at most %d characters", maxStatusReasonLength))
+	v.check(changedBy > 0, "changed_by", "must be a positive user ID")
+}
+
+// SetWalletStatus freezes an active wallet or unfreezes a frozen one.
+// Setting the status a wallet already has changes nothing; a closed wallet
+// cannot be reopened.
+func (s *transactionService) SetWalletStatus(ctx context.Context, walletID int, status, reason string, changedBy uint) (entity.Wallet, error) {
+	var v validator
+	validateStatusChange(&v, walletID, reason, changedBy)
+	v.check(status == entity.WalletActive || status == entity.WalletFrozen, "status",
+		fmt.Sprintf("must be %q or %q; close wallets with CloseWallet", entity.WalletActive, entity.WalletFrozen))
+	if err := v.err(); err != nil {
+		return entity.Wallet{}, err
+	}
+
+	var result entity.Wallet
+	err := s.transactionRepo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		wallet, err := repo.GetWalletByIDForUpdate(ctx, walletID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve wallet: %w", err)
+		}
+		switch wallet.Status {
+		case status:
+			result = wallet
+			return nil
+		case entity.WalletClosed:
+			return fmt.Errorf("wallet %d: %w", walletID, ErrWalletClosed)
+		}
+
+		result, err = changeWalletStatus(ctx, repo, wallet, status, reason, changedBy, nil)
+		return err
+	})
+	if err != nil {
+		return entity.Wallet{}, fmt.Errorf("failed to change wallet status: %w", err)
+	}
+	return result, nil
+}
+
+// CloseWallet closes a wallet for good. A wallet with open authorizations
+// cannot be closed. A non-zero balance is swept to sweepToWalletID, which
+// must be another active wallet, as a transfer converted at the current
+// rate; a balance too small to convert must be swept to a wallet of the
+// same currency. Without a sweep wallet only an empty wallet can be
+// closed. Scheduled transfers from or to the wallet are cancelled, and when
+// it was its owner's default wallet the flag moves to the owner's oldest
+// remaining active wallet. Closing a closed wallet changes nothing.
+func (s *transactionService) CloseWallet(ctx context.Context, walletID, sweepToWalletID int, reason string, changedBy uint) (entity.Wallet, error) {
+	var v validator
+	validateStatusChange(&v, walletID, reason, changedBy)
+	v.check(sweepToWalletID >= 0, "sweep_to_wallet_id", "must be a wallet ID or zero")
+	v.check(sweepToWalletID != walletID, "sweep_to_wallet_id", "must differ from wallet_id")
+	if err := v.err(); err != nil {
+		return entity.Wallet{}, err
+	}
+
+	var result entity.Wallet
+	var notify []int
+	err := s.transactionRepo.WithinTx(ctx, func(repo ITransactionRepository) error {
+		var wallet, target entity.Wallet
+		var err error
+		if sweepToWalletID > 0 {
+			wallet, target, err = lockWalletPair(ctx, repo, walletID, sweepToWalletID)
+		} else {
+			wallet, err = repo.GetWalletByIDForUpdate(ctx, walletID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to retrieve wallet: %w", err)
+		}
+		if wallet.Status == entity.WalletClosed {
+			result = wallet
+			return nil
+		}
+		if wallet.HeldBalance > 0 {
+			return fmt.Errorf("wallet %d has %s %s held by open authorizations: %w", walletID, wallet.HeldBalance, wallet.Currency, ErrWalletNotEmpty)
+		}
+
+		var sweepID *uint
+		if wallet.Balance > 0 {
+			if sweepToWalletID == 0 {
+				return fmt.Errorf("wallet %d holds %s %s and no wallet to sweep it to was given: %w", walletID, wallet.Balance, wallet.Currency, ErrWalletNotEmpty)
+			}
+			if err := requireActive(target); err != nil {
+				return fmt.Errorf("sweep destination: %w", err)
+			}
+			conv, err := convert(ctx, s.rates, wallet.Balance, wallet.Currency, target.Currency)
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				// convert rejects a balance that rounds to zero in the
+				// target currency; such dust can only be swept as is.
+				var v validator
+				v.check(false, "sweep_to_wallet_id", fmt.Sprintf("%s %s converts to zero %s; sweep it to a %s wallet",
+					wallet.Balance, wallet.Currency, target.Currency, wallet.Currency))
+				return v.err()
+			}
+			if err != nil {
+				return err
+			}
+			sweep, err := moveFunds(ctx, repo, wallet, target, wallet.Balance, conv)
+			if err != nil {
+				return fmt.Errorf("failed to sweep balance: %w", err)
+			}
+			sweepID = &sweep.TransactionID
+			wallet.Balance = 0
+			notify = []int{walletID, sweepToWalletID}
+		}
+
+		if _, err := repo.CancelScheduledTransfersByWallet(ctx, walletID); err != nil {
+			return fmt.Errorf("failed to cancel scheduled transfers: %w", err)
+		}
+		if wallet.IsDefault {
+			if err := promoteDefaultWallet(ctx, repo, wallet); err != nil {
+				return err
+			}
+			wallet.IsDefault = false
+		}
+
+		result, err = changeWalletStatus(ctx, repo, wallet, entity.WalletClosed, reason, changedBy, sweepID)
+		return err
+	})
+	if err != nil {
+		return entity.Wallet{}, fmt.Errorf("failed to close wallet: %w", err)
+	}
+	s.events.notify(notify...)
+	return result, nil
+}
+
+// promoteDefaultWallet moves the default flag from wallet, which is being
+// closed, to the oldest other active wallet of its owner, if there is one.
+func promoteDefaultWallet(ctx context.Context, repo ITransactionRepository, wallet entity.Wallet) error {
+	if err := repo.ClearDefaultWallet(ctx, int(wallet.UserID)); err != nil {
+		return fmt.Errorf("failed to clear default wallet: %w", err)
+	}
+	wallets, err := repo.GetWalletsByUserID(ctx, int(wallet.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to get wallets: %w", err)
+	}
+	for _, other := range wallets {
+		if other.Walletid != wallet.Walletid && other.Status == entity.WalletActive {
+			if err := repo.SetDefaultWallet(ctx, int(other.Walletid)); err != nil {
+				return fmt.Errorf("failed to set default wallet: %w", err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// changeWalletStatus stores a wallet's new status and reason, records the
+// change and publishes a WalletStatusChanged event. It must run in the
+// transaction that locked the wallet.
+func changeWalletStatus(ctx context.Context, repo ITransactionRepository, wallet entity.Wallet, status, reason string, changedBy uint, sweepID *uint) (entity.Wallet, error) {
+	change := &entity.WalletStatusChange{
+		WalletID:           int(wallet.Walletid),
+		FromStatus:         wallet.Status,
+		ToStatus:           status,
+		Reason:             reason,
+		ChangedBy:          changedBy,
+		SweepTransactionID: sweepID,
+	}
+	wallet.Status = status
+	wallet.StatusReason = reason
+	if err := repo.UpdateWalletStatus(ctx, &wallet); err != nil {
+		return entity.Wallet{}, fmt.Errorf("failed to update wallet status: %w", err)
+	}
+	if err := repo.CreateWalletStatusChange(ctx, change); err != nil {
+		return entity.Wallet{}, fmt.Errorf("failed to record wallet status change: %w", err)
+	}
+
+	err := enqueue(ctx, repo, outboxEvent{eventType: entity.EventWalletStatusChanged, aggregateID: int(wallet.Walletid), payload: WalletStatusChangedEvent{
+		WalletID:   int(wallet.Walletid),
+		UserID:     wallet.UserID,
+		FromStatus: change.FromStatus,
+		ToStatus:   status,
+		Reason:     reason,
+	}})
+	if err != nil {
+		return entity.Wallet{}, err
+	}
+	return wallet, nil
+}
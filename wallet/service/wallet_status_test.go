@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/fx"
+	"ewallet/wallet/service"
+	"testing"
+)
+
+func TestCloseWallet(t *testing.T) {
+	ctx := context.Background()
+	var validationErr *service.ValidationError
+	tests := []struct {
+		name    string
+		balance money.Amount
+		held    money.Amount
+		status  string
+		sweepTo int
+		// wantErr is checked with errors.Is, or with errors.As when it is
+		// a **service.ValidationError.
+		wantErr any
+		// wantSwept is what the sweep wallet gained.
+		wantSwept money.Amount
+	}{
+		{"empty", 0, 0, entity.WalletActive, 0, nil, 0},
+		{"empty with a sweep wallet", 0, 0, entity.WalletActive, 2, nil, 0},
+		{"frozen", 0, 0, entity.WalletFrozen, 0, nil, 0},
+		{"balance without a sweep wallet", 10_00, 0, entity.WalletActive, 0, service.ErrWalletNotEmpty, 0},
+		{"sweep", 10_00, 0, entity.WalletActive, 2, nil, 10_00},
+		{"sweep across currencies", 16_250_00, 0, entity.WalletActive, 3, nil, 1_00},
+		{"sweep converting to zero", 1, 0, entity.WalletActive, 3, &validationErr, 0},
+		{"sweep to a frozen wallet", 10_00, 0, entity.WalletActive, 4, service.ErrWalletFrozen, 0},
+		{"sweep to an unknown wallet", 10_00, 0, entity.WalletActive, 9, service.ErrWalletNotFound, 0},
+		{"open authorization", 10_00, 5_00, entity.WalletActive, 2, service.ErrWalletNotEmpty, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := money.ParseRate("16250")
+			if err != nil {
+				t.Fatal(err)
+			}
+			rates, err := fx.NewStatic(map[string]money.Rate{"USD/IDR": rate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Wallet 1 is being closed; 2 is its owner's other wallet.
+			repo := &ledgerRepo{wallets: map[int]*entity.Wallet{
+				1: {Walletid: 1, UserID: 1, Balance: tt.balance, HeldBalance: tt.held, Currency: "IDR", IsDefault: true, Status: tt.status},
+				2: {Walletid: 2, UserID: 1, Currency: "IDR", Status: entity.WalletActive},
+				3: {Walletid: 3, UserID: 2, Currency: "USD", Status: entity.WalletActive},
+				4: {Walletid: 4, UserID: 2, Currency: "IDR", Status: entity.WalletFrozen},
+			}}
+			repo.schedules = []*entity.ScheduledTransfer{
+				{ID: 1, FromWalletID: 1, ToWalletID: 3, Status: entity.ScheduleActive},
+				{ID: 2, FromWalletID: 3, ToWalletID: 1, Status: entity.SchedulePaused},
+				{ID: 3, FromWalletID: 2, ToWalletID: 3, Status: entity.ScheduleActive},
+			}
+			svc := service.NewTransactionService(repo, rates, nil, nil)
+
+			closed, err := svc.CloseWallet(ctx, 1, tt.sweepTo, "account closed", 7)
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("CloseWallet() error = %v", err)
+				}
+			case error:
+				if !errors.Is(err, want) {
+					t.Fatalf("CloseWallet() error = %v, want %v", err, want)
+				}
+			default:
+				if !errors.As(err, want) {
+					t.Fatalf("CloseWallet() error = %v, want %T", err, want)
+				}
+			}
+
+			wallet := repo.wallets[1]
+			if err != nil {
+				// Nothing changed: the wallet, its schedules and the
+				// default flag are as they were.
+				if wallet.Status != tt.status || wallet.Balance != tt.balance || !wallet.IsDefault || len(repo.changes) != 0 || len(repo.transactions) != 0 {
+					t.Fatalf("failed close left wallet %+v and %d status changes", wallet, len(repo.changes))
+				}
+				if repo.schedules[0].Status != entity.ScheduleActive {
+					t.Fatalf("failed close cancelled schedule 1")
+				}
+				return
+			}
+
+			if closed.Status != entity.WalletClosed || wallet.Status != entity.WalletClosed || wallet.StatusReason != "account closed" || wallet.Balance != 0 {
+				t.Fatalf("closed wallet %+v", wallet)
+			}
+			if tt.sweepTo != 0 {
+				if got := repo.balance(t, tt.sweepTo); got != tt.wantSwept {
+					t.Fatalf("sweep wallet holds %s, want %s", got, tt.wantSwept)
+				}
+			}
+			if len(repo.changes) != 1 {
+				t.Fatalf("%d status changes recorded, want 1", len(repo.changes))
+			}
+			change := repo.changes[0]
+			if change.FromStatus != tt.status || change.ToStatus != entity.WalletClosed || change.ChangedBy != 7 ||
+				(tt.balance > 0) != (change.SweepTransactionID != nil) {
+				t.Fatalf("status change %+v", change)
+			}
+
+			if wallet.IsDefault || !repo.wallets[2].IsDefault {
+				t.Fatalf("default wallet did not move to wallet 2")
+			}
+			for i, want := range []string{entity.ScheduleCancelled, entity.ScheduleCancelled, entity.ScheduleActive} {
+				if got := repo.schedules[i].Status; got != want {
+					t.Fatalf("schedule %d is %s, want %s", i+1, got, want)
+				}
+			}
+
+			// Closing again changes nothing.
+			if _, err := svc.CloseWallet(ctx, 1, 0, "again", 7); err != nil || len(repo.changes) != 1 {
+				t.Fatalf("closing a closed wallet: error = %v, %d status changes", err, len(repo.changes))
+			}
+		})
+	}
+}
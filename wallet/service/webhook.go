@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"ewallet/pkg/money"
+	"ewallet/wallet/entity"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+)
+
+// Errors returned by the webhook service.
+var (
+	ErrWebhookNotFound         = errors.New("webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// Limits on webhook registration and the delivery log.
+const (
+	maxMerchantIDLength      = 64
+	minWebhookSecretLength   = 16
+	maxWebhookSecretLength   = 128
+	DefaultWebhookDeliveries = 50
+	MaxWebhookDeliveries     = 500
+)
+
+// PaymentWebhookEvent is the payload of payment.succeeded and
+// payment.refunded deliveries.
+type PaymentWebhookEvent struct {
+	TransactionID uint         `json:"transaction_id"`
+	WalletID      int          `json:"wallet_id"`
+	MerchantID    string       `json:"merchant_id"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency"`
+	CreatedAt     time.Time    `json:"created_at"`
+	// RefundOf is the refunded payment, set on payment.refunded only.
+	RefundOf uint `json:"refund_of,omitempty"`
+}
+
+// enqueueWebhook records a delivery of a payment event to the merchant's
+// endpoint. It must run in the transaction that made the change the event
+// describes. Merchants without an endpoint are not notified.
+func enqueueWebhook(ctx context.Context, repo ITransactionRepository, eventType string, transaction entity.Transaction, currency string) error {
+	if transaction.MerchantID == "" {
+		return nil
+	}
+	if _, err := repo.GetMerchantWebhook(ctx, transaction.MerchantID); err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get merchant webhook: %w", err)
+	}
+
+	event := PaymentWebhookEvent{
+		TransactionID: transaction.TransactionID,
+		WalletID:      transaction.WalletID,
+		MerchantID:    transaction.MerchantID,
+		Amount:        transaction.Amount,
+		Currency:      currency,
+		CreatedAt:     transaction.CreatedAt,
+	}
+	if transaction.RefundOf != nil {
+		event.RefundOf = *transaction.RefundOf
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s webhook: %w", eventType, err)
+	}
+	delivery := &entity.WebhookDelivery{
+		MerchantID: transaction.MerchantID,
+		EventType:  eventType,
+		Payload:    payload,
+	}
+	if err := repo.CreateWebhookDelivery(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to store webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// WebhookSender sends one delivery to a merchant endpoint, signed with the
+// endpoint's secret. It returns the HTTP status code received, or 0 if the
+// endpoint could not be reached, and an error unless the merchant
+// acknowledged the delivery.
+type WebhookSender interface {
+	Send(ctx context.Context, endpoint entity.MerchantWebhook, delivery entity.WebhookDelivery) (int, error)
+}
+
+// WebhookConfig tunes the delivery of merchant webhooks.
+type WebhookConfig struct {
+	// PollInterval is how often the dispatcher looks for due deliveries.
+	PollInterval time.Duration
+	// MaxAttempts is the number of failed attempts after which a delivery
+	// is marked failed. It can still be replayed afterwards.
+	MaxAttempts int
+	// BatchSize is the number of deliveries claimed at once.
+	BatchSize int
+	// Lease is how long a claimed delivery is hidden from other dispatchers
+	// while it is being sent.
+	Lease time.Duration
+}
+
+// WebhookService registers merchant webhook endpoints and delivers payment
+// events to them, retrying failed deliveries with exponential backoff.
+type WebhookService struct {
+	repo   ITransactionRepository
+	sender WebhookSender
+	cfg    WebhookConfig
+}
+
+// NewWebhookService creates a webhook service. Zero BatchSize and Lease get
+// defaults.
+func NewWebhookService(repo ITransactionRepository, sender WebhookSender, cfg WebhookConfig) *WebhookService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	return &WebhookService{repo: repo, sender: sender, cfg: cfg}
+}
+
+// RegisterWebhook sets the endpoint of a merchant, replacing any previous
+// one. With an empty secret a random one is generated; the returned endpoint
+// carries it so the merchant can verify signatures.
+func (s *WebhookService) RegisterWebhook(ctx context.Context, merchantID, endpointURL, secret string) (entity.MerchantWebhook, error) {
+	var v validator
+	v.check(merchantID != "" && len(merchantID) <= maxMerchantIDLength, "merchant_id", fmt.Sprintf("must be 1 to %d characters", maxMerchantIDLength))
+	parsed, err := url.Parse(endpointURL)
+	v.check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "", "url", "must be an absolute http or https URL")
+	v.check(secret == "" || (len(secret) >= minWebhookSecretLength && len(secret) <= maxWebhookSecretLength), "secret",
+		fmt.Sprintf("must be %d to %d characters", minWebhookSecretLength, maxWebhookSecretLength))
+	if err := v.err(); err != nil {
+		return entity.MerchantWebhook{}, err
+	}
+
+	if secret == "" {
+		secret, err = newWebhookSecret()
+		if err != nil {
+			return entity.MerchantWebhook{}, err
+		}
+	}
+
+	webhook := &entity.MerchantWebhook{MerchantID: merchantID, URL: endpointURL, Secret: secret}
+	if err := s.repo.SaveMerchantWebhook(ctx, webhook); err != nil {
+		return entity.MerchantWebhook{}, fmt.Errorf("failed to save webhook: %w", err)
+	}
+	return *webhook, nil
+}
+
+// newWebhookSecret returns a random signing secret.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// GetWebhookDeliveries returns the merchant's most recent deliveries, newest
+// first. A zero limit means DefaultWebhookDeliveries.
+func (s *WebhookService) GetWebhookDeliveries(ctx context.Context, merchantID string, limit int) ([]entity.WebhookDelivery, error) {
+	if limit == 0 {
+		limit = DefaultWebhookDeliveries
+	}
+	var v validator
+	v.check(merchantID != "", "merchant_id", "is required")
+	v.check(limit > 0 && limit <= MaxWebhookDeliveries, "limit", fmt.Sprintf("must be between 1 and %d", MaxWebhookDeliveries))
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.GetWebhookDeliveries(ctx, merchantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ReplayWebhookDelivery sends a delivery again, whatever its status, with a
+// fresh attempt count. The merchant receives the same ID, so it can tell a
+// replay from a new event.
+func (s *WebhookService) ReplayWebhookDelivery(ctx context.Context, id int64) (entity.WebhookDelivery, error) {
+	var v validator
+	v.check(id > 0, "delivery_id", "must be a positive delivery ID")
+	if err := v.err(); err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+
+	delivery, err := s.repo.ResetWebhookDelivery(ctx, id)
+	if err != nil {
+		return entity.WebhookDelivery{}, fmt.Errorf("failed to replay webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// Run sends due deliveries every PollInterval until ctx is done.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhook dispatcher: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends every delivery that is currently due and returns how many
+// were acknowledged.
+func (s *WebhookService) RunOnce(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		deliveries, err := s.repo.ClaimWebhookDeliveries(ctx, s.cfg.BatchSize, s.cfg.Lease)
+		if err != nil {
+			return delivered, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+		}
+		for _, delivery := range deliveries {
+			ok, err := s.deliver(ctx, delivery)
+			if err != nil {
+				return delivered, err
+			}
+			if ok {
+				delivered++
+			}
+		}
+		if len(deliveries) < s.cfg.BatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// deliver sends one claimed delivery to the merchant's current endpoint and
+// records the outcome. It reports whether the merchant acknowledged it; the
+// error is only set when the outcome could not be stored.
+func (s *WebhookService) deliver(ctx context.Context, delivery entity.WebhookDelivery) (bool, error) {
+	var statusCode int
+	endpoint, sendErr := s.repo.GetMerchantWebhook(ctx, delivery.MerchantID)
+	if sendErr == nil {
+		statusCode, sendErr = s.sender.Send(ctx, endpoint, delivery)
+	}
+	if sendErr == nil {
+		if err := s.repo.MarkWebhookDelivered(ctx, delivery.ID, statusCode); err != nil {
+			return false, fmt.Errorf("failed to mark webhook delivery %d delivered: %w", delivery.ID, err)
+		}
+		return true, nil
+	}
+
+	attempts := delivery.Attempts + 1
+	failed := attempts >= s.cfg.MaxAttempts || errors.Is(sendErr, ErrWebhookNotFound)
+	if failed {
+		log.Printf("webhook dispatcher: delivery %d (%s to %s) failed after %d attempts: %v", delivery.ID, delivery.EventType, delivery.MerchantID, attempts, sendErr)
+	}
+	if err := s.repo.MarkWebhookFailed(ctx, delivery.ID, attempts, time.Now().Add(retryDelay(attempts)), statusCode, sendErr.Error(), failed); err != nil {
+		return false, fmt.Errorf("failed to record webhook delivery %d failure: %w", delivery.ID, err)
+	}
+	return false, nil
+}
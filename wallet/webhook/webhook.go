@@ -0,0 +1,133 @@
+// Package webhook sends signed payment notifications to merchant endpoints
+// and lets receivers verify them.
+//
+// Every delivery is a POST of the JSON envelope
+//
+//	{"id": 42, "merchant_id": "acme", "type": "payment.succeeded",
+//	 "data": {...}, "created_at": "2024-07-01T10:00:00Z"}
+//
+// with the headers
+//
+//	Webhook-Id:        42
+//	Webhook-Event:     payment.succeeded
+//	Webhook-Signature: t=1719828000,v1=<hex HMAC-SHA256>
+//
+// The signature is computed with the merchant's secret over the timestamp,
+// a dot and the raw body, so a captured request cannot be replayed with a
+// new timestamp. Delivery is at least once and a replayed delivery keeps its
+// id, so receivers should deduplicate by Webhook-Id.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"ewallet/wallet/entity"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request headers set on every delivery.
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderEvent     = "Webhook-Event"
+	HeaderSignature = "Webhook-Signature"
+)
+
+// Errors returned by Verify.
+var (
+	ErrMissingSignature = errors.New("webhook: missing or malformed signature")
+	ErrSignatureExpired = errors.New("webhook: signature timestamp outside tolerance")
+	ErrInvalidSignature = errors.New("webhook: signature mismatch")
+)
+
+// Sign returns the Webhook-Signature header value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac(secret, timestamp, body))
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Verify checks a Webhook-Signature header against the raw request body. It
+// rejects signatures made more than tolerance away from now.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return ErrMissingSignature
+	}
+	if age := now.Sub(time.Unix(sent, 0)); age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, mac(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Sender posts deliveries over HTTP. Any 2xx response counts as
+// acknowledged; anything else, including a timeout, is retried by the
+// dispatcher.
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSender returns a sender giving up on a single attempt after timeout.
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{client: &http.Client{Timeout: timeout}, now: time.Now}
+}
+
+// Send implements service.WebhookSender.
+func (s *Sender) Send(ctx context.Context, endpoint entity.MerchantWebhook, delivery entity.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(delivery)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, s.now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook: %s responded %s", endpoint.URL, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
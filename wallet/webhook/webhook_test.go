@@ -0,0 +1,236 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"ewallet/wallet/entity"
+	"ewallet/wallet/service"
+	"ewallet/wallet/webhook"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const secret = "whsec_test_secret_0123456789"
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1719828000, 0)
+	body := []byte(`{"id":42,"type":"payment.succeeded"}`)
+	header := webhook.Sign(secret, now, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"round trip", secret, header, body, now, nil},
+		{"within tolerance", secret, header, body, now.Add(4 * time.Minute), nil},
+		{"tampered body", secret, header, []byte(`{"id":42,"type":"payment.refunded"}`), now, webhook.ErrInvalidSignature},
+		{"wrong secret", "whsec_other_secret_0123456789", header, body, now, webhook.ErrInvalidSignature},
+		{"expired", secret, header, body, now.Add(6 * time.Minute), webhook.ErrSignatureExpired},
+		{"from the future", secret, header, body, now.Add(-6 * time.Minute), webhook.ErrSignatureExpired},
+		{"empty header", secret, "", body, now, webhook.ErrMissingSignature},
+		{"no timestamp", secret, "v1=abcd", body, now, webhook.ErrMissingSignature},
+		{"no signature", secret, "t=1719828000", body, now, webhook.ErrMissingSignature},
+		{"bad timestamp", secret, "t=soon,v1=abcd", body, now, webhook.ErrMissingSignature},
+		{"signature not hex", secret, "t=1719828000,v1=zz", body, now, webhook.ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// merchant is an endpoint that answers with the queued status codes, then
+// 200, and keeps the Webhook-Id of every request it verified.
+type merchant struct {
+	mu    sync.Mutex
+	codes []int
+	ids   []string
+	errs  []error
+}
+
+func (m *merchant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := webhook.Verify(secret, r.Header.Get(webhook.HeaderSignature), body, time.Minute, time.Now()); err != nil {
+		m.errs = append(m.errs, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	m.ids = append(m.ids, r.Header.Get(webhook.HeaderID))
+	code := http.StatusOK
+	if len(m.codes) > 0 {
+		code, m.codes = m.codes[0], m.codes[1:]
+	}
+	w.WriteHeader(code)
+}
+
+// deliveryRepo keeps webhook deliveries in memory. It claims every pending
+// delivery whatever its next attempt time, so tests do not wait out the
+// backoff; the time is recorded for them to check instead. Other methods
+// are not implemented.
+type deliveryRepo struct {
+	service.ITransactionRepository
+	endpoint   entity.MerchantWebhook
+	deliveries map[int64]*entity.WebhookDelivery
+}
+
+func (r *deliveryRepo) GetMerchantWebhook(ctx context.Context, merchantID string) (entity.MerchantWebhook, error) {
+	if merchantID != r.endpoint.MerchantID {
+		return entity.MerchantWebhook{}, service.ErrWebhookNotFound
+	}
+	return r.endpoint, nil
+}
+
+func (r *deliveryRepo) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error) {
+	var claimed []entity.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == entity.WebhookPending && len(claimed) < limit {
+			claimed = append(claimed, *delivery)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *deliveryRepo) MarkWebhookDelivered(ctx context.Context, id int64, statusCode int) error {
+	delivery := r.deliveries[id]
+	delivery.Status = entity.WebhookDelivered
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	return nil
+}
+
+func (r *deliveryRepo) MarkWebhookFailed(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, statusCode int, lastError string, failed bool) error {
+	delivery := r.deliveries[id]
+	delivery.Attempts = attempts
+	delivery.NextAttemptAt = nextAttemptAt
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = lastError
+	if failed {
+		delivery.Status = entity.WebhookFailed
+	}
+	return nil
+}
+
+func (r *deliveryRepo) ResetWebhookDelivery(ctx context.Context, id int64) (entity.WebhookDelivery, error) {
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return entity.WebhookDelivery{}, service.ErrWebhookDeliveryNotFound
+	}
+	delivery.Status = entity.WebhookPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	return *delivery, nil
+}
+
+// newDispatcher returns a webhook service delivering one pending
+// payment.succeeded event with ID 42 to m.
+func newDispatcher(t *testing.T, m *merchant, maxAttempts int) (*service.WebhookService, *entity.WebhookDelivery) {
+	t.Helper()
+	server := httptest.NewServer(m)
+	t.Cleanup(server.Close)
+
+	delivery := &entity.WebhookDelivery{
+		ID:         42,
+		MerchantID: "acme",
+		EventType:  entity.WebhookPaymentSucceeded,
+		Payload:    []byte(`{"transaction_id":7}`),
+		Status:     entity.WebhookPending,
+	}
+	repo := &deliveryRepo{
+		endpoint:   entity.MerchantWebhook{MerchantID: "acme", URL: server.URL, Secret: secret},
+		deliveries: map[int64]*entity.WebhookDelivery{delivery.ID: delivery},
+	}
+	return service.NewWebhookService(repo, webhook.NewSender(5*time.Second), service.WebhookConfig{MaxAttempts: maxAttempts}), delivery
+}
+
+func runOnce(t *testing.T, dispatcher *service.WebhookService, want int) {
+	t.Helper()
+	delivered, err := dispatcher.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if delivered != want {
+		t.Fatalf("RunOnce() delivered %d, want %d", delivered, want)
+	}
+}
+
+func TestRunOnceRetriesWithBackoff(t *testing.T) {
+	m := &merchant{codes: []int{http.StatusInternalServerError, http.StatusInternalServerError}}
+	dispatcher, delivery := newDispatcher(t, m, 5)
+
+	for attempt, wantDelay := range []time.Duration{time.Second, 2 * time.Second} {
+		before := time.Now()
+		runOnce(t, dispatcher, 0)
+		if delivery.Status != entity.WebhookPending || delivery.Attempts != attempt+1 || delivery.LastStatusCode != http.StatusInternalServerError || delivery.LastError == "" {
+			t.Fatalf("after failure %d: status %q, attempts %d, code %d, error %q", attempt+1,
+				delivery.Status, delivery.Attempts, delivery.LastStatusCode, delivery.LastError)
+		}
+		if delivery.NextAttemptAt.Before(before.Add(wantDelay)) || delivery.NextAttemptAt.After(time.Now().Add(wantDelay)) {
+			t.Fatalf("after failure %d: next attempt in %s, want %s", attempt+1, delivery.NextAttemptAt.Sub(before), wantDelay)
+		}
+	}
+
+	runOnce(t, dispatcher, 1)
+	if delivery.Status != entity.WebhookDelivered || delivery.Attempts != 3 || delivery.LastStatusCode != http.StatusOK || delivery.LastError != "" {
+		t.Fatalf("after success: status %q, attempts %d, code %d, error %q",
+			delivery.Status, delivery.Attempts, delivery.LastStatusCode, delivery.LastError)
+	}
+	if len(m.errs) > 0 {
+		t.Fatalf("merchant rejected signatures: %v", m.errs)
+	}
+}
+
+func TestRunOnceStopsAfterMaxAttempts(t *testing.T) {
+	m := &merchant{codes: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}}
+	dispatcher, delivery := newDispatcher(t, m, 2)
+
+	runOnce(t, dispatcher, 0)
+	runOnce(t, dispatcher, 0)
+	if delivery.Status != entity.WebhookFailed || delivery.Attempts != 2 {
+		t.Fatalf("status %q after %d attempts, want %q after 2", delivery.Status, delivery.Attempts, entity.WebhookFailed)
+	}
+	runOnce(t, dispatcher, 0)
+	if len(m.ids) != 2 {
+		t.Fatalf("merchant received %d requests, want 2", len(m.ids))
+	}
+}
+
+func TestReplayKeepsWebhookID(t *testing.T) {
+	m := &merchant{codes: []int{http.StatusInternalServerError}}
+	dispatcher, delivery := newDispatcher(t, m, 1)
+
+	runOnce(t, dispatcher, 0)
+	if delivery.Status != entity.WebhookFailed {
+		t.Fatalf("status %q, want %q", delivery.Status, entity.WebhookFailed)
+	}
+
+	replayed, err := dispatcher.ReplayWebhookDelivery(context.Background(), delivery.ID)
+	if err != nil {
+		t.Fatalf("ReplayWebhookDelivery() error = %v", err)
+	}
+	if replayed.ID != delivery.ID || replayed.Attempts != 0 || replayed.Status != entity.WebhookPending {
+		t.Fatalf("replayed delivery %d with status %q and %d attempts", replayed.ID, replayed.Status, replayed.Attempts)
+	}
+	runOnce(t, dispatcher, 1)
+
+	if len(m.ids) != 2 || m.ids[0] != "42" || m.ids[1] != "42" {
+		t.Fatalf("merchant received Webhook-Id %v, want [42 42]", m.ids)
+	}
+	if _, err := dispatcher.ReplayWebhookDelivery(context.Background(), 43); !errors.Is(err, service.ErrWebhookDeliveryNotFound) {
+		t.Fatalf("replaying an unknown delivery: error = %v, want %v", err, service.ErrWebhookDeliveryNotFound)
+	}
+}